@@ -11,6 +11,8 @@ import (
 	"time"
 
 	"github.com/code-harsh006/food-delivery/internal/api"
+	"github.com/code-harsh006/food-delivery/internal/outbox"
+	"github.com/code-harsh006/food-delivery/internal/services"
 	"github.com/code-harsh006/food-delivery/pkg/config"
 	"github.com/code-harsh006/food-delivery/pkg/db"
 	"github.com/code-harsh006/food-delivery/pkg/logger"
@@ -51,6 +53,23 @@ func main() {
 		log.Println("✅ MongoDB connected successfully")
 	}
 
+	// Background jobs stop when the server shuts down
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
+	// Create the indexes the MongoDB handlers rely on and start background jobs
+	if db.GetMongoDB() != nil {
+		if err := services.EnsureIndexes(); err != nil {
+			log.Printf("⚠️  Failed to create MongoDB indexes: %v", err)
+		}
+		services.StartRecommendationRefresher(jobsCtx, time.Duration(cfg.RecommendationRefreshMinutes)*time.Minute)
+		outbox.StartDispatcher(jobsCtx, time.Duration(cfg.OutboxPollSeconds)*time.Second, cfg.OutboxMaxAttempts)
+		services.StartSeriesScheduler(jobsCtx, time.Duration(cfg.SeriesRefreshMinutes)*time.Minute)
+		services.StartWaitlistScheduler(jobsCtx, time.Duration(cfg.WaitlistPollSeconds)*time.Second)
+		services.StartReminderScheduler(jobsCtx, time.Duration(cfg.ReminderPollSeconds)*time.Second)
+		services.StartWalletExpiryScheduler(jobsCtx, time.Duration(cfg.WalletExpiryPollMinutes)*time.Minute)
+	}
+
 	// Initialize Gin router
 	if cfg.Environment == "production" {
 		gin.SetMode(gin.ReleaseMode)
@@ -61,6 +80,7 @@ func main() {
 	router.Use(gin.Recovery())
 	router.Use(middleware.Logger())
 	router.Use(middleware.CORS())
+	router.Use(middleware.Idempotency())
 	fmt.Println("Router middleware configured")
 
 	// Initialize API router
@@ -120,6 +140,7 @@ func main() {
 	<-quit
 
 	fmt.Println("\n🛑 Shutting down server...")
+	stopJobs()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/code-harsh006/food-delivery/internal/services"
+	"github.com/code-harsh006/food-delivery/pkg/middleware"
 	"github.com/code-harsh006/food-delivery/pkg/response"
 	"github.com/gin-gonic/gin"
 )
@@ -31,11 +32,14 @@ func (r *APIRouter) SetupMongoDBRoutes() {
 				"message": "MongoDB API v1",
 				"version": "1.0.0",
 				"endpoints": gin.H{
-					"auth":     "/api/mongo/v1/auth",
-					"services": "/api/mongo/v1/services",
-					"bookings": "/api/mongo/v1/bookings",
-					"users":    "/api/mongo/v1/users",
-					"admin":    "/api/mongo/v1/admin",
+					"auth":        "/api/mongo/v1/auth",
+					"services":    "/api/mongo/v1/services",
+					"bookings":    "/api/mongo/v1/bookings",
+					"vendor":      "/api/mongo/v1/vendor",
+					"technicians": "/api/mongo/v1/technicians",
+					"quotes":      "/api/mongo/v1/quotes",
+					"users":       "/api/mongo/v1/users",
+					"admin":       "/api/mongo/v1/admin",
 				},
 				"description": "Food Delivery API with MongoDB backend",
 				"health":      "/health",
@@ -82,8 +86,11 @@ func (r *APIRouter) SetupMongoDBRoutes() {
 			// Add a handler for the /services root path
 			serviceRoutes.GET("", services.GetServices)
 			serviceRoutes.GET("/categories", services.GetServiceCategories)
+			serviceRoutes.GET("/dietary-options", services.GetDietaryOptions)
 			serviceRoutes.GET("/search", services.SearchServices)
 			serviceRoutes.GET("/:id", services.GetServiceByID)
+			serviceRoutes.GET("/:id/recommendations", services.GetServiceRecommendations)
+			serviceRoutes.GET("/:id/availability", services.GetServiceAvailability)
 			log.Println("Registered service endpoints")
 		}
 
@@ -95,12 +102,63 @@ func (r *APIRouter) SetupMongoDBRoutes() {
 			// Add a handler for the /bookings root path
 			bookings.GET("", services.GetUserBookings)
 			bookings.POST("", services.CreateBooking)
+			bookings.POST("/series", services.CreateBookingSeries)
+			bookings.GET("/series", services.GetUserBookingSeries)
+			bookings.GET("/series/:id", services.GetBookingSeriesByID)
+			bookings.POST("/series/:id/skip", services.SkipSeriesOccurrence)
+			bookings.DELETE("/series/:id", services.CancelBookingSeries)
+			bookings.POST("/waitlist", services.JoinWaitlist)
+			bookings.GET("/waitlist", services.GetUserWaitlist)
+			bookings.GET("/waitlist/:id", services.GetWaitlistEntry)
+			bookings.POST("/waitlist/:id/confirm", services.ConfirmWaitlistHold)
+			bookings.DELETE("/waitlist/:id", services.LeaveWaitlist)
 			bookings.GET("/:id", services.GetBookingByID)
 			bookings.PUT("/:id", services.UpdateBooking)
 			bookings.DELETE("/:id", services.CancelBooking)
+			bookings.POST("/:id/reschedule", services.RescheduleBooking)
+			bookings.GET("/:id/cancellation", services.PreviewBookingCancellation)
+			bookings.POST("/:id/review", services.CreateBookingReview)
+			bookings.GET("/:id/calendar.ics", services.GetBookingCalendar)
+			bookings.POST("/:id/payment", services.CreateBookingPayment)
 			log.Println("Registered booking endpoints")
 		}
 
+		// Vendor routes (for vendor owners managing their staff)
+		vendor := mongoV1.Group("/vendor", services.RequireVendorOwner())
+		log.Println("Created vendor group: /api/mongo/v1/vendor")
+
+		{
+			vendor.GET("/bookings", services.GetVendorBookings)
+			vendor.PUT("/bookings/:id/technician", services.AssignBookingTechnician)
+			vendor.DELETE("/bookings/:id/technician", services.UnassignBookingTechnician)
+			vendor.POST("/technicians", services.CreateTechnician)
+			vendor.GET("/technicians", services.GetTechnicians)
+			vendor.PUT("/technicians/:id", services.UpdateTechnician)
+			vendor.DELETE("/technicians/:id", services.DeleteTechnician)
+			vendor.GET("/reviews", services.GetVendorReviews)
+			vendor.PUT("/reviews/:id/reply", services.ReplyToReview)
+			log.Println("Registered vendor endpoints")
+		}
+
+		// Technician routes
+		technicians := mongoV1.Group("/technicians")
+		log.Println("Created technicians group: /api/mongo/v1/technicians")
+
+		{
+			technicians.GET("/me/jobs", services.GetTechnicianJobs)
+			technicians.PUT("/me/jobs/:id/status", services.UpdateTechnicianJobStatus)
+			log.Println("Registered technician endpoints")
+		}
+
+		// Quote routes; cart quotes find the cart like the cart routes, from the JWT or X-Cart-Token
+		mongoV1.POST("/quotes", middleware.OptionalAuthMiddleware(), services.CreateQuote)
+
+		// Calendar feeds, authenticated by the secret token in the URL
+		mongoV1.GET("/calendar/:token", services.GetCalendarFeed)
+
+		// Payment webhooks, authenticated by the provider's signature
+		mongoV1.POST("/payments/webhooks/:provider", services.HandlePaymentWebhook)
+
 		// User routes
 		users := mongoV1.Group("/users")
 		log.Println("Created users group: /api/mongo/v1/users")
@@ -114,16 +172,28 @@ func (r *APIRouter) SetupMongoDBRoutes() {
 					"endpoints": gin.H{
 						"profile":        "GET /api/mongo/v1/users/profile",
 						"update_profile": "PUT /api/mongo/v1/users/profile",
+						"dietary":        "GET|PUT /api/mongo/v1/users/dietary-preferences",
+						"recommended":    "GET /api/mongo/v1/users/me/recommendations",
 						"notifications":  "GET /api/mongo/v1/users/notifications",
 						"mark_read":      "PUT /api/mongo/v1/users/notifications/:id/read",
+						"calendar_feed":  "POST|DELETE /api/mongo/v1/users/calendar-feed",
+						"wallet":         "GET /api/mongo/v1/users/wallet",
+						"statement":      "GET /api/mongo/v1/users/wallet/statement",
 					},
 					"description": "Use these endpoints for user profile and notification management",
 				})
 			})
 			users.GET("/profile", services.GetUserProfile)
 			users.PUT("/profile", services.UpdateUserProfile)
+			users.GET("/dietary-preferences", services.GetDietaryPreferences)
+			users.PUT("/dietary-preferences", services.UpdateDietaryPreferences)
 			users.GET("/notifications", services.GetUserNotifications)
+			users.GET("/me/recommendations", services.GetUserRecommendations)
 			users.PUT("/notifications/:id/read", services.MarkNotificationAsRead)
+			users.POST("/calendar-feed", services.CreateCalendarFeed)
+			users.DELETE("/calendar-feed", services.DeleteCalendarFeed)
+			users.GET("/wallet", services.GetWallet)
+			users.GET("/wallet/statement", services.GetWalletStatement)
 			log.Println("Registered user endpoints")
 		}
 
@@ -141,6 +211,20 @@ func (r *APIRouter) SetupMongoDBRoutes() {
 						"bookings":      "GET /api/mongo/v1/admin/bookings",
 						"update_status": "PUT /api/mongo/v1/admin/bookings/:id/status",
 						"dashboard":     "GET /api/mongo/v1/admin/dashboard",
+						"import":        "POST /api/mongo/v1/admin/catalog/import",
+						"export":        "GET /api/mongo/v1/admin/catalog/export",
+						"categories":    "POST /api/mongo/v1/admin/categories",
+						"promotions":    "GET|POST /api/mongo/v1/admin/promotions",
+						"slots":         "GET|POST /api/mongo/v1/admin/slot-templates",
+						"cancellation":  "GET|POST /api/mongo/v1/admin/cancellation-policies",
+						"technicians":   "GET|POST /api/mongo/v1/admin/technicians",
+						"assign":        "PUT|DELETE /api/mongo/v1/admin/bookings/:id/technician",
+						"reviews":       "POST /api/mongo/v1/admin/reviews/backfill",
+						"timezone":      "PUT /api/mongo/v1/admin/vendors/:id/timezone",
+						"money":         "POST /api/mongo/v1/admin/money/backfill",
+						"booking_times": "POST /api/mongo/v1/admin/bookings/backfill",
+						"wallets":       "GET /api/mongo/v1/admin/wallets/:id",
+						"wallet_adjust": "POST /api/mongo/v1/admin/wallets/:id/credit|debit",
 					},
 					"description": "Use these endpoints for admin panel functionality (requires admin privileges)",
 				})
@@ -148,6 +232,38 @@ func (r *APIRouter) SetupMongoDBRoutes() {
 			admin.GET("/bookings", services.GetAllBookings)
 			admin.PUT("/bookings/:id/status", services.UpdateBookingStatus)
 			admin.GET("/dashboard", services.GetDashboardStats)
+			admin.POST("/catalog/import", services.ImportCatalog)
+			admin.GET("/catalog/export", services.ExportCatalog)
+			admin.POST("/categories", services.CreateCategory)
+			admin.POST("/categories/backfill", services.BackfillCategories)
+			admin.PUT("/categories/:id", services.UpdateCategory)
+			admin.DELETE("/categories/:id", services.DeleteCategory)
+			admin.PUT("/services/:id/category", services.SetServiceCategory)
+			admin.POST("/promotions", services.CreatePromotion)
+			admin.GET("/promotions", services.GetPromotions)
+			admin.GET("/promotions/:id", services.GetPromotion)
+			admin.PUT("/promotions/:id", services.UpdatePromotion)
+			admin.POST("/slot-templates", services.CreateSlotTemplate)
+			admin.GET("/slot-templates", services.GetSlotTemplates)
+			admin.PUT("/slot-templates/:id", services.UpdateSlotTemplate)
+			admin.DELETE("/slot-templates/:id", services.DeleteSlotTemplate)
+			admin.POST("/cancellation-policies", services.CreateCancellationPolicy)
+			admin.GET("/cancellation-policies", services.GetCancellationPolicies)
+			admin.PUT("/cancellation-policies/:id", services.UpdateCancellationPolicy)
+			admin.DELETE("/cancellation-policies/:id", services.DeleteCancellationPolicy)
+			admin.POST("/technicians", services.CreateTechnician)
+			admin.GET("/technicians", services.GetTechnicians)
+			admin.PUT("/technicians/:id", services.UpdateTechnician)
+			admin.DELETE("/technicians/:id", services.DeleteTechnician)
+			admin.PUT("/bookings/:id/technician", services.AssignBookingTechnician)
+			admin.DELETE("/bookings/:id/technician", services.UnassignBookingTechnician)
+			admin.POST("/reviews/backfill", services.BackfillReviews)
+			admin.PUT("/vendors/:id/timezone", services.SetVendorTimezone)
+			admin.POST("/money/backfill", services.BackfillMoney)
+			admin.POST("/bookings/backfill", services.BackfillBookingTimes)
+			admin.GET("/wallets/:id", services.GetUserWallet)
+			admin.POST("/wallets/:id/credit", services.CreditWallet)
+			admin.POST("/wallets/:id/debit", services.DebitWallet)
 			log.Println("Registered admin endpoints")
 		}
 	}
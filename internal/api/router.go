@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/code-harsh006/food-delivery/internal/cart"
 	"github.com/code-harsh006/food-delivery/internal/notification"
+	"github.com/code-harsh006/food-delivery/internal/order"
 	"github.com/code-harsh006/food-delivery/pkg/middleware"
 	"github.com/code-harsh006/food-delivery/pkg/response"
 	"github.com/gin-gonic/gin"
@@ -38,6 +40,10 @@ func (r *APIRouter) SetupRoutes() {
 		// API documentation
 		api.GET("/docs", r.docsHandler)
 
+		// Cart routes (anonymous carts use the X-Cart-Token header)
+		cartModule := cart.NewModule()
+		cartModule.SetupRoutes(api, middleware.OptionalAuthMiddleware())
+
 		// Protected routes (require authentication)
 		protected := api.Group("")
 		protected.Use(middleware.AuthMiddleware())
@@ -45,6 +51,10 @@ func (r *APIRouter) SetupRoutes() {
 			// Notification routes
 			notificationModule := notification.NewModule()
 			notificationModule.SetupRoutes(protected, middleware.AuthMiddleware())
+
+			// Order routes
+			orderModule := order.NewModule()
+			orderModule.SetupRoutes(protected, middleware.AuthMiddleware())
 		}
 	}
 
@@ -229,6 +239,53 @@ func (r *APIRouter) docsHandler(c *gin.Context) {
 				"request_body":     "None (WebSocket connection)",
 				"response_example": "WebSocket connection established",
 			},
+			"orders_place": gin.H{
+				"method":         "POST",
+				"path":           "/api/v1/orders",
+				"description":    "Place an order from the current cart (all items from one vendor)",
+				"authentication": "Required (JWT)",
+				"request_body": gin.H{
+					"delivery_address": gin.H{
+						"name":         "John Doe",
+						"phone":        "+1234567890",
+						"address":      "123 Main St",
+						"instructions": "Ring the bell",
+					},
+					"notes":       "No cutlery",
+					"distance_km": 3.2,
+					"region":      "CA",
+					"tip_percent": 15,
+				},
+				"response_example": gin.H{
+					"message": "Order placed successfully",
+					"order": gin.H{
+						"id":     "order_123456",
+						"status": "placed",
+						"pricing": gin.H{
+							"currency":     "USD",
+							"subtotal":     "24.50",
+							"delivery_fee": "3.59",
+							"service_fee":  "1.23",
+							"tax":          "2.13",
+							"tip":          "3.68",
+							"total":        "35.13",
+						},
+						"total": "35.13",
+					},
+				},
+			},
+			"orders_cancel": gin.H{
+				"method":         "PUT",
+				"path":           "/api/v1/orders/:id/cancel",
+				"description":    "Cancel an order the vendor has not accepted yet",
+				"authentication": "Required (JWT)",
+				"request_body": gin.H{
+					"reason": "Ordered by mistake",
+				},
+				"response_example": gin.H{
+					"message": "Order cancelled successfully",
+				},
+			},
 			"mongo_health": gin.H{
 				"method":       "GET",
 				"path":         "/health",
@@ -335,7 +392,7 @@ func (r *APIRouter) docsHandler(c *gin.Context) {
 								"id":          "service_123",
 								"name":        "Food Delivery",
 								"description": "Fast food delivery service",
-								"price":       15.99,
+								"price":       "15.99",
 								"category":    "food",
 								"rating":      4.5,
 								"image_url":   "https://example.com/food.jpg",
@@ -359,7 +416,7 @@ func (r *APIRouter) docsHandler(c *gin.Context) {
 						"id":          "service_123",
 						"name":        "Food Delivery",
 						"description": "Fast food delivery service",
-						"price":       15.99,
+						"price":       "15.99",
 						"category":    "food",
 						"rating":      4.5,
 						"image_url":   "https://example.com/food.jpg",
@@ -374,23 +431,47 @@ func (r *APIRouter) docsHandler(c *gin.Context) {
 			"services_categories": gin.H{
 				"method":         "GET",
 				"path":           "/api/mongo/v1/services/categories",
-				"description":    "Get all service categories",
+				"description":    "Get the category tree with active service counts",
 				"authentication": "Not required",
 				"request_body":   "None",
+				"query_parameters": gin.H{
+					"include_inactive": "true|false",
+				},
 				"response_example": gin.H{
-					"success": true,
-					"data": []gin.H{
+					"categories": []gin.H{
 						gin.H{
 							"id":          "cat_1",
 							"name":        "Food",
+							"slug":        "food",
 							"description": "Food delivery services",
 							"icon":        "🍕",
+							"sort_order":  1,
+							"is_active":   true,
+							"item_count":  3,
+							"children": []gin.H{
+								gin.H{
+									"id":         "cat_3",
+									"name":       "Meal Prep",
+									"slug":       "meal-prep",
+									"parent_id":  "cat_1",
+									"icon":       "🥗",
+									"sort_order": 1,
+									"is_active":  true,
+									"item_count": 1,
+									"children":   []gin.H{},
+								},
+							},
 						},
 						gin.H{
 							"id":          "cat_2",
 							"name":        "Cleaning",
+							"slug":        "cleaning",
 							"description": "Cleaning services",
 							"icon":        "🧹",
+							"sort_order":  2,
+							"is_active":   true,
+							"item_count":  4,
+							"children":    []gin.H{},
 						},
 					},
 				},
@@ -416,7 +497,7 @@ func (r *APIRouter) docsHandler(c *gin.Context) {
 								"id":          "service_123",
 								"name":        "Food Delivery",
 								"description": "Fast food delivery service",
-								"price":       15.99,
+								"price":       "15.99",
 								"rating":      4.5,
 							},
 						},
@@ -470,11 +551,11 @@ func (r *APIRouter) docsHandler(c *gin.Context) {
 								"service": gin.H{
 									"id":    "service_123",
 									"name":  "Food Delivery",
-									"price": 15.99,
+									"price": "15.99",
 								},
 								"status":         "pending",
 								"scheduled_date": "2025-07-20T14:00:00Z",
-								"total_amount":   15.99,
+								"total_amount":   "15.99",
 							},
 						},
 						"total": 1,
@@ -497,7 +578,7 @@ func (r *APIRouter) docsHandler(c *gin.Context) {
 							"id":          "service_123",
 							"name":        "Food Delivery",
 							"description": "Fast food delivery service",
-							"price":       15.99,
+							"price":       "15.99",
 						},
 						"status":         "pending",
 						"scheduled_date": "2025-07-20T14:00:00Z",
@@ -507,7 +588,7 @@ func (r *APIRouter) docsHandler(c *gin.Context) {
 							"state":    "NY",
 							"zip_code": "10001",
 						},
-						"total_amount":         15.99,
+						"total_amount":         "15.99",
 						"special_instructions": "Please deliver to front door",
 					},
 				},
@@ -669,7 +750,7 @@ func (r *APIRouter) docsHandler(c *gin.Context) {
 								},
 								"status":         "pending",
 								"scheduled_date": "2025-07-20T14:00:00Z",
-								"total_amount":   15.99,
+								"total_amount":   "15.99",
 							},
 						},
 						"total": 1,
@@ -710,14 +791,14 @@ func (r *APIRouter) docsHandler(c *gin.Context) {
 						"pending_bookings":   12,
 						"completed_bookings": 28,
 						"cancelled_bookings": 5,
-						"total_revenue":      1250.75,
+						"total_revenue":      "1250.75",
 						"recent_bookings": []gin.H{
 							gin.H{
 								"id":           "booking_789",
 								"user_name":    "John Doe",
 								"service_name": "Food Delivery",
 								"status":       "pending",
-								"amount":       15.99,
+								"amount":       "15.99",
 							},
 						},
 					},
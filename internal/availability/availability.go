@@ -0,0 +1,360 @@
+package availability
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/code-harsh006/food-delivery/internal/models"
+	"github.com/code-harsh006/food-delivery/pkg/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DateFormat is the layout of the dates slots are booked on
+const DateFormat = "2006-01-02"
+
+// defaultSlotMinutes is the slot length for vendor templates without one, and for service
+// templates without one when the service has no duration
+const defaultSlotMinutes = 60
+
+var (
+	ErrSlotUnavailable = errors.New("the selected time is not a bookable slot")
+	ErrSlotFull        = errors.New("the selected slot is fully booked")
+)
+
+// schedule is the set of slot templates that apply to a service and the scope their capacity is
+// counted in: the service itself, or the vendor when the templates are shared by its services
+type schedule struct {
+	scope     string
+	shared    bool
+	templates []models.SlotTemplate
+}
+
+// candidate is a possible booking start and the capacity cells it would hold
+type candidate struct {
+	start    int
+	end      int
+	capacity int
+	cells    []cell
+}
+
+// cell is one slot of a schedule on one day, identified by its slot_capacity ID
+type cell struct {
+	id    string
+	start int
+}
+
+// Slots returns the bookable start times of a service on a date for a booking lasting duration
+// minutes, or the service's own duration when it is 0. The second result is false when neither
+// the service nor its vendor has a schedule, in which case any time may be booked.
+// Dates here are the midnight the day starts at in the booking's timezone, so slot times are
+// local to it.
+func Slots(ctx context.Context, service models.Service, date time.Time, duration int) ([]models.Slot, bool, error) {
+	sched, err := loadSchedule(ctx, service)
+	if err != nil {
+		return nil, false, err
+	}
+	if sched == nil {
+		return []models.Slot{}, false, nil
+	}
+
+	candidates := sched.candidates(service, date, duration)
+	ids := make([]string, 0)
+	for _, cand := range candidates {
+		for _, c := range cand.cells {
+			ids = append(ids, c.id)
+		}
+	}
+
+	booked := make(map[string]int)
+	if len(ids) > 0 {
+		cursor, err := db.GetMongoDB().Collection("slot_capacity").Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+		if err != nil {
+			return nil, true, err
+		}
+		var counts []models.SlotCapacity
+		if err := cursor.All(ctx, &counts); err != nil {
+			return nil, true, err
+		}
+		for _, count := range counts {
+			booked[count.ID] = count.Booked
+		}
+	}
+
+	now := time.Now()
+	slots := make([]models.Slot, 0, len(candidates))
+	for _, cand := range candidates {
+		remaining := cand.capacity
+		for _, c := range cand.cells {
+			if left := cand.capacity - booked[c.id]; left < remaining {
+				remaining = left
+			}
+		}
+		if remaining < 0 {
+			remaining = 0
+		}
+
+		slots = append(slots, models.Slot{
+			StartTime: FormatClock(cand.start),
+			EndTime:   FormatClock(cand.end),
+			Capacity:  cand.capacity,
+			Remaining: remaining,
+			Available: remaining > 0 && startsAt(date, cand.start).After(now),
+		})
+	}
+
+	return slots, true, nil
+}
+
+// Claim takes one unit of capacity in every slot a booking starting at startTime on date and
+// lasting duration minutes (0 for the service's own duration) covers. Slots in held, the ones a
+// booking being moved already holds, are kept without taking another unit. Run it inside the
+// transaction that stores the booking and keep the returned IDs on the booking so they can be
+// released. It returns no IDs and no error for services without a schedule.
+func Claim(ctx context.Context, service models.Service, date time.Time, startTime string, duration int, held []string) ([]string, error) {
+	sched, err := loadSchedule(ctx, service)
+	if err != nil || sched == nil {
+		return nil, err
+	}
+
+	start, err := ParseClock(startTime)
+	if err != nil || !startsAt(date, start).After(time.Now()) {
+		return nil, ErrSlotUnavailable
+	}
+
+	var chosen *candidate
+	for _, cand := range sched.candidates(service, date, duration) {
+		if cand.start == start {
+			chosen = &cand
+			break
+		}
+	}
+	if chosen == nil {
+		return nil, ErrSlotUnavailable
+	}
+
+	keep := make(map[string]bool, len(held))
+	for _, id := range held {
+		keep[id] = true
+	}
+
+	collection := db.GetMongoDB().Collection("slot_capacity")
+	cells := make([]string, 0, len(chosen.cells))
+	claimed := make([]string, 0, len(chosen.cells))
+	for _, c := range chosen.cells {
+		if keep[c.id] {
+			cells = append(cells, c.id)
+			continue
+		}
+
+		// Take the unit first and check the count after: the increment is atomic, so of two
+		// concurrent claims on the last unit exactly one sees a count within capacity
+		var count models.SlotCapacity
+		err := collection.FindOneAndUpdate(ctx,
+			bson.M{"_id": c.id},
+			bson.M{
+				"$inc": bson.M{"booked": 1},
+				"$setOnInsert": bson.M{
+					"scope":      sched.scope,
+					"date":       date.Format(DateFormat),
+					"start_time": FormatClock(c.start),
+				},
+			},
+			options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+		).Decode(&count)
+		if err != nil {
+			Release(ctx, claimed)
+			return nil, err
+		}
+		claimed = append(claimed, c.id)
+		cells = append(cells, c.id)
+
+		if count.Booked > chosen.capacity {
+			// Outside a transaction nothing is rolled back, so give the units back explicitly
+			Release(ctx, claimed)
+			return nil, ErrSlotFull
+		}
+	}
+
+	return cells, nil
+}
+
+// Cells returns the slot_capacity IDs a booking starting at startTime on date and lasting
+// duration minutes (0 for the service's own duration) would hold, and how many more bookings the
+// slot has room for. It returns no IDs and no error for services without a
+// schedule.
+func Cells(ctx context.Context, service models.Service, date time.Time, startTime string, duration int) ([]string, int, error) {
+	sched, err := loadSchedule(ctx, service)
+	if err != nil || sched == nil {
+		return nil, 0, err
+	}
+
+	start, err := ParseClock(startTime)
+	if err != nil || !startsAt(date, start).After(time.Now()) {
+		return nil, 0, ErrSlotUnavailable
+	}
+
+	for _, cand := range sched.candidates(service, date, duration) {
+		if cand.start != start {
+			continue
+		}
+
+		ids := make([]string, 0, len(cand.cells))
+		for _, c := range cand.cells {
+			ids = append(ids, c.id)
+		}
+		var counts []models.SlotCapacity
+		cursor, err := db.GetMongoDB().Collection("slot_capacity").Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+		if err != nil {
+			return nil, 0, err
+		}
+		if err := cursor.All(ctx, &counts); err != nil {
+			return nil, 0, err
+		}
+
+		remaining := cand.capacity
+		for _, count := range counts {
+			if left := cand.capacity - count.Booked; left < remaining {
+				remaining = left
+			}
+		}
+		if remaining < 0 {
+			remaining = 0
+		}
+		return ids, remaining, nil
+	}
+	return nil, 0, ErrSlotUnavailable
+}
+
+// Release gives back the capacity held by a booking
+func Release(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := db.GetMongoDB().Collection("slot_capacity").UpdateMany(ctx,
+		bson.M{"_id": bson.M{"$in": ids}, "booked": bson.M{"$gt": 0}},
+		bson.M{"$inc": bson.M{"booked": -1}},
+	)
+	return err
+}
+
+// ParseClock converts an HH:MM time of day to minutes after midnight
+func ParseClock(value string) (int, error) {
+	clock, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, use HH:MM", value)
+	}
+	return clock.Hour()*60 + clock.Minute(), nil
+}
+
+// FormatClock converts minutes after midnight to HH:MM
+func FormatClock(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
+
+// loadSchedule returns the service's own active templates, or its vendor's shared templates when
+// it has none. It returns nil when there are neither.
+func loadSchedule(ctx context.Context, service models.Service) (*schedule, error) {
+	collection := db.GetMongoDB().Collection("slot_templates")
+
+	var templates []models.SlotTemplate
+	cursor, err := collection.Find(ctx, bson.M{"service_id": service.ID, "is_active": true})
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &templates); err != nil {
+		return nil, err
+	}
+	if len(templates) > 0 {
+		return &schedule{scope: "service:" + service.ID.Hex(), templates: templates}, nil
+	}
+
+	if service.VendorID.IsZero() {
+		return nil, nil
+	}
+	cursor, err = collection.Find(ctx, bson.M{
+		"vendor_id":  service.VendorID,
+		"service_id": bson.M{"$exists": false},
+		"is_active":  true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &templates); err != nil {
+		return nil, err
+	}
+	if len(templates) > 0 {
+		return &schedule{scope: "vendor:" + service.VendorID.Hex(), shared: true, templates: templates}, nil
+	}
+
+	return nil, nil
+}
+
+// candidates lists every start time on date at which a booking of duration minutes fits inside a
+// template's window. A booking holds every slot it overlaps.
+func (s *schedule) candidates(service models.Service, date time.Time, duration int) []candidate {
+	weekday := int(date.Weekday())
+	day := date.Format(DateFormat)
+
+	var result []candidate
+	for _, template := range s.templates {
+		if !containsWeekday(template.Weekdays, weekday) {
+			continue
+		}
+		open, err := ParseClock(template.StartTime)
+		if err != nil {
+			continue
+		}
+		closing, err := ParseClock(template.EndTime)
+		if err != nil {
+			continue
+		}
+
+		step := s.slotMinutes(template, service)
+		length := duration
+		if length <= 0 {
+			length = service.Duration
+		}
+		if length <= 0 {
+			length = step
+		}
+
+		for start := open; start+length <= closing; start += step {
+			cand := candidate{start: start, end: start + length, capacity: template.Capacity}
+			for at := start; at < start+length; at += step {
+				cand.cells = append(cand.cells, cell{id: fmt.Sprintf("%s:%s:%s", s.scope, day, FormatClock(at)), start: at})
+			}
+			result = append(result, cand)
+		}
+	}
+	return result
+}
+
+// slotMinutes returns the slot length of a template. Shared templates never fall back to the
+// service duration: every service of the vendor has to count its bookings in the same slots.
+func (s *schedule) slotMinutes(template models.SlotTemplate, service models.Service) int {
+	switch {
+	case template.SlotMinutes > 0:
+		return template.SlotMinutes
+	case !s.shared && service.Duration > 0:
+		return service.Duration
+	default:
+		return defaultSlotMinutes
+	}
+}
+
+func containsWeekday(weekdays []int, weekday int) bool {
+	for _, day := range weekdays {
+		if day == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+// startsAt returns the moment a slot starting minutes after midnight on date begins
+func startsAt(date time.Time, minutes int) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location()).Add(time.Duration(minutes) * time.Minute)
+}
@@ -0,0 +1,479 @@
+package cart
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/code-harsh006/food-delivery/internal/models"
+	"github.com/code-harsh006/food-delivery/internal/session"
+	"github.com/code-harsh006/food-delivery/pkg/config"
+	"github.com/code-harsh006/food-delivery/pkg/db"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TokenHeader carries the anonymous cart token for customers who have not logged in yet
+const TokenHeader = "X-Cart-Token"
+
+const maxQuantity = 99
+
+// errQuantityLimit is returned when adding to a line would take it past maxQuantity
+var errQuantityLimit = errors.New("a cart line can hold at most 99 of an item")
+
+type Module struct{}
+
+func NewModule() *Module {
+	return &Module{}
+}
+
+func (m *Module) SetupRoutes(router *gin.RouterGroup, authMiddleware gin.HandlerFunc) {
+	cart := router.Group("/cart")
+	cart.Use(authMiddleware)
+	{
+		cart.GET("", m.getCart)
+		cart.DELETE("", m.clearCart)
+		cart.POST("/items", m.addItem)
+		cart.PUT("/items/:id", m.updateItem)
+		cart.DELETE("/items/:id", m.removeItem)
+	}
+}
+
+// owner identifies whose cart a request addresses
+type owner struct {
+	userID primitive.ObjectID
+	token  string
+}
+
+func (o owner) filter() bson.M {
+	if !o.userID.IsZero() {
+		return bson.M{"user_id": o.userID}
+	}
+	return bson.M{"token": o.token}
+}
+
+func (m *Module) getCart(c *gin.Context) {
+	if !databaseAvailable(c) {
+		return
+	}
+
+	cartOwner, ok := resolveOwner(c, false)
+	if !ok {
+		return
+	}
+
+	cart, err := find(context.Background(), cartOwner)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch cart"})
+		return
+	}
+
+	view, err := Price(context.Background(), cart)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to price cart"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"cart": view})
+}
+
+func (m *Module) addItem(c *gin.Context) {
+	if !databaseAvailable(c) {
+		return
+	}
+
+	var req models.AddCartItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Quantity == 0 {
+		req.Quantity = 1
+	}
+	if req.Quantity < 1 || req.Quantity > maxQuantity {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Quantity must be between 1 and 99"})
+		return
+	}
+
+	menuItemID, err := primitive.ObjectIDFromHex(req.MenuItemID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid menu item ID"})
+		return
+	}
+
+	var menuItem models.MenuItem
+	err = db.GetMongoDB().Collection("menu_items").FindOne(context.Background(), bson.M{"_id": menuItemID}).Decode(&menuItem)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Menu item not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if !menuItem.IsAvailable {
+		c.JSON(http.StatusConflict, gin.H{"error": "Menu item is not available"})
+		return
+	}
+
+	modifiers, modifiersPrice, err := ResolveModifiers(menuItem, req.Modifiers)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cartOwner, ok := resolveOwner(c, true)
+	if !ok {
+		return
+	}
+
+	line := models.CartItem{
+		ID:           primitive.NewObjectID(),
+		MenuItemID:   menuItem.ID,
+		Quantity:     req.Quantity,
+		Modifiers:    modifiers,
+		ModifiersKey: modifiersKey(modifiers),
+		Notes:        req.Notes,
+		UnitPrice:    menuItem.Price.Add(modifiersPrice),
+		AddedAt:      time.Now(),
+	}
+	if err := addLine(context.Background(), cartOwner, line); err != nil {
+		if errors.Is(err, errQuantityLimit) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Quantity must be between 1 and 99"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add item to cart"})
+		return
+	}
+
+	respondWithCart(c, cartOwner, http.StatusCreated, "Item added to cart")
+}
+
+func (m *Module) updateItem(c *gin.Context) {
+	if !databaseAvailable(c) {
+		return
+	}
+
+	lineID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cart item ID"})
+		return
+	}
+
+	var req models.UpdateCartItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Quantity < 1 || req.Quantity > maxQuantity {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Quantity must be between 1 and 99"})
+		return
+	}
+
+	cartOwner, ok := resolveOwner(c, false)
+	if !ok {
+		return
+	}
+
+	filter := cartOwner.filter()
+	filter["items._id"] = lineID
+	result, err := db.GetMongoDB().Collection("carts").UpdateOne(context.Background(), filter, bson.M{
+		"$set": bson.M{
+			"items.$.quantity": req.Quantity,
+			"updated_at":       time.Now(),
+			"expires_at":       expiresAt(),
+		},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update cart item"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cart item not found"})
+		return
+	}
+
+	respondWithCart(c, cartOwner, http.StatusOK, "Cart item updated")
+}
+
+func (m *Module) removeItem(c *gin.Context) {
+	if !databaseAvailable(c) {
+		return
+	}
+
+	lineID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cart item ID"})
+		return
+	}
+
+	cartOwner, ok := resolveOwner(c, false)
+	if !ok {
+		return
+	}
+
+	result, err := db.GetMongoDB().Collection("carts").UpdateOne(context.Background(), cartOwner.filter(), bson.M{
+		"$pull": bson.M{"items": bson.M{"_id": lineID}},
+		"$set":  bson.M{"updated_at": time.Now(), "expires_at": expiresAt()},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove cart item"})
+		return
+	}
+	if result.ModifiedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cart item not found"})
+		return
+	}
+
+	respondWithCart(c, cartOwner, http.StatusOK, "Cart item removed")
+}
+
+func (m *Module) clearCart(c *gin.Context) {
+	if !databaseAvailable(c) {
+		return
+	}
+
+	cartOwner, ok := resolveOwner(c, false)
+	if !ok {
+		return
+	}
+
+	if err := clearItems(context.Background(), cartOwner); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear cart"})
+		return
+	}
+
+	respondWithCart(c, cartOwner, http.StatusOK, "Cart cleared")
+}
+
+// Load returns the user's cart, or an empty cart when they have none
+func Load(ctx context.Context, userID primitive.ObjectID) (*models.Cart, error) {
+	return find(ctx, owner{userID: userID})
+}
+
+// Current returns the cart of the caller, identified the way the cart routes identify them: the
+// logged-in user, or the anonymous cart token. It writes the error response and returns false
+// when the caller cannot be resolved.
+func Current(c *gin.Context) (*models.Cart, bool) {
+	cartOwner, ok := resolveOwner(c, false)
+	if !ok {
+		return nil, false
+	}
+	cart, err := find(c.Request.Context(), cartOwner)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch cart"})
+		return nil, false
+	}
+	return cart, true
+}
+
+// Clear empties the user's cart
+func Clear(ctx context.Context, userID primitive.ObjectID) error {
+	return clearItems(ctx, owner{userID: userID})
+}
+
+// MergeAnonymousCart moves the lines of an anonymous cart into the user's cart and deletes
+// the anonymous cart. Lines for the same item and modifiers are combined. The anonymous cart is
+// deleted last, so a merge that fails part-way never loses its lines.
+func MergeAnonymousCart(ctx context.Context, token string, userID primitive.ObjectID) error {
+	mongoDB := db.GetMongoDB()
+	if token == "" || userID.IsZero() || mongoDB == nil {
+		return nil
+	}
+
+	return db.WithTransaction(ctx, func(ctx context.Context) error {
+		collection := mongoDB.Collection("carts")
+
+		var anonymous models.Cart
+		if err := collection.FindOne(ctx, bson.M{"token": token}).Decode(&anonymous); err != nil {
+			if err == mongo.ErrNoDocuments {
+				return nil
+			}
+			return err
+		}
+
+		userOwner := owner{userID: userID}
+		for _, line := range anonymous.Items {
+			err := addLine(ctx, userOwner, line)
+			if errors.Is(err, errQuantityLimit) {
+				// Lines the user already has plenty of are filled up to the limit
+				err = fillLine(ctx, userOwner, line)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err := collection.DeleteOne(ctx, bson.M{"_id": anonymous.ID})
+		return err
+	})
+}
+
+// find returns the owner's cart, or an empty unsaved cart when none exists
+func find(ctx context.Context, cartOwner owner) (*models.Cart, error) {
+	cart := &models.Cart{UserID: cartOwner.userID, Token: cartOwner.token, Items: []models.CartItem{}}
+	if cartOwner.userID.IsZero() && cartOwner.token == "" {
+		return cart, nil
+	}
+
+	err := db.GetMongoDB().Collection("carts").FindOne(ctx, cartOwner.filter()).Decode(cart)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+
+	return cart, nil
+}
+
+// addLine increments a matching line or appends a new one, creating the cart when needed. It
+// fails with errQuantityLimit when the matching line would hold more than maxQuantity.
+func addLine(ctx context.Context, cartOwner owner, line models.CartItem) error {
+	collection := db.GetMongoDB().Collection("carts")
+	now := time.Now()
+	same := bson.M{"menu_item_id": line.MenuItemID, "modifiers_key": line.ModifiersKey}
+
+	increment := func() (bool, error) {
+		filter := cartOwner.filter()
+		filter["items"] = bson.M{"$elemMatch": bson.M{
+			"menu_item_id":  line.MenuItemID,
+			"modifiers_key": line.ModifiersKey,
+			"quantity":      bson.M{"$lte": maxQuantity - line.Quantity},
+		}}
+		result, err := collection.UpdateOne(ctx, filter, bson.M{
+			"$inc": bson.M{"items.$.quantity": line.Quantity},
+			"$set": bson.M{"updated_at": now, "expires_at": expiresAt()},
+		})
+		return err == nil && result.MatchedCount > 0, err
+	}
+	if done, err := increment(); done || err != nil {
+		return err
+	}
+
+	// Only push when the cart has no matching line, so a full line is never duplicated
+	filter := cartOwner.filter()
+	filter["items"] = bson.M{"$not": bson.M{"$elemMatch": same}}
+	result, err := collection.UpdateOne(ctx, filter, bson.M{
+		"$push": bson.M{"items": line},
+		"$set":  bson.M{"updated_at": now, "expires_at": expiresAt()},
+	})
+	if err != nil || result.MatchedCount > 0 {
+		return err
+	}
+
+	existing, err := collection.CountDocuments(ctx, cartOwner.filter())
+	if err != nil {
+		return err
+	}
+	if existing > 0 {
+		// The line is there and full, unless it was added concurrently and has room
+		if done, err := increment(); done || err != nil {
+			return err
+		}
+		return errQuantityLimit
+	}
+
+	_, err = collection.UpdateOne(ctx, cartOwner.filter(), bson.M{
+		"$push":        bson.M{"items": line},
+		"$set":         bson.M{"updated_at": now, "expires_at": expiresAt()},
+		"$setOnInsert": bson.M{"created_at": now},
+	}, options.Update().SetUpsert(true))
+	return err
+}
+
+// fillLine raises the owner's line matching line to maxQuantity
+func fillLine(ctx context.Context, cartOwner owner, line models.CartItem) error {
+	filter := cartOwner.filter()
+	filter["items"] = bson.M{"$elemMatch": bson.M{"menu_item_id": line.MenuItemID, "modifiers_key": line.ModifiersKey}}
+	_, err := db.GetMongoDB().Collection("carts").UpdateOne(ctx, filter, bson.M{
+		"$set": bson.M{"items.$.quantity": maxQuantity, "updated_at": time.Now(), "expires_at": expiresAt()},
+	})
+	return err
+}
+
+// clearItems removes every line from the owner's cart
+func clearItems(ctx context.Context, cartOwner owner) error {
+	_, err := db.GetMongoDB().Collection("carts").UpdateOne(ctx, cartOwner.filter(), bson.M{
+		"$set": bson.M{
+			"items":      []models.CartItem{},
+			"updated_at": time.Now(),
+			"expires_at": expiresAt(),
+		},
+	})
+	return err
+}
+
+// resolveOwner identifies the cart owner from the authenticated user or the cart token header.
+// When create is set, anonymous callers without a token are issued a new one.
+func resolveOwner(c *gin.Context, create bool) (owner, bool) {
+	if c.GetString("user_email") != "" {
+		userID, err := session.UserID(c)
+		if err != nil {
+			if err == session.ErrNotAuthenticated {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve user"})
+			}
+			return owner{}, false
+		}
+		return owner{userID: userID}, true
+	}
+
+	token := c.GetHeader(TokenHeader)
+	if token == "" && create {
+		b := make([]byte, 24)
+		if _, err := rand.Read(b); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create cart"})
+			return owner{}, false
+		}
+		token = hex.EncodeToString(b)
+	}
+	if token != "" {
+		c.Header(TokenHeader, token)
+	}
+
+	return owner{token: token}, true
+}
+
+// respondWithCart writes the freshly priced cart after a change
+func respondWithCart(c *gin.Context, cartOwner owner, status int, message string) {
+	cart, err := find(context.Background(), cartOwner)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch cart"})
+		return
+	}
+
+	view, err := Price(context.Background(), cart)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to price cart"})
+		return
+	}
+
+	body := gin.H{"message": message, "cart": view}
+	if cartOwner.userID.IsZero() {
+		body["cart_token"] = cartOwner.token
+	}
+	c.JSON(status, body)
+}
+
+// databaseAvailable writes a 503 when MongoDB is not connected
+func databaseAvailable(c *gin.Context) bool {
+	if db.GetMongoDB() == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return false
+	}
+	return true
+}
+
+// expiresAt returns when a cart touched now becomes stale
+func expiresAt() time.Time {
+	return time.Now().Add(time.Duration(config.Load().CartExpiryHours) * time.Hour)
+}
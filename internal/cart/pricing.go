@@ -0,0 +1,196 @@
+package cart
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/code-harsh006/food-delivery/internal/models"
+	"github.com/code-harsh006/food-delivery/internal/pricing"
+	"github.com/code-harsh006/food-delivery/pkg/db"
+	"github.com/code-harsh006/food-delivery/pkg/money"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Price revalidates every cart line against the current menu. Lines whose item or modifiers are no
+// longer available are flagged and left out of the subtotal, and lines whose price moved since they
+// were added are marked so the client can tell the customer before checkout.
+func Price(ctx context.Context, cart *models.Cart) (*models.CartView, error) {
+	view := &models.CartView{
+		ID:        cart.ID,
+		Lines:     []models.CartLine{},
+		Subtotal:  money.New(0, money.Currency()),
+		UpdatedAt: cart.UpdatedAt,
+		ExpiresAt: cart.ExpiresAt,
+	}
+	if len(cart.Items) == 0 {
+		return view, nil
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(cart.Items))
+	for _, item := range cart.Items {
+		ids = append(ids, item.MenuItemID)
+	}
+
+	cursor, err := db.GetMongoDB().Collection("menu_items").Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	var menuItems []models.MenuItem
+	if err := cursor.All(ctx, &menuItems); err != nil {
+		return nil, err
+	}
+
+	byID := make(map[primitive.ObjectID]models.MenuItem, len(menuItems))
+	for _, menuItem := range menuItems {
+		byID[menuItem.ID] = menuItem
+	}
+
+	repriced := make([]mongo.WriteModel, 0)
+	for _, item := range cart.Items {
+		line := models.CartLine{CartItem: item, UnitPrice: item.UnitPrice}
+
+		menuItem, ok := byID[item.MenuItemID]
+		switch {
+		case !ok:
+			line.Issues = append(line.Issues, "Menu item no longer exists")
+		case !menuItem.IsAvailable:
+			line.Name = menuItem.Name
+			line.Issues = append(line.Issues, "Menu item is not available")
+		default:
+			line.Name = menuItem.Name
+			if !menuItem.VendorID.IsZero() {
+				line.VendorID = menuItem.VendorID.Hex()
+			}
+
+			_, modifiersPrice, err := ResolveModifiers(menuItem, item.Modifiers)
+			if err != nil {
+				line.Issues = append(line.Issues, err.Error())
+				break
+			}
+
+			line.Available = true
+			line.ModifiersPrice = modifiersPrice
+			line.UnitPrice = menuItem.Price.Add(modifiersPrice)
+			line.LineTotal = line.UnitPrice.Mul(item.Quantity)
+			if line.UnitPrice != item.UnitPrice {
+				line.PriceChanged = true
+				repriced = append(repriced, mongo.NewUpdateOneModel().
+					SetFilter(bson.M{"_id": cart.ID, "items._id": item.ID}).
+					SetUpdate(bson.M{"$set": bson.M{"items.$.unit_price": line.UnitPrice}}))
+			}
+
+			view.ItemCount += item.Quantity
+			view.Subtotal = view.Subtotal.Add(line.LineTotal)
+		}
+
+		if !line.Available {
+			view.HasIssues = true
+		}
+		view.Lines = append(view.Lines, line)
+	}
+
+	// Store the new prices so a change is only reported once
+	if len(repriced) > 0 && !cart.ID.IsZero() {
+		if _, err := db.GetMongoDB().Collection("carts").BulkWrite(ctx, repriced); err != nil {
+			return nil, err
+		}
+	}
+
+	return view, nil
+}
+
+// ResolveModifiers checks the selected modifiers against the menu item's modifier groups and
+// returns them in a canonical order together with their combined price
+func ResolveModifiers(menuItem models.MenuItem, selected []models.SelectedModifier) ([]models.SelectedModifier, money.Money, error) {
+	groups := make(map[string]models.ModifierGroup, len(menuItem.ModifierGroups))
+	for _, group := range menuItem.ModifierGroups {
+		groups[group.Name] = group
+	}
+
+	resolved := make([]models.SelectedModifier, 0, len(selected))
+	perGroup := make(map[string]int)
+	seen := make(map[models.SelectedModifier]bool)
+	var total money.Money
+
+	for _, choice := range selected {
+		group, ok := groups[choice.Group]
+		if !ok {
+			return nil, money.Money{}, fmt.Errorf("unknown modifier group %q", choice.Group)
+		}
+
+		var option *models.ModifierOption
+		for i := range group.Options {
+			if group.Options[i].Name == choice.Option {
+				option = &group.Options[i]
+				break
+			}
+		}
+		if option == nil {
+			return nil, money.Money{}, fmt.Errorf("unknown option %q for modifier group %q", choice.Option, choice.Group)
+		}
+		if option.Unavailable {
+			return nil, money.Money{}, fmt.Errorf("option %q for modifier group %q is not available", choice.Option, choice.Group)
+		}
+		if seen[choice] {
+			return nil, money.Money{}, fmt.Errorf("option %q for modifier group %q selected more than once", choice.Option, choice.Group)
+		}
+		seen[choice] = true
+
+		perGroup[choice.Group]++
+		if group.MaxSelections > 0 && perGroup[choice.Group] > group.MaxSelections {
+			return nil, money.Money{}, fmt.Errorf("at most %d options allowed for modifier group %q", group.MaxSelections, choice.Group)
+		}
+
+		resolved = append(resolved, choice)
+		total = total.Add(option.Price)
+	}
+
+	for _, group := range menuItem.ModifierGroups {
+		if group.Required && perGroup[group.Name] == 0 {
+			return nil, money.Money{}, fmt.Errorf("modifier group %q is required", group.Name)
+		}
+	}
+
+	sort.Slice(resolved, func(i, j int) bool {
+		if resolved[i].Group != resolved[j].Group {
+			return resolved[i].Group < resolved[j].Group
+		}
+		return resolved[i].Option < resolved[j].Option
+	})
+
+	return resolved, total, nil
+}
+
+// modifiersKey identifies a modifier selection so identical lines can be combined
+func modifiersKey(modifiers []models.SelectedModifier) string {
+	var key strings.Builder
+	for _, modifier := range modifiers {
+		key.WriteString(modifier.Group)
+		key.WriteString("=")
+		key.WriteString(modifier.Option)
+		key.WriteString(";")
+	}
+	return key.String()
+}
+
+// PricingItems converts the available lines of a priced cart into pricing engine items
+func PricingItems(view *models.CartView) []pricing.Item {
+	items := make([]pricing.Item, 0, len(view.Lines))
+	for _, line := range view.Lines {
+		if !line.Available {
+			continue
+		}
+		items = append(items, pricing.Item{
+			MenuItemID:     line.MenuItemID,
+			Name:           line.Name,
+			Quantity:       line.Quantity,
+			UnitPrice:      line.UnitPrice.Sub(line.ModifiersPrice),
+			ModifiersPrice: line.ModifiersPrice,
+		})
+	}
+	return items
+}
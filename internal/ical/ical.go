@@ -0,0 +1,110 @@
+package ical
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Event statuses
+const (
+	StatusTentative = "TENTATIVE"
+	StatusConfirmed = "CONFIRMED"
+	StatusCancelled = "CANCELLED"
+)
+
+// stampFormat is the UTC date-time form used for every time in a calendar
+const stampFormat = "20060102T150405Z"
+
+// maxLineOctets is the longest a content line may be before it is folded (RFC 5545 3.1)
+const maxLineOctets = 75
+
+// Event is one VEVENT. Calendar apps match events by UID and replace an event with one of a
+// higher Sequence, so both must stay stable for the same booking across feeds and downloads.
+type Event struct {
+	UID          string
+	Sequence     int
+	Start        time.Time
+	End          time.Time
+	Summary      string
+	Description  string
+	Status       string
+	LastModified time.Time
+}
+
+// Calendar is a VCALENDAR published to subscribers
+type Calendar struct {
+	Name   string
+	Events []Event
+}
+
+// Encode renders the calendar as an iCalendar (.ics) document. All times are written in UTC.
+func (cal Calendar) Encode() []byte {
+	var buf bytes.Buffer
+	now := time.Now().UTC().Format(stampFormat)
+
+	writeLine(&buf, "BEGIN:VCALENDAR")
+	writeLine(&buf, "VERSION:2.0")
+	writeLine(&buf, "PRODID:-//Food Delivery//Bookings//EN")
+	writeLine(&buf, "CALSCALE:GREGORIAN")
+	writeLine(&buf, "METHOD:PUBLISH")
+	if cal.Name != "" {
+		writeLine(&buf, "X-WR-CALNAME:"+escapeText(cal.Name))
+	}
+
+	for _, event := range cal.Events {
+		writeLine(&buf, "BEGIN:VEVENT")
+		writeLine(&buf, "UID:"+escapeText(event.UID))
+		writeLine(&buf, "SEQUENCE:"+strconv.Itoa(event.Sequence))
+		writeLine(&buf, "DTSTAMP:"+now)
+		writeLine(&buf, "DTSTART:"+event.Start.UTC().Format(stampFormat))
+		writeLine(&buf, "DTEND:"+event.End.UTC().Format(stampFormat))
+		if !event.LastModified.IsZero() {
+			writeLine(&buf, "LAST-MODIFIED:"+event.LastModified.UTC().Format(stampFormat))
+		}
+		writeLine(&buf, "SUMMARY:"+escapeText(event.Summary))
+		if event.Description != "" {
+			writeLine(&buf, "DESCRIPTION:"+escapeText(event.Description))
+		}
+		if event.Status != "" {
+			writeLine(&buf, "STATUS:"+event.Status)
+		}
+		writeLine(&buf, "END:VEVENT")
+	}
+
+	writeLine(&buf, "END:VCALENDAR")
+	return buf.Bytes()
+}
+
+// escapeText escapes a TEXT value (RFC 5545 3.3.11)
+func escapeText(value string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", `\n`,
+	).Replace(value)
+}
+
+// writeLine writes a content line ended by CRLF, folding it into continuation lines that start
+// with a space so no line is longer than 75 octets. Lines are only split between characters.
+func writeLine(buf *bytes.Buffer, line string) {
+	limit := maxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		buf.WriteString(line[:cut])
+		buf.WriteString("\r\n ")
+		line = line[cut:]
+		// Continuation lines lose one octet to the leading space
+		limit = maxLineOctets - 1
+	}
+	buf.WriteString(line)
+	buf.WriteString("\r\n")
+}
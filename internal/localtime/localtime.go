@@ -0,0 +1,105 @@
+package localtime
+
+import (
+	"context"
+	"fmt"
+	"time"
+	// The runtime image has no zoneinfo files, so the timezone database is compiled in
+	_ "time/tzdata"
+
+	"github.com/code-harsh006/food-delivery/internal/models"
+	"github.com/code-harsh006/food-delivery/pkg/config"
+	"github.com/code-harsh006/food-delivery/pkg/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// DateFormat is the layout of local calendar dates in requests and responses
+const DateFormat = "2006-01-02"
+
+// Resolve returns the IANA timezone a booking of service in region is scheduled in: the
+// vendor's own timezone, else the region's from ZONE_TIMEZONES, else DEFAULT_TIMEZONE
+func Resolve(ctx context.Context, service models.Service, region string) (string, error) {
+	return ForVendor(ctx, service.VendorID, region)
+}
+
+// ForVendor returns the timezone a vendor works in, falling back like Resolve when the vendor
+// has none set
+func ForVendor(ctx context.Context, vendorID primitive.ObjectID, region string) (string, error) {
+	if !vendorID.IsZero() {
+		var vendor models.Vendor
+		err := db.GetMongoDB().Collection("vendors").FindOne(ctx, bson.M{"_id": vendorID}).Decode(&vendor)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return "", err
+		}
+		if Valid(vendor.Timezone) {
+			return vendor.Timezone, nil
+		}
+	}
+
+	cfg := config.Load()
+	if name := cfg.ZoneTimezones[region]; Valid(name) {
+		return name, nil
+	}
+	if Valid(cfg.DefaultTimezone) {
+		return cfg.DefaultTimezone, nil
+	}
+	return "UTC", nil
+}
+
+// Valid reports whether name is a known IANA timezone
+func Valid(name string) bool {
+	if name == "" {
+		return false
+	}
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
+
+// Location loads an IANA timezone, falling back to UTC for unknown names
+func Location(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// At returns the instant a local date (YYYY-MM-DD) and time of day (HH:MM) denote in loc
+func At(date, clock string, loc *time.Location) (time.Time, error) {
+	at, err := time.ParseInLocation(DateFormat+" 15:04", date+" "+clock, loc)
+	if err != nil {
+		if _, dateErr := time.Parse(DateFormat, date); dateErr != nil {
+			return time.Time{}, fmt.Errorf("invalid date %q, use YYYY-MM-DD", date)
+		}
+		return time.Time{}, fmt.Errorf("invalid time %q, use HH:MM", clock)
+	}
+	return at, nil
+}
+
+// ParseDate parses a local calendar date into the form dates are stored in: midnight UTC of
+// that date, whatever the timezone
+func ParseDate(date string) (time.Time, error) {
+	day, err := time.Parse(DateFormat, date)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, use YYYY-MM-DD", date)
+	}
+	return day, nil
+}
+
+// Date returns the calendar date of t in loc, stored as midnight UTC
+func Date(t time.Time, loc *time.Location) time.Time {
+	local := t.In(loc)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// Today returns today's date in loc, stored as midnight UTC
+func Today(loc *time.Location) time.Time {
+	return Date(time.Now(), loc)
+}
+
+// Midnight returns the instant a stored date begins in loc
+func Midnight(date time.Time, loc *time.Location) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, loc)
+}
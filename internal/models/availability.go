@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SlotTemplate describes when a service, or every service of a vendor, can be booked: on the
+// given weekdays between StartTime and EndTime, starting every SlotMinutes, with Capacity
+// bookings (technicians or kitchen throughput) allowed at the same time.
+type SlotTemplate struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ServiceID   primitive.ObjectID `bson:"service_id,omitempty" json:"service_id,omitempty"`
+	VendorID    primitive.ObjectID `bson:"vendor_id,omitempty" json:"vendor_id,omitempty"`
+	Weekdays    []int              `bson:"weekdays" json:"weekdays"`         // 0 = Sunday ... 6 = Saturday
+	StartTime   string             `bson:"start_time" json:"start_time"`     // HH:MM
+	EndTime     string             `bson:"end_time" json:"end_time"`         // HH:MM
+	SlotMinutes int                `bson:"slot_minutes" json:"slot_minutes"` // 0 uses the service duration, or 60 for a vendor
+	Capacity    int                `bson:"capacity" json:"capacity"`
+	IsActive    bool               `bson:"is_active" json:"is_active"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// SlotCapacity counts the bookings holding one slot of a schedule on one day. Its ID is
+// "<scope>:<date>:<start time>", where the scope is the service or vendor owning the schedule.
+type SlotCapacity struct {
+	ID        string `bson:"_id" json:"id"`
+	Scope     string `bson:"scope" json:"scope"`
+	Date      string `bson:"date" json:"date"`
+	StartTime string `bson:"start_time" json:"start_time"`
+	Booked    int    `bson:"booked" json:"booked"`
+}
+
+// Slot is a bookable start time returned by the availability endpoint
+type Slot struct {
+	StartTime string `json:"start_time"`
+	EndTime   string `json:"end_time"`
+	Capacity  int    `json:"capacity"`
+	Remaining int    `json:"remaining"`
+	Available bool   `json:"available"`
+}
+
+type CreateSlotTemplateRequest struct {
+	ServiceID   string `json:"service_id"`
+	VendorID    string `json:"vendor_id"`
+	Weekdays    []int  `json:"weekdays" binding:"required,min=1"`
+	StartTime   string `json:"start_time" binding:"required"`
+	EndTime     string `json:"end_time" binding:"required"`
+	SlotMinutes int    `json:"slot_minutes"`
+	Capacity    int    `json:"capacity" binding:"required,min=1"`
+	IsActive    *bool  `json:"is_active"`
+}
+
+type UpdateSlotTemplateRequest struct {
+	Weekdays    []int   `json:"weekdays"`
+	StartTime   *string `json:"start_time"`
+	EndTime     *string `json:"end_time"`
+	SlotMinutes *int    `json:"slot_minutes"`
+	Capacity    *int    `json:"capacity"`
+	IsActive    *bool   `json:"is_active"`
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"time"
+
+	"github.com/code-harsh006/food-delivery/pkg/money"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CancellationPolicy sets how much of a booking is refunded when the customer cancels it, for a
+// service or for every service of a vendor
+type CancellationPolicy struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ServiceID primitive.ObjectID `bson:"service_id,omitempty" json:"service_id,omitempty"`
+	VendorID  primitive.ObjectID `bson:"vendor_id,omitempty" json:"vendor_id,omitempty"`
+	Name      string             `bson:"name" json:"name"`
+	Tiers     []CancellationTier `bson:"tiers" json:"tiers"` // sorted by hours_before, longest notice first
+	IsActive  bool               `bson:"is_active" json:"is_active"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// CancellationTier refunds RefundPercent of the booking when it is cancelled at least HoursBefore
+// hours before it starts. Cancelling with less notice than every tier refunds nothing.
+type CancellationTier struct {
+	HoursBefore   float64 `bson:"hours_before" json:"hours_before" binding:"min=0"`
+	RefundPercent float64 `bson:"refund_percent" json:"refund_percent" binding:"min=0,max=100"`
+}
+
+// BookingCancellation records the fee charged and the amount refunded when a booking was cancelled
+type BookingCancellation struct {
+	PolicyID      primitive.ObjectID `bson:"policy_id,omitempty" json:"policy_id,omitempty"`
+	Policy        string             `bson:"policy" json:"policy"`
+	HoursBefore   float64            `bson:"hours_before" json:"hours_before"`
+	RefundPercent float64            `bson:"refund_percent" json:"refund_percent"`
+	Fee           money.Money        `bson:"fee" json:"fee"`
+	RefundAmount  money.Money        `bson:"refund_amount" json:"refund_amount"`
+	RefundTo      string             `bson:"refund_to,omitempty" json:"refund_to,omitempty"` // wallet when the refund went to the customer's wallet
+	CancelledBy   string             `bson:"cancelled_by,omitempty" json:"cancelled_by,omitempty"`
+	CancelledAt   time.Time          `bson:"cancelled_at" json:"cancelled_at"`
+}
+
+// Payment statuses of a booking
+const (
+	PaymentStatusPending           = "pending"
+	PaymentStatusAuthorized        = "authorized"
+	PaymentStatusFailed            = "failed"
+	PaymentStatusPaid              = "paid"
+	PaymentStatusPartiallyRefunded = "partially_refunded"
+	PaymentStatusRefunded          = "refunded"
+)
+
+// Where a cancelled booking's refund goes
+const (
+	RefundToOriginal = "original" // back to the card or wallet it was paid with
+	RefundToWallet   = "wallet"
+)
+
+type CancelBookingRequest struct {
+	RefundTo string `json:"refund_to"` // original (default) or wallet
+}
+
+type CreateCancellationPolicyRequest struct {
+	ServiceID string             `json:"service_id"`
+	VendorID  string             `json:"vendor_id"`
+	Name      string             `json:"name" binding:"required"`
+	Tiers     []CancellationTier `json:"tiers" binding:"required,min=1,dive"`
+	IsActive  *bool              `json:"is_active"`
+}
+
+type UpdateCancellationPolicyRequest struct {
+	Name     *string            `json:"name"`
+	Tiers    []CancellationTier `json:"tiers" binding:"omitempty,min=1,dive"`
+	IsActive *bool              `json:"is_active"`
+}
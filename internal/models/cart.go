@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+
+	"github.com/code-harsh006/food-delivery/pkg/money"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Cart is a persistent shopping cart owned by a user or, before login, by an anonymous cart token
+type Cart struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID    primitive.ObjectID `bson:"user_id,omitempty" json:"user_id,omitempty"`
+	Token     string             `bson:"token,omitempty" json:"-"`
+	Items     []CartItem         `bson:"items" json:"items"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+	ExpiresAt time.Time          `bson:"expires_at" json:"expires_at"`
+}
+
+// CartItem is a menu item line in a cart with the modifiers the customer picked
+type CartItem struct {
+	ID           primitive.ObjectID `bson:"_id" json:"id"`
+	MenuItemID   primitive.ObjectID `bson:"menu_item_id" json:"menu_item_id"`
+	Quantity     int                `bson:"quantity" json:"quantity"`
+	Modifiers    []SelectedModifier `bson:"modifiers" json:"modifiers"`
+	ModifiersKey string             `bson:"modifiers_key" json:"-"`
+	Notes        string             `bson:"notes" json:"notes"`
+	UnitPrice    money.Money        `bson:"unit_price" json:"-"`
+	AddedAt      time.Time          `bson:"added_at" json:"added_at"`
+}
+
+// SelectedModifier is one option chosen from a menu item's modifier group
+type SelectedModifier struct {
+	Group  string `bson:"group" json:"group" binding:"required"`
+	Option string `bson:"option" json:"option" binding:"required"`
+}
+
+// CartLine is a cart item priced against the current menu
+type CartLine struct {
+	CartItem
+	Name           string      `json:"name"`
+	VendorID       string      `json:"vendor_id,omitempty"`
+	UnitPrice      money.Money `json:"unit_price"`
+	ModifiersPrice money.Money `json:"modifiers_price"`
+	LineTotal      money.Money `json:"line_total"`
+	Available      bool        `json:"available"`
+	PriceChanged   bool        `json:"price_changed"`
+	Issues         []string    `json:"issues,omitempty"`
+}
+
+// CartView is the priced cart returned to clients
+type CartView struct {
+	ID        primitive.ObjectID `json:"id"`
+	Lines     []CartLine         `json:"items"`
+	ItemCount int                `json:"item_count"`
+	Subtotal  money.Money        `json:"subtotal"`
+	HasIssues bool               `json:"has_issues"`
+	UpdatedAt time.Time          `json:"updated_at"`
+	ExpiresAt time.Time          `json:"expires_at"`
+}
+
+type AddCartItemRequest struct {
+	MenuItemID string             `json:"menu_item_id" binding:"required"`
+	Quantity   int                `json:"quantity"`
+	Modifiers  []SelectedModifier `json:"modifiers"`
+	Notes      string             `json:"notes"`
+}
+
+type UpdateCartItemRequest struct {
+	Quantity int `json:"quantity" binding:"required"`
+}
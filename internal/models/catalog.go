@@ -0,0 +1,101 @@
+package models
+
+import (
+	"time"
+
+	"github.com/code-harsh006/food-delivery/pkg/money"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MenuItem represents a dish or product sold by a vendor
+type MenuItem struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	SKU            string             `bson:"sku" json:"sku"`
+	VendorID       primitive.ObjectID `bson:"vendor_id,omitempty" json:"vendor_id,omitempty"`
+	Name           string             `bson:"name" json:"name"`
+	Description    string             `bson:"description" json:"description"`
+	Category       string             `bson:"category" json:"category"`
+	Price          money.Money        `bson:"price" json:"price"`
+	DietaryTags    []string           `bson:"dietary_tags" json:"dietary_tags"`
+	Allergens      []string           `bson:"allergens" json:"allergens"`
+	Nutrition      *NutritionFacts    `bson:"nutrition,omitempty" json:"nutrition,omitempty"`
+	ModifierGroups []ModifierGroup    `bson:"modifier_groups" json:"modifier_groups"`
+	IsAvailable    bool               `bson:"is_available" json:"is_available"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// ModifierGroup is a set of choices offered with a menu item, e.g. "Size" or "Extra toppings"
+type ModifierGroup struct {
+	Name          string           `bson:"name" json:"name"`
+	Required      bool             `bson:"required" json:"required"`
+	MaxSelections int              `bson:"max_selections" json:"max_selections"`
+	Options       []ModifierOption `bson:"options" json:"options"`
+}
+
+// ModifierOption is a single choice within a modifier group and its price on top of the item
+type ModifierOption struct {
+	Name        string      `bson:"name" json:"name"`
+	Price       money.Money `bson:"price" json:"price"`
+	Unavailable bool        `bson:"unavailable" json:"unavailable"`
+}
+
+// CatalogImportRowError describes why a single import row was rejected
+type CatalogImportRowError struct {
+	Row    int      `json:"row"`
+	SKU    string   `json:"sku,omitempty"`
+	Errors []string `json:"errors"`
+}
+
+// CatalogImportResult summarises a bulk catalog import
+type CatalogImportResult struct {
+	Type      string                  `json:"type"`
+	Format    string                  `json:"format"`
+	DryRun    bool                    `json:"dry_run"`
+	TotalRows int                     `json:"total_rows"`
+	ValidRows int                     `json:"valid_rows"`
+	Created   int                     `json:"created"`
+	Updated   int                     `json:"updated"`
+	Errors    []CatalogImportRowError `json:"errors"`
+}
+
+// Category is a managed, nestable catalog category
+type Category struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name        string             `bson:"name" json:"name"`
+	Slug        string             `bson:"slug" json:"slug"`
+	Description string             `bson:"description" json:"description"`
+	Icon        string             `bson:"icon" json:"icon"`
+	ParentID    primitive.ObjectID `bson:"parent_id,omitempty" json:"parent_id,omitempty"`
+	SortOrder   int                `bson:"sort_order" json:"sort_order"`
+	IsActive    bool               `bson:"is_active" json:"is_active"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// CategoryNode is a category with its children and the number of active services below it
+type CategoryNode struct {
+	Category
+	ItemCount int             `json:"item_count"`
+	Children  []*CategoryNode `json:"children"`
+}
+
+type CreateCategoryRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Slug        string `json:"slug"`
+	Description string `json:"description"`
+	Icon        string `json:"icon"`
+	ParentID    string `json:"parent_id"`
+	SortOrder   int    `json:"sort_order"`
+	IsActive    *bool  `json:"is_active"`
+}
+
+type UpdateCategoryRequest struct {
+	Name        *string `json:"name"`
+	Slug        *string `json:"slug"`
+	Description *string `json:"description"`
+	Icon        *string `json:"icon"`
+	ParentID    *string `json:"parent_id"`
+	SortOrder   *int    `json:"sort_order"`
+	IsActive    *bool   `json:"is_active"`
+}
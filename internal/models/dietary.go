@@ -0,0 +1,75 @@
+package models
+
+// Dietary tags that can be attached to catalog items
+const (
+	DietVegan            = "vegan"
+	DietVegetarian       = "vegetarian"
+	DietEggetarian       = "eggetarian"
+	DietGlutenFree       = "gluten_free"
+	DietDairyFree        = "dairy_free"
+	DietNutFree          = "nut_free"
+	DietHalal            = "halal"
+	DietKosher           = "kosher"
+	DietJain             = "jain"
+	DietKeto             = "keto"
+	DietLowCarb          = "low_carb"
+	DietDiabeticFriendly = "diabetic_friendly"
+)
+
+// DietaryTags lists every supported dietary tag
+var DietaryTags = []string{
+	DietVegan,
+	DietVegetarian,
+	DietEggetarian,
+	DietGlutenFree,
+	DietDairyFree,
+	DietNutFree,
+	DietHalal,
+	DietKosher,
+	DietJain,
+	DietKeto,
+	DietLowCarb,
+	DietDiabeticFriendly,
+}
+
+// Allergens lists every supported allergen identifier
+var Allergens = []string{
+	"peanut",
+	"tree_nut",
+	"milk",
+	"egg",
+	"wheat",
+	"gluten",
+	"soy",
+	"fish",
+	"shellfish",
+	"mollusc",
+	"sesame",
+	"mustard",
+	"celery",
+	"lupin",
+	"sulphite",
+}
+
+// NutritionFacts holds optional per-serving nutrition information
+type NutritionFacts struct {
+	ServingSize      string  `bson:"serving_size,omitempty" json:"serving_size,omitempty"`
+	Calories         int     `bson:"calories" json:"calories"`
+	ProteinGrams     float64 `bson:"protein_g" json:"protein_g"`
+	CarbsGrams       float64 `bson:"carbs_g" json:"carbs_g"`
+	FatGrams         float64 `bson:"fat_g" json:"fat_g"`
+	FiberGrams       float64 `bson:"fiber_g" json:"fiber_g"`
+	SugarGrams       float64 `bson:"sugar_g" json:"sugar_g"`
+	SodiumMilligrams float64 `bson:"sodium_mg" json:"sodium_mg"`
+}
+
+// DietaryPreferences are the filters a user wants applied to catalog listings by default
+type DietaryPreferences struct {
+	Diets            []string `bson:"diets" json:"diets"`
+	ExcludeAllergens []string `bson:"exclude_allergens" json:"exclude_allergens"`
+}
+
+type UpdateDietaryPreferencesRequest struct {
+	Diets            []string `json:"diets"`
+	ExcludeAllergens []string `json:"exclude_allergens"`
+}
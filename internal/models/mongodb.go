@@ -1,22 +1,26 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
+	"github.com/code-harsh006/food-delivery/pkg/money"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // User represents a user in the system
 type User struct {
-	ID                primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Email             string             `bson:"email" json:"email"`
-	Phone             string             `bson:"phone" json:"phone"`
-	Name              string             `bson:"name" json:"name"`
-	AccommodationType string             `bson:"accommodation_type" json:"accommodation_type"`
-	Address           string             `bson:"address" json:"address"`
-	IsVerified        bool               `bson:"is_verified" json:"is_verified"`
-	CreatedAt         time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt         time.Time          `bson:"updated_at" json:"updated_at"`
+	ID                 primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Email              string             `bson:"email" json:"email"`
+	Phone              string             `bson:"phone" json:"phone"`
+	Name               string             `bson:"name" json:"name"`
+	AccommodationType  string             `bson:"accommodation_type" json:"accommodation_type"`
+	Address            string             `bson:"address" json:"address"`
+	IsVerified         bool               `bson:"is_verified" json:"is_verified"`
+	DietaryPreferences DietaryPreferences `bson:"dietary_preferences" json:"dietary_preferences"`
+	CalendarTokenHash  string             `bson:"calendar_token_hash,omitempty" json:"-"` // SHA-256 of the secret in the calendar feed URL
+	CreatedAt          time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt          time.Time          `bson:"updated_at" json:"updated_at"`
 }
 
 // OTP represents one-time passwords for verification
@@ -33,34 +37,128 @@ type OTP struct {
 // Service represents available services
 type Service struct {
 	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	SKU         string             `bson:"sku,omitempty" json:"sku,omitempty"`
 	Name        string             `bson:"name" json:"name"`
 	Description string             `bson:"description" json:"description"`
 	Category    string             `bson:"category" json:"category"`
-	BasePrice   float64            `bson:"base_price" json:"base_price"`
+	CategoryID  primitive.ObjectID `bson:"category_id,omitempty" json:"category_id,omitempty"`
+	VendorID    primitive.ObjectID `bson:"vendor_id,omitempty" json:"vendor_id,omitempty"`
+	BasePrice   money.Money        `bson:"base_price" json:"base_price"`
 	Duration    int                `bson:"duration" json:"duration"`
+	Skills      []string           `bson:"skills,omitempty" json:"skills,omitempty"` // technician skills the service needs
+	AddOns      []ServiceAddOn     `bson:"add_ons,omitempty" json:"add_ons,omitempty"`
+	DietaryTags []string           `bson:"dietary_tags" json:"dietary_tags"`
+	Allergens   []string           `bson:"allergens" json:"allergens"`
+	Nutrition   *NutritionFacts    `bson:"nutrition,omitempty" json:"nutrition,omitempty"`
+	Rating      RatingSummary      `bson:"rating" json:"rating"`
 	IsActive    bool               `bson:"is_active" json:"is_active"`
 	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
 }
 
+// ServiceAddOn is an optional extra a customer can book with a service, e.g. "Inside the oven"
+// with a deep clean. It adds its price and its time to the booking.
+type ServiceAddOn struct {
+	Name     string      `bson:"name" json:"name"`
+	Price    money.Money `bson:"price" json:"price"`
+	Duration int         `bson:"duration" json:"duration"` // minutes
+}
+
+// BookingItem is one service in a booking with the add-ons chosen for it, priced and timed as
+// they were when it was booked
+type BookingItem struct {
+	ServiceID primitive.ObjectID `bson:"service_id" json:"service_id"`
+	Name      string             `bson:"name" json:"name"`
+	Price     money.Money        `bson:"price" json:"price"`
+	Duration  int                `bson:"duration" json:"duration"` // minutes, without add-ons
+	Skills    []string           `bson:"skills,omitempty" json:"-"`
+	AddOns    []ServiceAddOn     `bson:"add_ons,omitempty" json:"add_ons,omitempty"`
+}
+
 // Booking represents service bookings
 type Booking struct {
-	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	UserID          primitive.ObjectID `bson:"user_id" json:"user_id"`
-	ServiceID       primitive.ObjectID `bson:"service_id" json:"service_id"`
-	ScheduledDate   time.Time          `bson:"scheduled_date" json:"scheduled_date"`
-	ScheduledTime   string             `bson:"scheduled_time" json:"scheduled_time"`
-	Status          string             `bson:"status" json:"status"`
-	TotalAmount     float64            `bson:"total_amount" json:"total_amount"`
-	PaymentStatus   string             `bson:"payment_status" json:"payment_status"`
-	SpecialRequests string             `bson:"special_requests" json:"special_requests"`
-	TechnicianNotes string             `bson:"technician_notes" json:"technician_notes"`
-	Rating          int                `bson:"rating" json:"rating"`
-	Review          string             `bson:"review" json:"review"`
-	CreatedAt       time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt       time.Time          `bson:"updated_at" json:"updated_at"`
+	ID              primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	UserID          primitive.ObjectID   `bson:"user_id" json:"user_id"`
+	ServiceID       primitive.ObjectID   `bson:"service_id" json:"service_id"`                 // first service of the booking
+	Items           []BookingItem        `bson:"items,omitempty" json:"items,omitempty"`       // every service booked, with its add-ons
+	Duration        int                  `bson:"duration,omitempty" json:"duration,omitempty"` // minutes of all items and add-ons together
+	ScheduledAt     time.Time            `bson:"scheduled_at" json:"scheduled_at"`             // start of the booking, shown in its timezone
+	Timezone        string               `bson:"timezone,omitempty" json:"timezone,omitempty"` // IANA name the booking was made in
+	Status          string               `bson:"status" json:"status"`                         // pending, confirmed, in_progress, completed, cancelled, no_show
+	TotalAmount     money.Money          `bson:"total_amount" json:"total_amount"`
+	Pricing         *PriceBreakdown      `bson:"pricing,omitempty" json:"pricing,omitempty"`
+	PromoCode       string               `bson:"promo_code,omitempty" json:"promo_code,omitempty"`
+	PaymentStatus   string               `bson:"payment_status" json:"payment_status"` // pending, authorized, failed, paid, partially_refunded, refunded
+	Payment         *BookingPayment      `bson:"payment,omitempty" json:"payment,omitempty"`
+	Cancellation    *BookingCancellation `bson:"cancellation,omitempty" json:"cancellation,omitempty"`
+	CapacitySlots   []string             `bson:"capacity_slots,omitempty" json:"-"` // slot_capacity entries held by the booking
+	RescheduleCount int                  `bson:"reschedule_count" json:"reschedule_count"`
+	EventSequence   int                  `bson:"event_sequence,omitempty" json:"-"` // changes to what the booking's calendar event shows
+	RemindersSent   []string             `bson:"reminders_sent,omitempty" json:"-"` // reminders sent or skipped for the current time
+	SeriesID        primitive.ObjectID   `bson:"series_id,omitempty" json:"series_id,omitempty"`
+	TechnicianID    primitive.ObjectID   `bson:"technician_id,omitempty" json:"technician_id,omitempty"`
+	TechnicianName  string               `bson:"technician_name,omitempty" json:"technician_name,omitempty"`
+	JobStart        *time.Time           `bson:"job_start,omitempty" json:"job_start,omitempty"` // time the assigned technician is booked for
+	JobEnd          *time.Time           `bson:"job_end,omitempty" json:"job_end,omitempty"`
+	SpecialRequests string               `bson:"special_requests" json:"special_requests"`
+	TechnicianNotes string               `bson:"technician_notes" json:"technician_notes"`
+	CreatedAt       time.Time            `bson:"created_at" json:"created_at"`
+	UpdatedAt       time.Time            `bson:"updated_at" json:"updated_at"`
+}
+
+// MarshalJSON shows the booking's start and its technician's job in the booking's timezone,
+// e.g. "2025-07-20T19:30:00+05:30", instead of the UTC they are stored in, together with the
+// local date and time the booking starts at
+func (b Booking) MarshalJSON() ([]byte, error) {
+	type booking Booking
+	local := struct {
+		booking
+		ScheduledDate time.Time `json:"scheduled_date"`
+		ScheduledTime string    `json:"scheduled_time"`
+	}{booking: booking(b), ScheduledDate: b.LocalDate(), ScheduledTime: b.LocalTime()}
+
+	loc := b.location()
+	local.ScheduledAt = b.ScheduledAt.In(loc)
+	if b.JobStart != nil {
+		start := b.JobStart.In(loc)
+		local.JobStart = &start
+	}
+	if b.JobEnd != nil {
+		end := b.JobEnd.In(loc)
+		local.JobEnd = &end
+	}
+	return json.Marshal(local)
+}
+
+// LocalDate returns the date the booking starts on in its timezone, as midnight UTC
+func (b Booking) LocalDate() time.Time {
+	year, month, day := b.ScheduledAt.In(b.location()).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// LocalTime returns the time of day the booking starts at in its timezone, as HH:MM
+func (b Booking) LocalTime() string {
+	return b.ScheduledAt.In(b.location()).Format("15:04")
+}
+
+// location loads the booking's timezone; bookings without a known one are in UTC
+func (b Booking) location() *time.Location {
+	loc, err := time.LoadLocation(b.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
 }
 
+const (
+	BookingStatusPending    = "pending"
+	BookingStatusConfirmed  = "confirmed"
+	BookingStatusInProgress = "in_progress"
+	BookingStatusCompleted  = "completed"
+	BookingStatusCancelled  = "cancelled"
+	BookingStatusNoShow     = "no_show"
+)
+
 // BookingStatus represents possible booking statuses
 type BookingStatus struct {
 	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
@@ -95,20 +193,39 @@ type LoginRequest struct {
 }
 
 type VerifyOTPRequest struct {
-	Email string `json:"email" binding:"required,email"`
-	Code  string `json:"code" binding:"required"`
+	Email     string `json:"email" binding:"required,email"`
+	Code      string `json:"code" binding:"required"`
+	CartToken string `json:"cart_token"`
 }
 
+// CreateBookingRequest books either one service, with service_id and add_ons, or several of
+// the same vendor's services at once, with items
 type CreateBookingRequest struct {
-	ServiceID       string `json:"service_id" binding:"required"`
-	ScheduledDate   string `json:"scheduled_date" binding:"required"`
-	ScheduledTime   string `json:"scheduled_time" binding:"required"`
-	SpecialRequests string `json:"special_requests"`
+	ServiceID       string               `json:"service_id"`
+	AddOns          []string             `json:"add_ons"`
+	Items           []BookingItemRequest `json:"items" binding:"omitempty,dive"`
+	ScheduledDate   string               `json:"scheduled_date" binding:"required"`
+	ScheduledTime   string               `json:"scheduled_time" binding:"required"`
+	SpecialRequests string               `json:"special_requests"`
+	Region          string               `json:"region"`
+	Tip             money.Money          `json:"tip"`
+	TipPercent      float64              `json:"tip_percent"`
+	PromoCode       string               `json:"promo_code"`
+}
+
+// BookingItemRequest is one service of a booking and the names of the add-ons wanted with it
+type BookingItemRequest struct {
+	ServiceID string   `json:"service_id" binding:"required"`
+	AddOns    []string `json:"add_ons"`
+}
+
+type RescheduleBookingRequest struct {
+	ScheduledDate string `json:"scheduled_date" binding:"required"`
+	ScheduledTime string `json:"scheduled_time" binding:"required"`
+	Reason        string `json:"reason"`
 }
 
 type UpdateBookingRequest struct {
 	Status          string `json:"status"`
 	TechnicianNotes string `json:"technician_notes"`
-	Rating          int    `json:"rating"`
-	Review          string `json:"review"`
 }
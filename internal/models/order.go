@@ -0,0 +1,71 @@
+package models
+
+import (
+	"time"
+
+	"github.com/code-harsh006/food-delivery/pkg/money"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Order is a food order placed from a customer's cart with a single vendor
+type Order struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID          primitive.ObjectID `bson:"user_id" json:"user_id"`
+	VendorID        primitive.ObjectID `bson:"vendor_id" json:"vendor_id"`
+	Items           []OrderItem        `bson:"items" json:"items"`
+	DeliveryAddress DeliveryAddress    `bson:"delivery_address" json:"delivery_address"`
+	Pricing         *PriceBreakdown    `bson:"pricing" json:"pricing"`
+	PromoCode       string             `bson:"promo_code,omitempty" json:"promo_code,omitempty"`
+	Total           money.Money        `bson:"total" json:"total"`
+	PaymentMethod   string             `bson:"payment_method,omitempty" json:"payment_method,omitempty"`
+	Status          string             `bson:"status" json:"status"` // placed, accepted, preparing, out_for_delivery, delivered, cancelled
+	Notes           string             `bson:"notes" json:"notes"`
+	CancelReason    string             `bson:"cancel_reason,omitempty" json:"cancel_reason,omitempty"`
+	CancelledAt     *time.Time         `bson:"cancelled_at,omitempty" json:"cancelled_at,omitempty"`
+	CreatedAt       time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt       time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// OrderItem is a snapshot of a cart line at the moment the order was placed
+type OrderItem struct {
+	MenuItemID primitive.ObjectID `bson:"menu_item_id" json:"menu_item_id"`
+	Name       string             `bson:"name" json:"name"`
+	Quantity   int                `bson:"quantity" json:"quantity"`
+	Modifiers  []SelectedModifier `bson:"modifiers" json:"modifiers"`
+	Notes      string             `bson:"notes" json:"notes"`
+	UnitPrice  money.Money        `bson:"unit_price" json:"unit_price"`
+	LineTotal  money.Money        `bson:"line_total" json:"line_total"`
+}
+
+// DeliveryAddress is copied onto the order so later profile edits do not change past orders
+type DeliveryAddress struct {
+	Name         string `bson:"name" json:"name"`
+	Phone        string `bson:"phone" json:"phone"`
+	Address      string `bson:"address" json:"address"`
+	Instructions string `bson:"instructions" json:"instructions"`
+}
+
+const (
+	OrderStatusPlaced         = "placed"
+	OrderStatusAccepted       = "accepted"
+	OrderStatusPreparing      = "preparing"
+	OrderStatusOutForDelivery = "out_for_delivery"
+	OrderStatusDelivered      = "delivered"
+	OrderStatusCancelled      = "cancelled"
+)
+
+type PlaceOrderRequest struct {
+	DeliveryAddress *DeliveryAddress `json:"delivery_address"`
+	Notes           string           `json:"notes"`
+	Region          string           `json:"region"`
+	DistanceKM      float64          `json:"distance_km"`
+	Zone            string           `json:"zone"`
+	Tip             money.Money      `json:"tip"`
+	TipPercent      float64          `json:"tip_percent"`
+	PromoCode       string           `json:"promo_code"`
+	PaymentMethod   string           `json:"payment_method"` // wallet, or empty to pay on delivery
+}
+
+type CancelOrderRequest struct {
+	Reason string `json:"reason"`
+}
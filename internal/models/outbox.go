@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OutboxMessage is a side effect recorded together with the write that caused it and
+// delivered later by the outbox dispatcher
+type OutboxMessage struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Type          string             `bson:"type" json:"type"`
+	Notification  *Notification      `bson:"notification,omitempty" json:"notification,omitempty"`
+	Payment       *PaymentAction     `bson:"payment,omitempty" json:"payment,omitempty"`
+	Status        string             `bson:"status" json:"status"` // pending, processing, delivered, failed
+	Attempts      int                `bson:"attempts" json:"attempts"`
+	NextAttemptAt time.Time          `bson:"next_attempt_at" json:"next_attempt_at"`
+	LockedUntil   time.Time          `bson:"locked_until,omitempty" json:"-"`
+	LastError     string             `bson:"last_error,omitempty" json:"last_error,omitempty"`
+	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
+	DeliveredAt   *time.Time         `bson:"delivered_at,omitempty" json:"delivered_at,omitempty"`
+}
+
+const (
+	OutboxTypeNotification = "notification"
+	OutboxTypePayment      = "payment"
+
+	OutboxStatusPending    = "pending"
+	OutboxStatusProcessing = "processing"
+	OutboxStatusDelivered  = "delivered"
+	OutboxStatusFailed     = "failed"
+)
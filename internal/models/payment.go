@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"github.com/code-harsh006/food-delivery/pkg/money"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BookingPayment is the payment taken for a booking through a payment provider. The amount is
+// authorised when the customer pays and captured when the booking is completed.
+type BookingPayment struct {
+	Provider  string      `bson:"provider" json:"provider"`
+	IntentID  string      `bson:"intent_id" json:"intent_id"`
+	Amount    money.Money `bson:"amount" json:"amount"`     // authorised
+	Captured  money.Money `bson:"captured" json:"captured"` // charged to the customer
+	Refunded  money.Money `bson:"refunded" json:"refunded"`
+	UpdatedAt time.Time   `bson:"updated_at" json:"updated_at"`
+}
+
+// Payment methods of bookings and orders
+const (
+	PaymentMethodCard   = "card"
+	PaymentMethodWallet = "wallet"
+)
+
+// PaymentAction is a call to a payment provider made through the outbox after the booking change
+// that needs it commits, e.g. refunding a cancelled booking. Provider and IntentID are set for
+// money that arrived on an intent the booking is no longer paid with; the call is then made on
+// that intent and the booking is left as it is.
+type PaymentAction struct {
+	BookingID primitive.ObjectID `bson:"booking_id" json:"booking_id"`
+	Action    string             `bson:"action" json:"action"` // capture, cancel, refund
+	Amount    money.Money        `bson:"amount" json:"amount"`
+	Provider  string             `bson:"provider,omitempty" json:"provider,omitempty"`
+	IntentID  string             `bson:"intent_id,omitempty" json:"intent_id,omitempty"`
+}
+
+const (
+	PaymentActionCapture = "capture"
+	PaymentActionCancel  = "cancel"
+	PaymentActionRefund  = "refund"
+)
+
+// PaymentEvent records a webhook event that has been applied, so a redelivered event is ignored
+type PaymentEvent struct {
+	ID        string             `bson:"_id" json:"id"` // provider:event ID
+	Type      string             `bson:"type" json:"type"`
+	BookingID primitive.ObjectID `bson:"booking_id,omitempty" json:"booking_id,omitempty"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+type CreateBookingPaymentRequest struct {
+	Method string `json:"method"` // card (default) or wallet
+}
@@ -0,0 +1,52 @@
+package models
+
+import "github.com/code-harsh006/food-delivery/pkg/money"
+
+// PriceBreakdown is an itemised quote. Every amount is rounded to the minor unit of the currency
+// on its own and the total is the sum of the rounded components, so the parts always add up to
+// what is charged.
+type PriceBreakdown struct {
+	Currency    string            `bson:"currency" json:"currency"`
+	Lines       []PriceLine       `bson:"lines" json:"lines"`
+	Subtotal    money.Money       `bson:"subtotal" json:"subtotal"`
+	Modifiers   money.Money       `bson:"modifiers" json:"modifiers"`
+	DeliveryFee money.Money       `bson:"delivery_fee" json:"delivery_fee"`
+	ServiceFee  money.Money       `bson:"service_fee" json:"service_fee"`
+	Discounts   []AppliedDiscount `bson:"discounts" json:"discounts"`
+	Discount    money.Money       `bson:"discount" json:"discount"`
+	Region      string            `bson:"region" json:"region"`
+	TaxRate     float64           `bson:"tax_rate" json:"tax_rate"`
+	Tax         money.Money       `bson:"tax" json:"tax"`
+	Tip         money.Money       `bson:"tip" json:"tip"`
+	Total       money.Money       `bson:"total" json:"total"`
+}
+
+// PriceLine is one priced item in a quote
+type PriceLine struct {
+	Name      string      `bson:"name" json:"name"`
+	Quantity  int         `bson:"quantity" json:"quantity"`
+	UnitPrice money.Money `bson:"unit_price" json:"unit_price"`
+	Modifiers money.Money `bson:"modifiers" json:"modifiers"`
+	Total     money.Money `bson:"total" json:"total"`
+}
+
+// AppliedDiscount is a discount taken off the item subtotal
+type AppliedDiscount struct {
+	Code        string      `bson:"code,omitempty" json:"code,omitempty"`
+	Description string      `bson:"description" json:"description"`
+	Amount      money.Money `bson:"amount" json:"amount"`
+}
+
+// QuoteRequest asks for a quote for either a service booking or the user's cart
+type QuoteRequest struct {
+	Type       string               `json:"type" binding:"required,oneof=booking order"`
+	ServiceID  string               `json:"service_id"`
+	AddOns     []string             `json:"add_ons"`
+	Items      []BookingItemRequest `json:"items" binding:"omitempty,dive"` // several services, instead of service_id
+	Region     string               `json:"region"`
+	DistanceKM float64              `json:"distance_km"`
+	Zone       string               `json:"zone"`
+	Tip        money.Money          `json:"tip"`
+	TipPercent float64              `json:"tip_percent"`
+	PromoCode  string               `json:"promo_code"`
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"time"
+
+	"github.com/code-harsh006/food-delivery/pkg/money"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Promotion is a coupon code customers can apply to a booking or an order
+type Promotion struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Code        string             `bson:"code" json:"code"`
+	Description string             `bson:"description" json:"description"`
+	Type        string             `bson:"type" json:"type"`                       // percentage, fixed, free_delivery, buy_x_get_y
+	Value       float64            `bson:"value" json:"value"`                     // percentage off
+	AmountOff   money.Money        `bson:"amount_off,omitempty" json:"amount_off"` // amount off of fixed promotions
+	MaxDiscount money.Money        `bson:"max_discount" json:"max_discount"`       // cap for percentage discounts, 0 for none
+	AppliesTo   string             `bson:"applies_to" json:"applies_to"`           // all, booking, order
+
+	// Buy-X-get-Y: for every BuyQuantity of the menu item, GetQuantity more are free
+	MenuItemID  primitive.ObjectID `bson:"menu_item_id,omitempty" json:"menu_item_id,omitempty"`
+	BuyQuantity int                `bson:"buy_quantity,omitempty" json:"buy_quantity,omitempty"`
+	GetQuantity int                `bson:"get_quantity,omitempty" json:"get_quantity,omitempty"`
+
+	MinSpend        money.Money `bson:"min_spend" json:"min_spend"`
+	UsageLimit      int         `bson:"usage_limit" json:"usage_limit"`       // total redemptions, 0 for unlimited
+	PerUserLimit    int         `bson:"per_user_limit" json:"per_user_limit"` // redemptions per user, 0 for unlimited
+	FirstOrderOnly  bool        `bson:"first_order_only" json:"first_order_only"`
+	StartsAt        *time.Time  `bson:"starts_at,omitempty" json:"starts_at,omitempty"`
+	EndsAt          *time.Time  `bson:"ends_at,omitempty" json:"ends_at,omitempty"`
+	IsActive        bool        `bson:"is_active" json:"is_active"`
+	RedemptionCount int         `bson:"redemption_count" json:"redemption_count"`
+	CreatedAt       time.Time   `bson:"created_at" json:"created_at"`
+	UpdatedAt       time.Time   `bson:"updated_at" json:"updated_at"`
+}
+
+const (
+	PromotionTypePercentage   = "percentage"
+	PromotionTypeFixed        = "fixed"
+	PromotionTypeFreeDelivery = "free_delivery"
+	PromotionTypeBuyXGetY     = "buy_x_get_y"
+
+	PromotionAppliesToAll     = "all"
+	PromotionAppliesToBooking = "booking"
+	PromotionAppliesToOrder   = "order"
+)
+
+// PromotionUsage counts one user's redemptions of a promotion; the ID combines both so the
+// per-user limit can be enforced with a single conditional upsert
+type PromotionUsage struct {
+	ID          string             `bson:"_id" json:"id"`
+	PromotionID primitive.ObjectID `bson:"promotion_id" json:"promotion_id"`
+	UserID      primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Count       int                `bson:"count" json:"count"`
+}
+
+// PromotionRedemption records a promotion applied to a booking or an order
+type PromotionRedemption struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	PromotionID   primitive.ObjectID `bson:"promotion_id" json:"promotion_id"`
+	Code          string             `bson:"code" json:"code"`
+	UserID        primitive.ObjectID `bson:"user_id" json:"user_id"`
+	ReferenceType string             `bson:"reference_type" json:"reference_type"` // booking or order
+	ReferenceID   primitive.ObjectID `bson:"reference_id" json:"reference_id"`
+	Discount      money.Money        `bson:"discount" json:"discount"`
+	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
+}
+
+type CreatePromotionRequest struct {
+	Code           string      `json:"code" binding:"required"`
+	Description    string      `json:"description"`
+	Type           string      `json:"type" binding:"required,oneof=percentage fixed free_delivery buy_x_get_y"`
+	Value          float64     `json:"value"`
+	AmountOff      money.Money `json:"amount_off"`
+	MaxDiscount    money.Money `json:"max_discount"`
+	AppliesTo      string      `json:"applies_to"`
+	MenuItemID     string      `json:"menu_item_id"`
+	BuyQuantity    int         `json:"buy_quantity"`
+	GetQuantity    int         `json:"get_quantity"`
+	MinSpend       money.Money `json:"min_spend"`
+	UsageLimit     int         `json:"usage_limit"`
+	PerUserLimit   int         `json:"per_user_limit"`
+	FirstOrderOnly bool        `json:"first_order_only"`
+	StartsAt       *time.Time  `json:"starts_at"`
+	EndsAt         *time.Time  `json:"ends_at"`
+	IsActive       *bool       `json:"is_active"`
+}
+
+type UpdatePromotionRequest struct {
+	Description  *string      `json:"description"`
+	MinSpend     *money.Money `json:"min_spend"`
+	UsageLimit   *int         `json:"usage_limit"`
+	PerUserLimit *int         `json:"per_user_limit"`
+	StartsAt     *time.Time   `json:"starts_at"`
+	EndsAt       *time.Time   `json:"ends_at"`
+	IsActive     *bool        `json:"is_active"`
+}
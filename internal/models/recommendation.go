@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RecommendedService is a single "frequently booked together" suggestion
+type RecommendedService struct {
+	ServiceID  primitive.ObjectID `bson:"service_id" json:"service_id"`
+	Score      float64            `bson:"score" json:"score"`
+	CoBookings int                `bson:"co_bookings" json:"co_bookings"`
+}
+
+// ServiceRecommendations holds the precomputed suggestions for one service
+type ServiceRecommendations struct {
+	ServiceID       primitive.ObjectID   `bson:"_id" json:"service_id"`
+	Recommendations []RecommendedService `bson:"recommendations" json:"recommendations"`
+	WindowDays      int                  `bson:"window_days" json:"window_days"`
+	ComputedAt      time.Time            `bson:"computed_at" json:"computed_at"`
+}
+
+// UserRecommendations holds the precomputed personalised suggestions for one user
+type UserRecommendations struct {
+	UserID          primitive.ObjectID   `bson:"_id" json:"user_id"`
+	Recommendations []RecommendedService `bson:"recommendations" json:"recommendations"`
+	WindowDays      int                  `bson:"window_days" json:"window_days"`
+	ComputedAt      time.Time            `bson:"computed_at" json:"computed_at"`
+}
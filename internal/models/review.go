@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Review is a customer's rating of a completed booking. Each booking has at most one.
+type Review struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	BookingID primitive.ObjectID `bson:"booking_id" json:"booking_id"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
+	ServiceID primitive.ObjectID `bson:"service_id" json:"service_id"`
+	VendorID  primitive.ObjectID `bson:"vendor_id,omitempty" json:"vendor_id,omitempty"`
+	Rating    int                `bson:"rating" json:"rating"` // 1 to 5
+	Comment   string             `bson:"comment" json:"comment"`
+	Reply     *ReviewReply       `bson:"reply,omitempty" json:"reply,omitempty"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// ReviewReply is the vendor's public answer to a review
+type ReviewReply struct {
+	Message   string             `bson:"message" json:"message"`
+	RepliedBy primitive.ObjectID `bson:"replied_by" json:"replied_by"`
+	RepliedAt time.Time          `bson:"replied_at" json:"replied_at"`
+}
+
+// RatingSummary is the running average of the reviews of a service or vendor. It is updated as
+// reviews are added rather than recomputed.
+type RatingSummary struct {
+	Average float64 `bson:"average" json:"average"`
+	Count   int     `bson:"count" json:"count"`
+	Total   int     `bson:"total" json:"-"` // sum of all ratings
+}
+
+type CreateReviewRequest struct {
+	Rating  int    `json:"rating" binding:"required,min=1,max=5"`
+	Comment string `json:"comment" binding:"max=2000"`
+}
+
+type ReplyToReviewRequest struct {
+	Message string `json:"message" binding:"required,max=2000"`
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BookingSeries is a recurring booking. Its occurrences are created as individual bookings a few
+// days ahead by the series scheduler.
+type BookingSeries struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID          primitive.ObjectID `bson:"user_id" json:"user_id"`
+	ServiceID       primitive.ObjectID `bson:"service_id" json:"service_id"`
+	Rule            RecurrenceRule     `bson:"rule" json:"rule"`
+	RRule           string             `bson:"rrule" json:"rrule"`
+	StartDate       time.Time          `bson:"start_date" json:"start_date"`
+	ScheduledTime   string             `bson:"scheduled_time" json:"scheduled_time"`
+	Timezone        string             `bson:"timezone" json:"timezone"` // IANA name the dates and time are local to
+	SpecialRequests string             `bson:"special_requests" json:"special_requests"`
+	Region          string             `bson:"region,omitempty" json:"region,omitempty"`
+	Status          string             `bson:"status" json:"status"`                                       // active, cancelled, completed
+	SkippedDates    []string           `bson:"skipped_dates" json:"skipped_dates"`                         // YYYY-MM-DD
+	MaterializedTo  *time.Time         `bson:"materialized_to,omitempty" json:"materialized_to,omitempty"` // last occurrence date processed
+	Occurrences     int                `bson:"occurrences" json:"occurrences"`                             // bookings created so far
+	CreatedAt       time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt       time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+const (
+	SeriesStatusActive    = "active"
+	SeriesStatusCancelled = "cancelled"
+	SeriesStatusCompleted = "completed"
+)
+
+// RecurrenceRule is the subset of an iCalendar RRULE used for recurring bookings: every Interval
+// weeks on the given Weekdays, or every Interval months on MonthDay, ending after Count
+// occurrences or on Until, whichever comes first
+type RecurrenceRule struct {
+	Frequency string     `bson:"frequency" json:"frequency"` // weekly, monthly
+	Interval  int        `bson:"interval" json:"interval"`
+	Weekdays  []int      `bson:"weekdays,omitempty" json:"weekdays,omitempty"` // 0 = Sunday ... 6 = Saturday
+	MonthDay  int        `bson:"month_day,omitempty" json:"month_day,omitempty"`
+	Count     int        `bson:"count,omitempty" json:"count,omitempty"`
+	Until     *time.Time `bson:"until,omitempty" json:"until,omitempty"`
+}
+
+// CreateBookingSeriesRequest takes the rule either as an RRULE string or as separate fields
+type CreateBookingSeriesRequest struct {
+	ServiceID       string `json:"service_id" binding:"required"`
+	StartDate       string `json:"start_date" binding:"required"`
+	ScheduledTime   string `json:"scheduled_time" binding:"required"`
+	RRule           string `json:"rrule"`
+	Frequency       string `json:"frequency"` // weekly, biweekly, monthly
+	Interval        int    `json:"interval"`
+	Weekdays        []int  `json:"weekdays"`
+	MonthDay        int    `json:"month_day"`
+	Count           int    `json:"count"`
+	Until           string `json:"until"`
+	SpecialRequests string `json:"special_requests"`
+	Region          string `json:"region"`
+}
+
+type SkipOccurrenceRequest struct {
+	Date string `json:"date" binding:"required"`
+}
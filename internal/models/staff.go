@@ -0,0 +1,64 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Technician is a member of a vendor's staff who carries out service bookings
+type Technician struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	VendorID        primitive.ObjectID `bson:"vendor_id" json:"vendor_id"`
+	UserID          primitive.ObjectID `bson:"user_id,omitempty" json:"user_id,omitempty"` // account the technician signs in with
+	Name            string             `bson:"name" json:"name"`
+	Phone           string             `bson:"phone" json:"phone"`
+	Skills          []string           `bson:"skills" json:"skills"`
+	WorkingHours    []WorkingHours     `bson:"working_hours" json:"working_hours"`
+	TimeOff         []TimeOff          `bson:"time_off" json:"time_off"`
+	IsActive        bool               `bson:"is_active" json:"is_active"`
+	ScheduleVersion int                `bson:"schedule_version" json:"-"` // bumped on every assignment so concurrent assignments conflict
+	CreatedAt       time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt       time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// WorkingHours is a window of a weekday a technician can be booked in
+type WorkingHours struct {
+	Weekday   int    `bson:"weekday" json:"weekday" binding:"min=0,max=6"`    // 0 = Sunday ... 6 = Saturday
+	StartTime string `bson:"start_time" json:"start_time" binding:"required"` // HH:MM
+	EndTime   string `bson:"end_time" json:"end_time" binding:"required"`     // HH:MM
+}
+
+// TimeOff is a period a technician cannot be booked, such as a holiday or sick leave
+type TimeOff struct {
+	Start  time.Time `bson:"start" json:"start" binding:"required"`
+	End    time.Time `bson:"end" json:"end" binding:"required"`
+	Reason string    `bson:"reason,omitempty" json:"reason,omitempty"`
+}
+
+type CreateTechnicianRequest struct {
+	VendorID     string         `json:"vendor_id"`
+	UserID       string         `json:"user_id"`
+	Name         string         `json:"name" binding:"required"`
+	Phone        string         `json:"phone"`
+	Skills       []string       `json:"skills"`
+	WorkingHours []WorkingHours `json:"working_hours" binding:"required,min=1,dive"`
+	TimeOff      []TimeOff      `json:"time_off" binding:"dive"`
+	IsActive     *bool          `json:"is_active"`
+}
+
+type UpdateTechnicianRequest struct {
+	UserID       *string        `json:"user_id"`
+	Name         *string        `json:"name"`
+	Phone        *string        `json:"phone"`
+	Skills       []string       `json:"skills"`
+	WorkingHours []WorkingHours `json:"working_hours" binding:"omitempty,min=1,dive"`
+	TimeOff      []TimeOff      `json:"time_off" binding:"omitempty,dive"`
+	IsActive     *bool          `json:"is_active"`
+}
+
+// AssignTechnicianRequest names the technician for a booking, or asks for one to be picked
+type AssignTechnicianRequest struct {
+	TechnicianID string `json:"technician_id"`
+	Auto         bool   `json:"auto"`
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WaitlistEntry is a customer waiting for a place in a fully booked slot. When a booking in the
+// slot is cancelled the first waiting entry is offered its place, which stays held for the entry
+// until it is confirmed or the hold expires.
+type WaitlistEntry struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID          primitive.ObjectID `bson:"user_id" json:"user_id"`
+	ServiceID       primitive.ObjectID `bson:"service_id" json:"service_id"`
+	ScheduledAt     time.Time          `bson:"scheduled_at" json:"scheduled_at"` // start of the slot
+	Timezone        string             `bson:"timezone" json:"timezone"`
+	ScheduledDate   time.Time          `bson:"scheduled_date" json:"scheduled_date"` // local date of scheduled_at, as midnight UTC
+	ScheduledTime   string             `bson:"scheduled_time" json:"scheduled_time"` // local time of scheduled_at, HH:MM
+	Region          string             `bson:"region,omitempty" json:"region,omitempty"`
+	SpecialRequests string             `bson:"special_requests" json:"special_requests"`
+	Slots           []string           `bson:"slots" json:"-"`                                             // slot_capacity entries a booking needs, held while offered
+	Status          string             `bson:"status" json:"status"`                                       // waiting, offered, booked, expired, left
+	HoldExpiresAt   *time.Time         `bson:"hold_expires_at,omitempty" json:"hold_expires_at,omitempty"` // when an offered place passes to the next customer
+	BookingID       primitive.ObjectID `bson:"booking_id,omitempty" json:"booking_id,omitempty"`
+	Position        int64              `bson:"-" json:"position,omitempty"` // place in the queue while waiting
+	CreatedAt       time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt       time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// MarshalJSON shows the slot and the hold expiry in the entry's timezone, like a booking's times
+func (e WaitlistEntry) MarshalJSON() ([]byte, error) {
+	type entry WaitlistEntry
+	local := entry(e)
+	if loc, err := time.LoadLocation(e.Timezone); err == nil && e.Timezone != "" {
+		local.ScheduledAt = e.ScheduledAt.In(loc)
+		if e.HoldExpiresAt != nil {
+			expires := e.HoldExpiresAt.In(loc)
+			local.HoldExpiresAt = &expires
+		}
+	}
+	return json.Marshal(local)
+}
+
+const (
+	WaitlistStatusWaiting = "waiting"
+	WaitlistStatusOffered = "offered"
+	WaitlistStatusBooked  = "booked"
+	WaitlistStatusExpired = "expired"
+	WaitlistStatusLeft    = "left"
+)
+
+type JoinWaitlistRequest struct {
+	ServiceID       string `json:"service_id" binding:"required"`
+	ScheduledDate   string `json:"scheduled_date" binding:"required"`
+	ScheduledTime   string `json:"scheduled_time" binding:"required"`
+	Region          string `json:"region"`
+	SpecialRequests string `json:"special_requests"`
+}
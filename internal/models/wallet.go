@@ -0,0 +1,90 @@
+package models
+
+import (
+	"time"
+
+	"github.com/code-harsh006/food-delivery/pkg/money"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Wallet is a customer's store credit. Its balance is not stored but derived from the ledger; the
+// document only serialises changes to one wallet.
+type Wallet struct {
+	ID        primitive.ObjectID `bson:"_id" json:"user_id"` // the customer's user ID
+	Sequence  int64              `bson:"sequence" json:"sequence"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// WalletTransaction is a posting to the append-only wallet ledger. Its entries move money between
+// accounts and always add up to zero; a transaction is never changed once written.
+type WalletTransaction struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID      primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Kind        string             `bson:"kind" json:"kind"`                               // credit, debit, refund, payment, hold, capture, release, expiry
+	Reference   string             `bson:"reference,omitempty" json:"reference,omitempty"` // what the money is for, e.g. booking:64f1...
+	Description string             `bson:"description" json:"description"`
+	CreatedBy   string             `bson:"created_by,omitempty" json:"created_by,omitempty"`
+	Entries     []WalletEntry      `bson:"entries" json:"entries"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// WalletEntry changes the balance of one account; a positive amount adds to it. A customer's
+// accounts carry their user ID: "cash", "promo:<transaction ID>" for promotional credit, which
+// carries its expiry on every entry, and "hold:<reference>" for money set aside for a purchase.
+// System accounts have no user ID and record where money came from and went to.
+type WalletEntry struct {
+	UserID    primitive.ObjectID `bson:"user_id,omitempty" json:"user_id,omitempty"`
+	Account   string             `bson:"account" json:"account"`
+	Amount    money.Money        `bson:"amount" json:"amount"`
+	ExpiresAt *time.Time         `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+}
+
+// Kinds of wallet transaction
+const (
+	WalletKindCredit  = "credit"  // issued by support
+	WalletKindDebit   = "debit"   // taken back by support
+	WalletKindRefund  = "refund"  // a purchase refunded into the wallet
+	WalletKindPayment = "payment" // a purchase paid from the wallet
+	WalletKindHold    = "hold"    // set aside until a booking is finished
+	WalletKindCapture = "capture" // held money taken, the rest released
+	WalletKindRelease = "release" // held money given back in full
+	WalletKindExpiry  = "expiry"  // promotional credit that was not used in time
+)
+
+// WalletBalance is what a customer's wallet holds. Available can be spent; promotional credit is
+// part of it until it expires and is spent before cash.
+type WalletBalance struct {
+	Currency    string         `json:"currency"`
+	Available   money.Money    `json:"available"`
+	Cash        money.Money    `json:"cash"`
+	Promotional money.Money    `json:"promotional"`
+	Held        money.Money    `json:"held"`
+	Expiring    []WalletCredit `json:"expiring"`
+}
+
+// WalletCredit is promotional credit left from one grant
+type WalletCredit struct {
+	Amount    money.Money `json:"amount"`
+	ExpiresAt time.Time   `json:"expires_at"`
+}
+
+// WalletStatementLine is a wallet transaction as the customer sees it: how much it added to or
+// took from the spendable balance and from held money, and the balance afterwards
+type WalletStatementLine struct {
+	ID          primitive.ObjectID `json:"id"`
+	Kind        string             `json:"kind"`
+	Reference   string             `json:"reference,omitempty"`
+	Description string             `json:"description"`
+	Amount      money.Money        `json:"amount"`
+	Held        money.Money        `json:"held"`
+	Balance     money.Money        `json:"balance"`
+	CreatedAt   time.Time          `json:"created_at"`
+}
+
+type WalletAdjustmentRequest struct {
+	Amount      money.Money `json:"amount"`
+	Reason      string      `json:"reason" binding:"required"`
+	Promotional bool        `json:"promotional"` // credit only: expires at ExpiresAt or after WALLET_PROMO_CREDIT_DAYS
+	ExpiresAt   *time.Time  `json:"expires_at"`
+}
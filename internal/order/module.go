@@ -1,10 +1,28 @@
 package order
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/code-harsh006/food-delivery/internal/cart"
+	"github.com/code-harsh006/food-delivery/internal/models"
+	"github.com/code-harsh006/food-delivery/internal/outbox"
+	"github.com/code-harsh006/food-delivery/internal/pricing"
+	"github.com/code-harsh006/food-delivery/internal/promotion"
+	"github.com/code-harsh006/food-delivery/internal/session"
+	"github.com/code-harsh006/food-delivery/internal/wallet"
+	"github.com/code-harsh006/food-delivery/pkg/db"
 	"github.com/code-harsh006/food-delivery/pkg/logger"
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.uber.org/zap"
 )
 
@@ -15,80 +33,444 @@ func NewModule() *Module {
 }
 
 func (m *Module) SetupRoutes(router *gin.RouterGroup, authMiddleware gin.HandlerFunc) {
-	notifications := router.Group("/notifications")
-	notifications.Use(authMiddleware)
+	orders := router.Group("/orders")
+	orders.Use(authMiddleware)
 	{
-		notifications.POST("/send", m.sendNotification)
-		notifications.GET("/subscribe", m.subscribeToNotifications)
+		orders.POST("", m.placeOrder)
+		orders.GET("", m.listOrders)
+		orders.GET("/:id", m.getOrder)
+		orders.PUT("/:id/cancel", m.cancelOrder)
 	}
 }
 
-type SendNotificationRequest struct {
-	UserID  uint   `json:"user_id" binding:"required"`
-	Title   string `json:"title" binding:"required"`
-	Message string `json:"message" binding:"required"`
-	Type    string `json:"type"`
-}
+// placeOrder turns the user's cart into an order and empties the cart
+func (m *Module) placeOrder(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
 
-func (m *Module) sendNotification(c *gin.Context) {
-	var req SendNotificationRequest
+	var req models.PlaceOrderRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Stub implementation for sending push notifications
-	// In a real implementation, you would integrate with FCM, APNs, etc.
+	if req.PaymentMethod != "" && req.PaymentMethod != models.PaymentMethodWallet {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "payment_method must be wallet, or left out to pay on delivery"})
+		return
+	}
+
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
 
-	logger.Info("Sending notification",
-		zap.Uint("user_id", req.UserID),
-		zap.String("title", req.Title),
-		zap.String("message", req.Message),
-		zap.String("type", req.Type),
-	)
+	var user models.User
+	if err := mongoDB.Collection("users").FindOne(context.Background(), bson.M{"_id": userID}).Decode(&user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load user"})
+		return
+	}
 
-	// Simulate successful notification send
-	c.JSON(http.StatusOK, gin.H{
-		"message":         "Notification sent successfully",
-		"notification_id": "notif_123456",
-		"status":          "sent",
+	userCart, err := cart.Load(context.Background(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch cart"})
+		return
+	}
+	view, err := cart.Price(context.Background(), userCart)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to price cart"})
+		return
+	}
+
+	if len(view.Lines) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cart is empty"})
+		return
+	}
+	// The customer has to review unavailable or repriced lines before the order goes through
+	if view.HasIssues {
+		c.JSON(http.StatusConflict, gin.H{"error": "Some cart items are no longer available", "cart": view})
+		return
+	}
+	for _, line := range view.Lines {
+		if line.PriceChanged {
+			c.JSON(http.StatusConflict, gin.H{"error": "Cart prices have changed, please review your cart", "cart": view})
+			return
+		}
+	}
+
+	vendorID, err := singleVendor(view.Lines)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var vendor models.Vendor
+	err = mongoDB.Collection("vendors").FindOne(context.Background(), bson.M{"_id": vendorID}).Decode(&vendor)
+	if err != nil && err != mongo.ErrNoDocuments {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	// Menu items imported before vendors were managed may point at vendors without a document
+	if err == nil && !vendor.IsActive {
+		c.JSON(http.StatusConflict, gin.H{"error": "Vendor is not accepting orders"})
+		return
+	}
+
+	address := models.DeliveryAddress{Name: user.Name, Phone: user.Phone, Address: user.Address}
+	if req.DeliveryAddress != nil {
+		address = *req.DeliveryAddress
+		if address.Name == "" {
+			address.Name = user.Name
+		}
+		if address.Phone == "" {
+			address.Phone = user.Phone
+		}
+	}
+	if strings.TrimSpace(address.Address) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Delivery address is required"})
+		return
+	}
+
+	items := make([]models.OrderItem, 0, len(view.Lines))
+	for _, line := range view.Lines {
+		items = append(items, models.OrderItem{
+			MenuItemID: line.MenuItemID,
+			Name:       line.Name,
+			Quantity:   line.Quantity,
+			Modifiers:  line.Modifiers,
+			Notes:      line.Notes,
+			UnitPrice:  line.UnitPrice,
+			LineTotal:  line.LineTotal,
+		})
+	}
+
+	pricingItems := cart.PricingItems(view)
+	var discounts []pricing.Discount
+	var applied *promotion.Applied
+	if req.PromoCode != "" {
+		applied, err = promotion.Apply(context.Background(), req.PromoCode, userID, promotion.KindOrder, pricingItems)
+		if err != nil {
+			if promotion.Rejected(err) {
+				c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "promo_code": req.PromoCode})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check promotion code"})
+			return
+		}
+		discounts = append(discounts, applied.Discount)
+	}
+
+	quote, err := pricing.Quote(pricing.Request{
+		Items:      pricingItems,
+		Delivery:   &pricing.Delivery{DistanceKM: req.DistanceKM, Zone: req.Zone},
+		Region:     req.Region,
+		Tip:        req.Tip,
+		TipPercent: req.TipPercent,
+		Discounts:  discounts,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	order := models.Order{
+		ID:              primitive.NewObjectID(),
+		UserID:          userID,
+		VendorID:        vendorID,
+		Items:           items,
+		DeliveryAddress: address,
+		Pricing:         quote,
+		Total:           quote.Total,
+		PromoCode:       promotion.NormalizeCode(req.PromoCode),
+		PaymentMethod:   req.PaymentMethod,
+		Status:          models.OrderStatusPlaced,
+		Notes:           req.Notes,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+
+	// The order is stored last, so that without transactions a promotion or wallet that turns the
+	// order down leaves nothing behind
+	err = db.WithTransaction(context.Background(), func(ctx context.Context) error {
+		if applied != nil {
+			if err := promotion.Redeem(ctx, applied, userID, promotion.KindOrder, order.ID, promotion.DiscountAmount(applied, quote)); err != nil {
+				return err
+			}
+		}
+
+		// Paying from the wallet takes the total straight away; it is refunded if the order is cancelled
+		if order.PaymentMethod == models.PaymentMethodWallet && order.Total.IsPositive() {
+			if _, err := wallet.Pay(ctx, userID, order.Total, wallet.Reference("order", order.ID), "Order "+order.ID.Hex()); err != nil {
+				promotion.Release(ctx, promotion.KindOrder, order.ID)
+				return err
+			}
+		}
+
+		if _, err := mongoDB.Collection("orders").InsertOne(ctx, order); err != nil {
+			// Outside a transaction nothing is rolled back, so give the payment and code back explicitly
+			if order.PaymentMethod == models.PaymentMethodWallet && order.Total.IsPositive() {
+				wallet.Refund(ctx, userID, wallet.Reference("order", order.ID), order.Total, "Order "+order.ID.Hex()+" could not be placed")
+			}
+			promotion.Release(ctx, promotion.KindOrder, order.ID)
+			return err
+		}
+
+		return outbox.EnqueueNotification(ctx, models.Notification{
+			UserID:  userID,
+			Title:   "Order Placed",
+			Message: "Your order has been placed successfully",
+			Type:    "order",
+		})
+	})
+	if err != nil {
+		if promotion.Rejected(err) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "promo_code": req.PromoCode})
+			return
+		}
+		if errors.Is(err, wallet.ErrInsufficientFunds) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Wallet balance is too low", "total": order.Total})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to place order"})
+		return
+	}
+	outbox.Wake()
+
+	if err := cart.Clear(context.Background(), userID); err != nil {
+		logger.Error("Failed to clear cart after placing order", zap.String("order_id", order.ID.Hex()), zap.Error(err))
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Order placed successfully",
+		"order":   order,
 	})
 }
 
-func (m *Module) subscribeToNotifications(c *gin.Context) {
-	userID, _ := c.Get("user_id")
+// listOrders returns the user's orders, newest first
+func (m *Module) listOrders(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
 
-	// WebSocket connection would be established here
-	// For now, we'll just return a success message
+	filter := bson.M{"user_id": userID}
+	if status := c.Query("status"); status != "" {
+		filter["status"] = status
+	}
 
-	logger.Info("User subscribed to notifications", zap.Any("user_id", userID))
+	collection := mongoDB.Collection("orders")
+	total, err := collection.CountDocuments(context.Background(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count orders"})
+		return
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+	cursor, err := collection.Find(context.Background(), filter, findOptions)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch orders"})
+		return
+	}
+	defer cursor.Close(context.Background())
+
+	orders := []models.Order{}
+	if err = cursor.All(context.Background(), &orders); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode orders"})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Subscribed to notifications successfully",
-		"user_id": userID,
+		"orders": orders,
+		"total":  total,
+		"page":   page,
+		"limit":  limit,
 	})
 }
 
-// Stub methods for different notification types
-func (m *Module) SendOrderStatusNotification(userID uint, orderID uint, status string) {
-	logger.Info("Order status notification",
-		zap.Uint("user_id", userID),
-		zap.Uint("order_id", orderID),
-		zap.String("status", status),
-	)
+// getOrder returns one of the user's orders
+func (m *Module) getOrder(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	orderID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var order models.Order
+	err = mongoDB.Collection("orders").FindOne(context.Background(), bson.M{"_id": orderID, "user_id": userID}).Decode(&order)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"order": order})
+}
+
+// cancelOrder cancels an order the vendor has not accepted yet
+func (m *Module) cancelOrder(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	orderID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	var req models.CancelOrderRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	var order models.Order
+	err = db.WithTransaction(context.Background(), func(ctx context.Context) error {
+		err := mongoDB.Collection("orders").FindOneAndUpdate(ctx,
+			bson.M{"_id": orderID, "user_id": userID, "status": models.OrderStatusPlaced},
+			bson.M{"$set": bson.M{
+				"status":        models.OrderStatusCancelled,
+				"cancel_reason": req.Reason,
+				"cancelled_at":  now,
+				"updated_at":    now,
+			}},
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&order)
+		if err != nil {
+			return err
+		}
+
+		if err := promotion.Release(ctx, promotion.KindOrder, order.ID); err != nil {
+			return err
+		}
+
+		message := "Your order has been cancelled successfully"
+		if order.PaymentMethod == models.PaymentMethodWallet && order.Total.IsPositive() {
+			if _, err := wallet.Refund(ctx, userID, wallet.Reference("order", order.ID), order.Total, "Refund of order "+order.ID.Hex()); err != nil {
+				return err
+			}
+			message += " and " + order.Total.String() + " has been refunded to your wallet"
+		}
+
+		return outbox.EnqueueNotification(ctx, models.Notification{
+			UserID:  userID,
+			Title:   "Order Cancelled",
+			Message: message,
+			Type:    "order",
+		})
+	})
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel order"})
+			return
+		}
+
+		count, err := mongoDB.Collection("orders").CountDocuments(context.Background(), bson.M{"_id": orderID, "user_id": userID})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+		if count == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+			return
+		}
+		c.JSON(http.StatusConflict, gin.H{"error": "Order can no longer be cancelled"})
+		return
+	}
+	outbox.Wake()
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Order cancelled successfully",
+		"order":   order,
+	})
 }
 
-func (m *Module) SendDeliveryNotification(userID uint, message string) {
-	logger.Info("Delivery notification",
-		zap.Uint("user_id", userID),
-		zap.String("message", message),
-	)
+// currentUser resolves the authenticated user, writing the error response when that fails
+func currentUser(c *gin.Context) (primitive.ObjectID, bool) {
+	userID, err := session.UserID(c)
+	if err != nil {
+		if err == session.ErrNotAuthenticated {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve user"})
+		}
+		return primitive.NilObjectID, false
+	}
+	return userID, true
 }
 
-func (m *Module) SendPromotionalNotification(userID uint, title, message string) {
-	logger.Info("Promotional notification",
-		zap.Uint("user_id", userID),
-		zap.String("title", title),
-		zap.String("message", message),
-	)
+// singleVendor returns the vendor every line belongs to; an order is fulfilled by one vendor
+func singleVendor(lines []models.CartLine) (primitive.ObjectID, error) {
+	vendor := ""
+	for _, line := range lines {
+		if line.VendorID == "" {
+			return primitive.NilObjectID, fmt.Errorf("cart item %q is not sold by a vendor", line.Name)
+		}
+		if vendor != "" && line.VendorID != vendor {
+			return primitive.NilObjectID, errors.New("all cart items must come from the same vendor")
+		}
+		vendor = line.VendorID
+	}
+	return primitive.ObjectIDFromHex(vendor)
 }
@@ -0,0 +1,200 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/code-harsh006/food-delivery/internal/models"
+	"github.com/code-harsh006/food-delivery/internal/payment"
+	"github.com/code-harsh006/food-delivery/pkg/db"
+	"github.com/code-harsh006/food-delivery/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+const (
+	// lease is how long a claimed message stays locked before another dispatcher may retry it
+	lease      = time.Minute
+	maxBackoff = 10 * time.Minute
+)
+
+// wake lets writers nudge the dispatcher instead of waiting for the next poll
+var wake = make(chan struct{}, 1)
+
+// EnqueueNotification records a notification for delivery. Call it with the context of the
+// transaction that makes the change the notification is about, so both commit or neither does.
+func EnqueueNotification(ctx context.Context, notification models.Notification) error {
+	now := time.Now()
+	if notification.CreatedAt.IsZero() {
+		notification.CreatedAt = now
+	}
+
+	// The notification is stored under the message ID, so a redelivery cannot create a second copy
+	message := models.OutboxMessage{
+		ID:            primitive.NewObjectID(),
+		Type:          models.OutboxTypeNotification,
+		Notification:  &notification,
+		Status:        models.OutboxStatusPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+
+	_, err := db.GetMongoDB().Collection("outbox").InsertOne(ctx, message)
+	return err
+}
+
+// EnqueuePayment records a call to a payment provider, e.g. a refund, to be made once the
+// transaction that needs it commits. Retries use the message ID as their idempotency key.
+func EnqueuePayment(ctx context.Context, action models.PaymentAction) error {
+	now := time.Now()
+	message := models.OutboxMessage{
+		ID:            primitive.NewObjectID(),
+		Type:          models.OutboxTypePayment,
+		Payment:       &action,
+		Status:        models.OutboxStatusPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+
+	_, err := db.GetMongoDB().Collection("outbox").InsertOne(ctx, message)
+	return err
+}
+
+// Wake asks the dispatcher to look for new messages now. Call it after the transaction commits.
+func Wake() {
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+}
+
+// StartDispatcher delivers pending outbox messages until ctx is cancelled
+func StartDispatcher(ctx context.Context, interval time.Duration, maxAttempts int) {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := Dispatch(ctx, maxAttempts); err != nil {
+				logger.Error("Failed to dispatch outbox messages", zap.Error(err))
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-wake:
+			}
+		}
+	}()
+}
+
+// Dispatch delivers every message that is due and returns how many were delivered
+func Dispatch(ctx context.Context, maxAttempts int) (int, error) {
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		return 0, nil
+	}
+	collection := mongoDB.Collection("outbox")
+
+	delivered := 0
+	for ctx.Err() == nil {
+		now := time.Now()
+
+		var message models.OutboxMessage
+		err := collection.FindOneAndUpdate(ctx,
+			bson.M{"$or": []bson.M{
+				{"status": models.OutboxStatusPending, "next_attempt_at": bson.M{"$lte": now}},
+				{"status": models.OutboxStatusProcessing, "locked_until": bson.M{"$lt": now}},
+			}},
+			bson.M{
+				"$set": bson.M{"status": models.OutboxStatusProcessing, "locked_until": now.Add(lease)},
+				"$inc": bson.M{"attempts": 1},
+			},
+			options.FindOneAndUpdate().
+				SetSort(bson.D{{Key: "next_attempt_at", Value: 1}}).
+				SetReturnDocument(options.After),
+		).Decode(&message)
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				return delivered, nil
+			}
+			return delivered, err
+		}
+
+		if deliverErr := deliver(ctx, message); deliverErr != nil {
+			update := bson.M{"status": models.OutboxStatusPending, "last_error": deliverErr.Error()}
+			if maxAttempts > 0 && message.Attempts >= maxAttempts {
+				update["status"] = models.OutboxStatusFailed
+				logger.Error("Giving up on outbox message",
+					zap.String("id", message.ID.Hex()),
+					zap.String("type", message.Type),
+					zap.Int("attempts", message.Attempts),
+					zap.Error(deliverErr),
+				)
+			} else {
+				update["next_attempt_at"] = time.Now().Add(backoff(message.Attempts))
+			}
+			if _, err := collection.UpdateOne(ctx, bson.M{"_id": message.ID}, bson.M{"$set": update}); err != nil {
+				return delivered, err
+			}
+			continue
+		}
+
+		_, err = collection.UpdateOne(ctx, bson.M{"_id": message.ID}, bson.M{
+			"$set":   bson.M{"status": models.OutboxStatusDelivered, "delivered_at": time.Now()},
+			"$unset": bson.M{"locked_until": "", "last_error": ""},
+		})
+		if err != nil {
+			return delivered, err
+		}
+		delivered++
+	}
+
+	return delivered, nil
+}
+
+// deliver performs the side effect a message describes
+func deliver(ctx context.Context, message models.OutboxMessage) error {
+	switch message.Type {
+	case models.OutboxTypeNotification:
+		if message.Notification == nil {
+			return fmt.Errorf("notification message %s has no notification", message.ID.Hex())
+		}
+		notification := *message.Notification
+		notification.ID = message.ID
+		_, err := db.GetMongoDB().Collection("notifications").InsertOne(ctx, notification)
+		if mongo.IsDuplicateKeyError(err) {
+			// Delivered by an earlier attempt that did not get to mark the message
+			return nil
+		}
+		return err
+	case models.OutboxTypePayment:
+		if message.Payment == nil {
+			return fmt.Errorf("payment message %s has no payment", message.ID.Hex())
+		}
+		return payment.Settle(ctx, *message.Payment, "outbox-"+message.ID.Hex())
+	default:
+		return fmt.Errorf("unknown outbox message type %q", message.Type)
+	}
+}
+
+// backoff doubles the wait after every failed attempt, starting at five seconds
+func backoff(attempts int) time.Duration {
+	wait := 5 * time.Second
+	for i := 1; i < attempts && wait < maxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > maxBackoff {
+		wait = maxBackoff
+	}
+	return wait
+}
@@ -0,0 +1,178 @@
+package payment
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+
+	"github.com/code-harsh006/food-delivery/pkg/money"
+)
+
+// Fake is an in-memory provider for local development and tests. Every intent can be authorised
+// and every call succeeds unless the amounts do not add up. Its webhooks take the same JSON as
+// Stripe's but are not signed, so it is never offered in production.
+type Fake struct {
+	mu      sync.Mutex
+	next    int
+	intents map[string]*Intent
+	refunds map[string]money.Money // refunded total by intent
+	done    map[string]interface{}
+}
+
+// NewFake returns an empty fake provider
+func NewFake() *Fake {
+	return &Fake{
+		intents: make(map[string]*Intent),
+		refunds: make(map[string]money.Money),
+		done:    make(map[string]interface{}),
+	}
+}
+
+// Name implements Provider
+func (f *Fake) Name() string {
+	return ProviderFake
+}
+
+// CreateIntent implements Provider
+func (f *Fake) CreateIntent(ctx context.Context, req IntentRequest) (*Intent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if result, ok := f.done[req.IdempotencyKey]; ok && req.IdempotencyKey != "" {
+		intent := *result.(*Intent)
+		return &intent, nil
+	}
+
+	f.next++
+	id := fmt.Sprintf("pi_fake_%d", f.next)
+	intent := &Intent{
+		ID:           id,
+		Status:       IntentRequiresPayment,
+		Amount:       req.Amount,
+		ClientSecret: id + "_secret",
+	}
+	f.intents[id] = intent
+	if req.IdempotencyKey != "" {
+		f.done[req.IdempotencyKey] = intent
+	}
+
+	copied := *intent
+	return &copied, nil
+}
+
+// Capture implements Provider
+func (f *Fake) Capture(ctx context.Context, intentID string, amount money.Money, idempotencyKey string) (*Intent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if result, ok := f.done[idempotencyKey]; ok && idempotencyKey != "" {
+		intent := *result.(*Intent)
+		return &intent, nil
+	}
+
+	intent := f.intent(intentID)
+	if intent.Status != IntentRequiresCapture && intent.Status != IntentRequiresPayment {
+		return nil, fmt.Errorf("fake: cannot capture intent %s, it is %s", intentID, intent.Status)
+	}
+	if amount.Cmp(intent.Amount) > 0 {
+		return nil, fmt.Errorf("fake: cannot capture %s of %s", amount, intent.Amount)
+	}
+	intent.Status = IntentSucceeded
+	intent.Captured = amount
+
+	copied := *intent
+	if idempotencyKey != "" {
+		f.done[idempotencyKey] = &copied
+	}
+	return &copied, nil
+}
+
+// Cancel implements Provider
+func (f *Fake) Cancel(ctx context.Context, intentID string, idempotencyKey string) (*Intent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	intent := f.intent(intentID)
+	if intent.Status == IntentSucceeded {
+		return nil, fmt.Errorf("fake: cannot cancel intent %s, it was captured", intentID)
+	}
+	intent.Status = IntentCanceled
+
+	copied := *intent
+	return &copied, nil
+}
+
+// Refund implements Provider
+func (f *Fake) Refund(ctx context.Context, intentID string, amount money.Money, idempotencyKey string) (*Refund, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if result, ok := f.done[idempotencyKey]; ok && idempotencyKey != "" {
+		refund := *result.(*Refund)
+		return &refund, nil
+	}
+
+	intent := f.intent(intentID)
+	if intent.Status != IntentSucceeded {
+		return nil, fmt.Errorf("fake: cannot refund intent %s, it is %s", intentID, intent.Status)
+	}
+	total := f.refunds[intentID].Add(amount)
+	if total.Cmp(intent.Captured) > 0 {
+		return nil, fmt.Errorf("fake: cannot refund %s, only %s is left", amount, intent.Captured.Sub(f.refunds[intentID]))
+	}
+	f.refunds[intentID] = total
+
+	f.next++
+	refund := &Refund{
+		ID:       fmt.Sprintf("re_fake_%d", f.next),
+		IntentID: intentID,
+		Amount:   amount,
+		Total:    total,
+		Status:   IntentSucceeded,
+	}
+	if idempotencyKey != "" {
+		f.done[idempotencyKey] = refund
+	}
+
+	copied := *refund
+	return &copied, nil
+}
+
+// ParseWebhook implements Provider. Events are not signed.
+func (f *Fake) ParseWebhook(payload []byte, header http.Header) (*Event, error) {
+	event, err := parseStripeEvent(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep the in-memory intent in step with what the event says happened to it
+	if event.IntentID != "" {
+		f.mu.Lock()
+		intent := f.intent(event.IntentID)
+		switch event.Type {
+		case EventAuthorized:
+			intent.Status = IntentRequiresCapture
+			intent.Amount = event.Amount
+		case EventSucceeded:
+			intent.Status = IntentSucceeded
+			intent.Captured = event.Amount
+		case EventCanceled:
+			intent.Status = IntentCanceled
+		}
+		f.mu.Unlock()
+	}
+	return event, nil
+}
+
+// intent returns an intent by ID. Intents the fake has not seen, e.g. after a restart, are
+// taken to be authorised so local data keeps working. Hold f.mu.
+func (f *Fake) intent(id string) *Intent {
+	intent, ok := f.intents[id]
+	if !ok {
+		intent = &Intent{ID: id, Status: IntentRequiresCapture, Amount: money.New(math.MaxInt64, money.Currency())}
+		f.intents[id] = intent
+	}
+	return intent
+}
@@ -0,0 +1,199 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/code-harsh006/food-delivery/internal/models"
+	"github.com/code-harsh006/food-delivery/pkg/config"
+	"github.com/code-harsh006/food-delivery/pkg/db"
+	"github.com/code-harsh006/food-delivery/pkg/money"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Provider names, as configured with PAYMENT_PROVIDER and used in webhook URLs
+const (
+	ProviderStripe = "stripe"
+	ProviderFake   = "fake"
+)
+
+// Intent statuses, following Stripe's PaymentIntent
+const (
+	IntentRequiresPayment = "requires_payment_method"
+	IntentRequiresCapture = "requires_capture"
+	IntentSucceeded       = "succeeded"
+	IntentCanceled        = "canceled"
+)
+
+// Event types a webhook is parsed into. Events of other types are parsed with an empty type and
+// can be acknowledged without doing anything.
+const (
+	EventAuthorized = "authorized" // the amount is held on the customer's card, ready to capture
+	EventSucceeded  = "succeeded"  // the amount was charged
+	EventFailed     = "failed"     // the customer's payment attempt was declined
+	EventRefunded   = "refunded"   // Amount is the total refunded so far
+	EventCanceled   = "canceled"   // the authorisation was released or expired
+)
+
+var (
+	// ErrInvalidSignature is returned for webhooks that were not signed by the provider
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	// ErrUnknownProvider is returned for providers that are not available
+	ErrUnknownProvider = errors.New("unknown payment provider")
+)
+
+// Provider takes payments for bookings. Intents are created with manual capture: the customer
+// authorises the amount and it is captured later, in full or in part, or cancelled. Calls that
+// move money take an idempotency key so a retried call is only carried out once.
+type Provider interface {
+	Name() string
+	CreateIntent(ctx context.Context, req IntentRequest) (*Intent, error)
+	Capture(ctx context.Context, intentID string, amount money.Money, idempotencyKey string) (*Intent, error)
+	Cancel(ctx context.Context, intentID string, idempotencyKey string) (*Intent, error)
+	Refund(ctx context.Context, intentID string, amount money.Money, idempotencyKey string) (*Refund, error)
+	ParseWebhook(payload []byte, header http.Header) (*Event, error)
+}
+
+// IntentRequest asks for the customer to authorise Amount. Reference is the booking ID; it comes
+// back on the intent's webhook events.
+type IntentRequest struct {
+	Amount         money.Money
+	Reference      string
+	Description    string
+	IdempotencyKey string
+}
+
+// Intent is a payment the customer completes in the client with ClientSecret
+type Intent struct {
+	ID           string
+	Status       string
+	Amount       money.Money
+	Captured     money.Money
+	ClientSecret string
+}
+
+// Refund is money returned to the customer from a captured intent. Total is what has been
+// refunded from the intent altogether, this refund included.
+type Refund struct {
+	ID       string
+	IntentID string
+	Amount   money.Money
+	Total    money.Money
+	Status   string
+}
+
+// Event is a webhook event of a payment intent
+type Event struct {
+	ID        string
+	Type      string
+	IntentID  string
+	Amount    money.Money
+	Reference string
+}
+
+// fake is shared so intents created by one request are known to the next
+var fake = NewFake()
+
+// Get returns the provider with the given name. The fake provider is not available in production.
+func Get(name string) (Provider, error) {
+	cfg := config.Load()
+	switch name {
+	case ProviderStripe:
+		return NewStripe(cfg.StripeKey, cfg.StripeWebhookSecret, cfg.StripeAPIBaseURL, time.Duration(cfg.StripeWebhookToleranceSeconds)*time.Second), nil
+	case ProviderFake:
+		if cfg.Environment != "production" {
+			return fake, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownProvider, name)
+}
+
+// Default returns the provider new payments are taken with (PAYMENT_PROVIDER)
+func Default() (Provider, error) {
+	return Get(config.Load().PaymentProvider)
+}
+
+// Settle carries out a payment action queued for a booking and records the outcome on it. The
+// idempotency key must stay the same across retries of one action.
+func Settle(ctx context.Context, action models.PaymentAction, idempotencyKey string) error {
+	if action.IntentID != "" {
+		return settleStray(ctx, action, idempotencyKey)
+	}
+	collection := db.GetMongoDB().Collection("bookings")
+
+	var booking models.Booking
+	if err := collection.FindOne(ctx, bson.M{"_id": action.BookingID}).Decode(&booking); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		return err
+	}
+	if booking.Payment == nil || booking.Payment.IntentID == "" {
+		return nil
+	}
+	provider, err := Get(booking.Payment.Provider)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	switch action.Action {
+	case models.PaymentActionCapture:
+		intent, err := provider.Capture(ctx, booking.Payment.IntentID, action.Amount, idempotencyKey)
+		if err != nil {
+			return err
+		}
+		if _, err := collection.UpdateOne(ctx, bson.M{"_id": booking.ID}, bson.M{
+			"$set": bson.M{"payment.captured": intent.Captured, "payment.updated_at": now},
+		}); err != nil {
+			return err
+		}
+		// A booking that was finished, rather than cancelled, is now paid
+		_, err = collection.UpdateOne(ctx,
+			bson.M{"_id": booking.ID, "payment_status": models.PaymentStatusAuthorized},
+			bson.M{"$set": bson.M{"payment_status": models.PaymentStatusPaid, "updated_at": now}},
+		)
+		return err
+
+	case models.PaymentActionCancel:
+		_, err := provider.Cancel(ctx, booking.Payment.IntentID, idempotencyKey)
+		return err
+
+	case models.PaymentActionRefund:
+		refund, err := provider.Refund(ctx, booking.Payment.IntentID, action.Amount, idempotencyKey)
+		if err != nil {
+			return err
+		}
+		// The provider's running total is kept, so a repeated action or its webhook changes nothing
+		_, err = collection.UpdateOne(ctx, bson.M{"_id": booking.ID}, bson.M{
+			"$max": bson.M{"payment.refunded.minor": refund.Total.Minor},
+			"$set": bson.M{"payment.updated_at": now},
+		})
+		return err
+
+	default:
+		return fmt.Errorf("unknown payment action %q", action.Action)
+	}
+}
+
+// settleStray gives back money that arrived on an intent a booking is not paid with, e.g. a card
+// payment completed after the booking was paid from the wallet. The booking is not changed.
+func settleStray(ctx context.Context, action models.PaymentAction, idempotencyKey string) error {
+	provider, err := Get(action.Provider)
+	if err != nil {
+		return err
+	}
+	switch action.Action {
+	case models.PaymentActionCancel:
+		_, err = provider.Cancel(ctx, action.IntentID, idempotencyKey)
+	case models.PaymentActionRefund:
+		_, err = provider.Refund(ctx, action.IntentID, action.Amount, idempotencyKey)
+	default:
+		err = fmt.Errorf("unknown payment action %q", action.Action)
+	}
+	return err
+}
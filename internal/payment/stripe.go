@@ -0,0 +1,310 @@
+package payment
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/code-harsh006/food-delivery/pkg/money"
+)
+
+// placeholderWebhookSecret is the example STRIPE_WEBHOOK_SECRET; it is public, so webhooks signed
+// with it prove nothing
+const placeholderWebhookSecret = "whsec_dummy"
+
+// Stripe takes payments through the Stripe API, or any server that speaks it such as
+// stripe-mock, at baseURL
+type Stripe struct {
+	secretKey     string
+	webhookSecret string
+	baseURL       string
+	tolerance     time.Duration
+	client        *http.Client
+}
+
+// NewStripe returns a Stripe provider. Webhooks are verified with webhookSecret and rejected when
+// their signature is older than tolerance.
+func NewStripe(secretKey, webhookSecret, baseURL string, tolerance time.Duration) *Stripe {
+	return &Stripe{
+		secretKey:     secretKey,
+		webhookSecret: webhookSecret,
+		baseURL:       strings.TrimRight(baseURL, "/"),
+		tolerance:     tolerance,
+		client:        &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Name implements Provider
+func (s *Stripe) Name() string {
+	return ProviderStripe
+}
+
+// stripeIntent is the part of a Stripe PaymentIntent the provider reads
+type stripeIntent struct {
+	ID             string            `json:"id"`
+	Status         string            `json:"status"`
+	Amount         int64             `json:"amount"`
+	AmountReceived int64             `json:"amount_received"`
+	Currency       string            `json:"currency"`
+	ClientSecret   string            `json:"client_secret"`
+	Metadata       map[string]string `json:"metadata"`
+	LatestCharge   *stripeCharge     `json:"latest_charge"`
+}
+
+// stripeCharge is the part of a Stripe Charge the provider reads
+type stripeCharge struct {
+	PaymentIntent  string            `json:"payment_intent"`
+	AmountRefunded int64             `json:"amount_refunded"`
+	Currency       string            `json:"currency"`
+	Metadata       map[string]string `json:"metadata"`
+}
+
+// UnmarshalJSON accepts a charge that was not expanded, which Stripe sends as its ID
+func (c *stripeCharge) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		return nil
+	}
+	type charge stripeCharge
+	return json.Unmarshal(data, (*charge)(c))
+}
+
+func (i stripeIntent) toIntent() *Intent {
+	return &Intent{
+		ID:           i.ID,
+		Status:       i.Status,
+		Amount:       money.New(i.Amount, i.Currency),
+		Captured:     money.New(i.AmountReceived, i.Currency),
+		ClientSecret: i.ClientSecret,
+	}
+}
+
+// CreateIntent implements Provider
+func (s *Stripe) CreateIntent(ctx context.Context, req IntentRequest) (*Intent, error) {
+	form := url.Values{}
+	form.Set("amount", strconv.FormatInt(req.Amount.Minor, 10))
+	form.Set("currency", strings.ToLower(req.Amount.Currency))
+	form.Set("capture_method", "manual")
+	form.Set("description", req.Description)
+	form.Set("metadata[reference]", req.Reference)
+
+	var intent stripeIntent
+	if err := s.call(ctx, http.MethodPost, "/v1/payment_intents", form, req.IdempotencyKey, &intent); err != nil {
+		return nil, err
+	}
+	return intent.toIntent(), nil
+}
+
+// Capture implements Provider. The rest of the authorised amount is released.
+func (s *Stripe) Capture(ctx context.Context, intentID string, amount money.Money, idempotencyKey string) (*Intent, error) {
+	form := url.Values{}
+	form.Set("amount_to_capture", strconv.FormatInt(amount.Minor, 10))
+
+	var intent stripeIntent
+	if err := s.call(ctx, http.MethodPost, "/v1/payment_intents/"+url.PathEscape(intentID)+"/capture", form, idempotencyKey, &intent); err != nil {
+		return nil, err
+	}
+	return intent.toIntent(), nil
+}
+
+// Cancel implements Provider
+func (s *Stripe) Cancel(ctx context.Context, intentID string, idempotencyKey string) (*Intent, error) {
+	var intent stripeIntent
+	if err := s.call(ctx, http.MethodPost, "/v1/payment_intents/"+url.PathEscape(intentID)+"/cancel", url.Values{}, idempotencyKey, &intent); err != nil {
+		return nil, err
+	}
+	return intent.toIntent(), nil
+}
+
+// Refund implements Provider. The intent is read back afterwards for its refunded total.
+func (s *Stripe) Refund(ctx context.Context, intentID string, amount money.Money, idempotencyKey string) (*Refund, error) {
+	form := url.Values{}
+	form.Set("payment_intent", intentID)
+	form.Set("amount", strconv.FormatInt(amount.Minor, 10))
+
+	var refund struct {
+		ID       string `json:"id"`
+		Amount   int64  `json:"amount"`
+		Currency string `json:"currency"`
+		Status   string `json:"status"`
+	}
+	if err := s.call(ctx, http.MethodPost, "/v1/refunds", form, idempotencyKey, &refund); err != nil {
+		return nil, err
+	}
+
+	var intent stripeIntent
+	query := url.Values{}
+	query.Set("expand[]", "latest_charge")
+	if err := s.call(ctx, http.MethodGet, "/v1/payment_intents/"+url.PathEscape(intentID), query, "", &intent); err != nil {
+		return nil, err
+	}
+	total := money.New(refund.Amount, refund.Currency)
+	if intent.LatestCharge != nil {
+		total = money.New(intent.LatestCharge.AmountRefunded, refund.Currency)
+	}
+
+	return &Refund{ID: refund.ID, IntentID: intentID, Amount: money.New(refund.Amount, refund.Currency), Total: total, Status: refund.Status}, nil
+}
+
+// ParseWebhook implements Provider. The Stripe-Signature header must carry a v1 HMAC-SHA256 of
+// "timestamp.payload" made with the webhook secret, and a timestamp within the tolerance. Every
+// webhook is rejected while the secret is unset or still the example one.
+func (s *Stripe) ParseWebhook(payload []byte, header http.Header) (*Event, error) {
+	if s.webhookSecret == "" || s.webhookSecret == placeholderWebhookSecret {
+		return nil, fmt.Errorf("%w: STRIPE_WEBHOOK_SECRET is not configured", ErrInvalidSignature)
+	}
+	if err := verifyStripeSignature(payload, header.Get("Stripe-Signature"), s.webhookSecret, s.tolerance, time.Now()); err != nil {
+		return nil, err
+	}
+	return parseStripeEvent(payload)
+}
+
+// call sends a form-encoded request to the Stripe API and decodes the JSON response into out
+func (s *Stripe) call(ctx context.Context, method, path string, form url.Values, idempotencyKey string, out interface{}) error {
+	endpoint := s.baseURL + path
+	var body io.Reader
+	if method == http.MethodGet {
+		endpoint += "?" + form.Encode()
+	} else {
+		body = strings.NewReader(form.Encode())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.secretKey)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		var failure struct {
+			Error struct {
+				Type    string `json:"type"`
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(data, &failure) == nil && failure.Error.Message != "" {
+			return fmt.Errorf("stripe: %s (%s)", failure.Error.Message, resp.Status)
+		}
+		return fmt.Errorf("stripe: %s", resp.Status)
+	}
+	return json.Unmarshal(data, out)
+}
+
+// verifyStripeSignature checks a Stripe-Signature header, e.g. "t=1700000000,v1=5257a8..."
+func verifyStripeSignature(payload []byte, header, secret string, tolerance time.Duration, now time.Time) error {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	if timestamp == "" || len(signatures) == 0 {
+		return ErrInvalidSignature
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if tolerance > 0 && now.Sub(time.Unix(seconds, 0)) > tolerance {
+		return fmt.Errorf("%w: timestamp is too old", ErrInvalidSignature)
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	expected := mac.Sum(nil)
+	for _, signature := range signatures {
+		actual, err := hex.DecodeString(signature)
+		if err == nil && hmac.Equal(actual, expected) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// parseStripeEvent reads a Stripe event of a PaymentIntent or a Charge into an Event
+func parseStripeEvent(payload []byte) (*Event, error) {
+	var raw struct {
+		ID   string `json:"id"`
+		Type string `json:"type"`
+		Data struct {
+			Object json.RawMessage `json:"object"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(payload, &raw); err != nil {
+		return nil, fmt.Errorf("invalid webhook payload: %w", err)
+	}
+	if raw.ID == "" {
+		return nil, fmt.Errorf("invalid webhook payload: no event id")
+	}
+	event := &Event{ID: raw.ID}
+
+	switch raw.Type {
+	case "payment_intent.amount_capturable_updated", "payment_intent.succeeded",
+		"payment_intent.payment_failed", "payment_intent.canceled":
+		var intent stripeIntent
+		if err := json.Unmarshal(raw.Data.Object, &intent); err != nil {
+			return nil, fmt.Errorf("invalid webhook payload: %w", err)
+		}
+		event.IntentID = intent.ID
+		event.Reference = intent.Metadata["reference"]
+		switch raw.Type {
+		case "payment_intent.amount_capturable_updated":
+			event.Type = EventAuthorized
+			event.Amount = money.New(intent.Amount, intent.Currency)
+		case "payment_intent.succeeded":
+			event.Type = EventSucceeded
+			event.Amount = money.New(intent.AmountReceived, intent.Currency)
+		case "payment_intent.payment_failed":
+			event.Type = EventFailed
+		case "payment_intent.canceled":
+			event.Type = EventCanceled
+		}
+
+	case "charge.refunded":
+		var charge stripeCharge
+		if err := json.Unmarshal(raw.Data.Object, &charge); err != nil {
+			return nil, fmt.Errorf("invalid webhook payload: %w", err)
+		}
+		event.Type = EventRefunded
+		event.IntentID = charge.PaymentIntent
+		event.Reference = charge.Metadata["reference"]
+		event.Amount = money.New(charge.AmountRefunded, charge.Currency)
+	}
+
+	return event, nil
+}
@@ -0,0 +1,131 @@
+package payment
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/code-harsh006/food-delivery/pkg/money"
+)
+
+const testWebhookSecret = "whsec_test"
+
+// signStripe returns the v1 signature Stripe would send for payload at timestamp
+func signStripe(payload, secret string, timestamp int64) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "." + payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyStripeSignature(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	payload := `{"id":"evt_1","type":"payment_intent.succeeded"}`
+	fresh := now.Add(-time.Minute).Unix()
+	stale := now.Add(-10 * time.Minute).Unix()
+	t1 := "t=" + strconv.FormatInt(fresh, 10)
+
+	tests := []struct {
+		name    string
+		payload string
+		header  string
+		wantErr bool
+	}{
+		{"valid", payload, t1 + ",v1=" + signStripe(payload, testWebhookSecret, fresh), false},
+		{"valid among several signatures", payload, t1 + ",v1=00ff,v1=" + signStripe(payload, testWebhookSecret, fresh) + ",v0=abc", false},
+		{"spaces after commas", payload, t1 + ", v1=" + signStripe(payload, testWebhookSecret, fresh), false},
+		{"tampered payload", `{"id":"evt_1","type":"payment_intent.canceled"}`, t1 + ",v1=" + signStripe(payload, testWebhookSecret, fresh), true},
+		{"wrong secret", payload, t1 + ",v1=" + signStripe(payload, "whsec_other", fresh), true},
+		{"timestamp does not match signature", payload, "t=" + strconv.FormatInt(fresh+1, 10) + ",v1=" + signStripe(payload, testWebhookSecret, fresh), true},
+		{"stale timestamp", payload, "t=" + strconv.FormatInt(stale, 10) + ",v1=" + signStripe(payload, testWebhookSecret, stale), true},
+		{"missing t", payload, "v1=" + signStripe(payload, testWebhookSecret, fresh), true},
+		{"timestamp not a number", payload, "t=abc,v1=" + signStripe(payload, testWebhookSecret, fresh), true},
+		{"missing v1", payload, t1, true},
+		{"signature not hex", payload, t1 + ",v1=zz", true},
+		{"empty header", payload, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyStripeSignature([]byte(tt.payload), tt.header, testWebhookSecret, 5*time.Minute, now)
+			if tt.wantErr && !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("verifyStripeSignature() error = %v, want %v", err, ErrInvalidSignature)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("verifyStripeSignature() error = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestVerifyStripeSignatureWithoutTolerance(t *testing.T) {
+	payload := `{"id":"evt_1"}`
+	old := time.Unix(1700000000, 0).Unix()
+	header := "t=" + strconv.FormatInt(old, 10) + ",v1=" + signStripe(payload, testWebhookSecret, old)
+
+	if err := verifyStripeSignature([]byte(payload), header, testWebhookSecret, 0, time.Unix(1800000000, 0)); err != nil {
+		t.Errorf("verifyStripeSignature() with no tolerance error = %v, want nil", err)
+	}
+}
+
+func TestParseStripeEvent(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    Event
+		wantErr bool
+	}{
+		{
+			name:    "authorized",
+			payload: `{"id":"evt_1","type":"payment_intent.amount_capturable_updated","data":{"object":{"id":"pi_1","amount":1250,"amount_received":0,"currency":"usd","metadata":{"reference":"b1"}}}}`,
+			want:    Event{ID: "evt_1", Type: EventAuthorized, IntentID: "pi_1", Amount: money.New(1250, "USD"), Reference: "b1"},
+		},
+		{
+			name:    "succeeded uses the amount received",
+			payload: `{"id":"evt_2","type":"payment_intent.succeeded","data":{"object":{"id":"pi_1","amount":1250,"amount_received":1000,"currency":"usd","latest_charge":"ch_1","metadata":{"reference":"b1"}}}}`,
+			want:    Event{ID: "evt_2", Type: EventSucceeded, IntentID: "pi_1", Amount: money.New(1000, "USD"), Reference: "b1"},
+		},
+		{
+			name:    "failed",
+			payload: `{"id":"evt_3","type":"payment_intent.payment_failed","data":{"object":{"id":"pi_1","metadata":{}}}}`,
+			want:    Event{ID: "evt_3", Type: EventFailed, IntentID: "pi_1"},
+		},
+		{
+			name:    "canceled",
+			payload: `{"id":"evt_4","type":"payment_intent.canceled","data":{"object":{"id":"pi_1"}}}`,
+			want:    Event{ID: "evt_4", Type: EventCanceled, IntentID: "pi_1"},
+		},
+		{
+			name:    "refunded",
+			payload: `{"id":"evt_5","type":"charge.refunded","data":{"object":{"id":"ch_1","payment_intent":"pi_1","amount_refunded":500,"currency":"usd","metadata":{"reference":"b1"}}}}`,
+			want:    Event{ID: "evt_5", Type: EventRefunded, IntentID: "pi_1", Amount: money.New(500, "USD"), Reference: "b1"},
+		},
+		{
+			name:    "other type is acknowledged",
+			payload: `{"id":"evt_6","type":"customer.created","data":{"object":{"id":"cus_1"}}}`,
+			want:    Event{ID: "evt_6"},
+		},
+		{name: "no id", payload: `{"type":"payment_intent.succeeded","data":{"object":{}}}`, wantErr: true},
+		{name: "not JSON", payload: `not json`, wantErr: true},
+		{name: "object of the wrong shape", payload: `{"id":"evt_7","type":"payment_intent.succeeded","data":{"object":{"amount":"lots"}}}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseStripeEvent([]byte(tt.payload))
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseStripeEvent() = %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseStripeEvent() error = %v", err)
+			}
+			if *got != tt.want {
+				t.Errorf("parseStripeEvent() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package pricing
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/code-harsh006/food-delivery/internal/models"
+	"github.com/code-harsh006/food-delivery/pkg/config"
+	"github.com/code-harsh006/food-delivery/pkg/money"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrDeliveryOutOfRange = errors.New("delivery address is outside the delivery radius")
+	ErrUnknownZone        = errors.New("unknown delivery zone")
+	ErrInvalidTip         = errors.New("tip cannot be negative")
+)
+
+// Item is a priced line: the unit price of the item and the per-unit price of its modifiers
+type Item struct {
+	MenuItemID     primitive.ObjectID
+	Name           string
+	Quantity       int
+	UnitPrice      money.Money
+	ModifiersPrice money.Money
+}
+
+// Discount takes either a fixed amount or a percentage off the item subtotal, or waives the delivery fee
+type Discount struct {
+	Code         string
+	Description  string
+	Amount       money.Money
+	Percent      float64
+	FreeDelivery bool
+}
+
+// Delivery describes where an order goes. A zone, when given, takes precedence over distance.
+type Delivery struct {
+	DistanceKM float64
+	Zone       string
+}
+
+// Request is everything that goes into a quote
+type Request struct {
+	Items      []Item
+	Delivery   *Delivery
+	Region     string
+	Tip        money.Money
+	TipPercent float64
+	Discounts  []Discount
+}
+
+// Quote prices a request using the fee and tax settings from the configuration.
+//
+// Items and modifiers are priced per line. Discounts come off the item total and never take it
+// below zero; a free delivery discount waives the delivery fee instead. The service fee is a
+// percentage of the discounted item total with a minimum, tax is charged on the discounted items
+// plus the fees actually charged at the region's rate, and the tip is added untaxed. Amounts are
+// whole minor units of the currency, so only percentages are rounded.
+func Quote(req Request) (*models.PriceBreakdown, error) {
+	cfg := config.Load()
+	currency := cfg.Currency
+
+	if req.Tip.IsNegative() || req.TipPercent < 0 {
+		return nil, ErrInvalidTip
+	}
+
+	zero := money.New(0, currency)
+	breakdown := &models.PriceBreakdown{
+		Currency:    currency,
+		Lines:       []models.PriceLine{},
+		Subtotal:    zero,
+		Modifiers:   zero,
+		DeliveryFee: zero,
+		ServiceFee:  zero,
+		Discounts:   []models.AppliedDiscount{},
+		Discount:    zero,
+		Tip:         zero,
+	}
+
+	for _, item := range req.Items {
+		if item.Quantity <= 0 {
+			continue
+		}
+		base := item.UnitPrice.Mul(item.Quantity)
+		modifiers := item.ModifiersPrice.Mul(item.Quantity)
+		breakdown.Lines = append(breakdown.Lines, models.PriceLine{
+			Name:      item.Name,
+			Quantity:  item.Quantity,
+			UnitPrice: zero.Add(item.UnitPrice),
+			Modifiers: zero.Add(item.ModifiersPrice),
+			Total:     base.Add(modifiers),
+		})
+		breakdown.Subtotal = breakdown.Subtotal.Add(base)
+		breakdown.Modifiers = breakdown.Modifiers.Add(modifiers)
+	}
+	items := breakdown.Subtotal.Add(breakdown.Modifiers)
+
+	remaining := items
+	for _, discount := range req.Discounts {
+		if discount.FreeDelivery {
+			continue
+		}
+		amount := zero.Add(discount.Amount)
+		if discount.Percent > 0 {
+			amount = items.Percent(discount.Percent)
+		}
+		amount = money.Min(amount, remaining)
+		if !amount.IsPositive() {
+			continue
+		}
+		remaining = remaining.Sub(amount)
+		breakdown.Discount = breakdown.Discount.Add(amount)
+		breakdown.Discounts = append(breakdown.Discounts, models.AppliedDiscount{
+			Code:        discount.Code,
+			Description: discount.Description,
+			Amount:      amount,
+		})
+	}
+
+	if items.IsPositive() {
+		breakdown.ServiceFee = money.Max(remaining.Percent(cfg.ServiceFeePercent), money.FromFloat(cfg.ServiceFeeMinimum, currency))
+	}
+
+	deliveryFee := zero
+	if req.Delivery != nil {
+		fee, err := calculateDeliveryFee(cfg, *req.Delivery)
+		if err != nil {
+			return nil, err
+		}
+		breakdown.DeliveryFee = fee
+		deliveryFee = fee
+	}
+
+	// A waived delivery fee is still shown, with a matching discount
+	for _, discount := range req.Discounts {
+		if !discount.FreeDelivery || deliveryFee.IsZero() {
+			continue
+		}
+		breakdown.Discount = breakdown.Discount.Add(deliveryFee)
+		breakdown.Discounts = append(breakdown.Discounts, models.AppliedDiscount{
+			Code:        discount.Code,
+			Description: discount.Description,
+			Amount:      deliveryFee,
+		})
+		deliveryFee = zero
+	}
+
+	breakdown.Region, breakdown.TaxRate = taxRate(cfg, req.Region)
+	breakdown.Tax = remaining.Add(breakdown.ServiceFee).Add(deliveryFee).Percent(breakdown.TaxRate)
+
+	breakdown.Tip = zero.Add(req.Tip)
+	if req.TipPercent > 0 {
+		breakdown.Tip = items.Percent(req.TipPercent)
+	}
+
+	breakdown.Total = remaining.Add(breakdown.ServiceFee).Add(deliveryFee).Add(breakdown.Tax).Add(breakdown.Tip)
+
+	return breakdown, nil
+}
+
+// calculateDeliveryFee charges the zone's flat fee, or the base fee plus a per-kilometre rate
+// beyond the included distance
+func calculateDeliveryFee(cfg *config.Config, delivery Delivery) (money.Money, error) {
+	if delivery.Zone != "" {
+		fee, ok := cfg.DeliveryZoneFees[delivery.Zone]
+		if !ok {
+			return money.Money{}, fmt.Errorf("%w %q", ErrUnknownZone, delivery.Zone)
+		}
+		return money.FromFloat(fee, cfg.Currency), nil
+	}
+
+	if delivery.DistanceKM < 0 {
+		return money.Money{}, fmt.Errorf("distance cannot be negative")
+	}
+	if cfg.DeliveryRadiusKM > 0 && delivery.DistanceKM > float64(cfg.DeliveryRadiusKM) {
+		return money.Money{}, ErrDeliveryOutOfRange
+	}
+
+	extra := math.Max(delivery.DistanceKM-cfg.DeliveryIncludedKM, 0)
+	return money.FromFloat(cfg.DeliveryBaseFee+extra*cfg.DeliveryFeePerKM, cfg.Currency), nil
+}
+
+// taxRate returns the region a rate was found for and the rate as a percentage, falling back to
+// the "default" entry for regions without their own rate
+func taxRate(cfg *config.Config, region string) (string, float64) {
+	region = strings.TrimSpace(region)
+	if rate, ok := cfg.TaxRates[region]; ok && region != "" {
+		return region, rate
+	}
+	return "default", cfg.TaxRates["default"]
+}
@@ -0,0 +1,292 @@
+package promotion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/code-harsh006/food-delivery/internal/models"
+	"github.com/code-harsh006/food-delivery/internal/pricing"
+	"github.com/code-harsh006/food-delivery/pkg/db"
+	"github.com/code-harsh006/food-delivery/pkg/money"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Purchases a promotion can be applied to
+const (
+	KindBooking = "booking"
+	KindOrder   = "order"
+)
+
+// Reasons a promotion code is rejected
+var (
+	ErrNotFound          = errors.New("promotion code not found")
+	ErrInactive          = errors.New("promotion is not active")
+	ErrNotStarted        = errors.New("promotion has not started yet")
+	ErrExpired           = errors.New("promotion has expired")
+	ErrNotApplicable     = errors.New("promotion does not apply to this purchase")
+	ErrMinSpend          = errors.New("minimum spend for this promotion not reached")
+	ErrFirstOrderOnly    = errors.New("promotion is only valid on a first purchase")
+	ErrUsageLimitReached = errors.New("promotion has been fully redeemed")
+	ErrUserLimitReached  = errors.New("you have already used this promotion the maximum number of times")
+	ErrLoginRequired     = errors.New("log in to use a promotion code")
+)
+
+// Rejected reports whether err explains why a code cannot be used, as opposed to a database failure
+func Rejected(err error) bool {
+	for _, reason := range []error{
+		ErrNotFound, ErrInactive, ErrNotStarted, ErrExpired, ErrNotApplicable,
+		ErrMinSpend, ErrFirstOrderOnly, ErrUsageLimitReached, ErrUserLimitReached, ErrLoginRequired,
+	} {
+		if errors.Is(err, reason) {
+			return true
+		}
+	}
+	return false
+}
+
+// Applied is a validated promotion and the discount it gives on a purchase
+type Applied struct {
+	Promotion models.Promotion
+	Discount  pricing.Discount
+}
+
+// NormalizeCode returns the stored form of a code: trimmed and upper case
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Apply checks that a code can be used by the user on a purchase of the given kind and works out
+// the discount. It does not redeem the code; call Redeem in the checkout transaction for that.
+func Apply(ctx context.Context, code string, userID primitive.ObjectID, kind string, items []pricing.Item) (*Applied, error) {
+	mongoDB := db.GetMongoDB()
+
+	var promo models.Promotion
+	err := mongoDB.Collection("promotions").FindOne(ctx, bson.M{"code": NormalizeCode(code)}).Decode(&promo)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	switch {
+	case !promo.IsActive:
+		return nil, ErrInactive
+	case promo.StartsAt != nil && now.Before(*promo.StartsAt):
+		return nil, ErrNotStarted
+	case promo.EndsAt != nil && !now.Before(*promo.EndsAt):
+		return nil, ErrExpired
+	case promo.AppliesTo != "" && promo.AppliesTo != models.PromotionAppliesToAll && promo.AppliesTo != kind:
+		return nil, ErrNotApplicable
+	case promo.UsageLimit > 0 && promo.RedemptionCount >= promo.UsageLimit:
+		return nil, ErrUsageLimitReached
+	}
+
+	spend := money.New(0, money.Currency())
+	for _, item := range items {
+		spend = spend.Add(item.UnitPrice.Add(item.ModifiersPrice).Mul(item.Quantity))
+	}
+	if spend.Cmp(promo.MinSpend) < 0 {
+		return nil, fmt.Errorf("%w (%s)", ErrMinSpend, promo.MinSpend)
+	}
+
+	if userID.IsZero() {
+		return nil, ErrLoginRequired
+	}
+
+	if promo.PerUserLimit > 0 {
+		var usage models.PromotionUsage
+		err := mongoDB.Collection("promotion_usages").FindOne(ctx, bson.M{"_id": usageID(promo.ID, userID)}).Decode(&usage)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return nil, err
+		}
+		if usage.Count >= promo.PerUserLimit {
+			return nil, ErrUserLimitReached
+		}
+	}
+
+	if promo.FirstOrderOnly {
+		collection := "bookings"
+		if kind == KindOrder {
+			collection = "orders"
+		}
+		previous, err := mongoDB.Collection(collection).CountDocuments(ctx,
+			bson.M{"user_id": userID, "status": bson.M{"$ne": "cancelled"}},
+			options.Count().SetLimit(1))
+		if err != nil {
+			return nil, err
+		}
+		if previous > 0 {
+			return nil, ErrFirstOrderOnly
+		}
+	}
+
+	description := promo.Description
+	if description == "" {
+		description = promo.Code
+	}
+	discount := pricing.Discount{Code: promo.Code, Description: description}
+
+	switch promo.Type {
+	case models.PromotionTypePercentage:
+		discount.Amount = spend.Percent(promo.Value)
+		if promo.MaxDiscount.IsPositive() {
+			discount.Amount = money.Min(discount.Amount, promo.MaxDiscount)
+		}
+	case models.PromotionTypeFixed:
+		discount.Amount = promo.AmountOff
+		if promo.AmountOff.IsZero() {
+			// Kept in value by older versions until the money backfill moves it
+			discount.Amount = money.FromFloat(promo.Value, spend.Currency)
+		}
+	case models.PromotionTypeFreeDelivery:
+		if kind != KindOrder {
+			return nil, ErrNotApplicable
+		}
+		discount.FreeDelivery = true
+	case models.PromotionTypeBuyXGetY:
+		amount := buyXGetYDiscount(promo, items)
+		if kind != KindOrder || amount.IsZero() {
+			return nil, ErrNotApplicable
+		}
+		discount.Amount = amount
+	default:
+		return nil, ErrNotApplicable
+	}
+
+	return &Applied{Promotion: promo, Discount: discount}, nil
+}
+
+// Redeem atomically counts a redemption against the global and per-user limits and records it.
+// Run it inside the transaction that stores the booking or order; when a limit has been reached
+// in the meantime it fails and the purchase is rolled back with it.
+func Redeem(ctx context.Context, applied *Applied, userID primitive.ObjectID, kind string, referenceID primitive.ObjectID, amount money.Money) error {
+	mongoDB := db.GetMongoDB()
+	promo := applied.Promotion
+	now := time.Now()
+
+	result, err := mongoDB.Collection("promotions").UpdateOne(ctx,
+		bson.M{
+			"_id":       promo.ID,
+			"is_active": true,
+			"$or": []bson.M{
+				{"usage_limit": 0},
+				{"$expr": bson.M{"$lt": bson.A{"$redemption_count", "$usage_limit"}}},
+			},
+		},
+		bson.M{"$inc": bson.M{"redemption_count": 1}, "$set": bson.M{"updated_at": now}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrUsageLimitReached
+	}
+
+	// When the user is at the limit the filter misses and the upsert collides with their existing
+	// counter on _id, which is what rejects the redemption
+	usageFilter := bson.M{"_id": usageID(promo.ID, userID)}
+	if promo.PerUserLimit > 0 {
+		usageFilter["count"] = bson.M{"$lt": promo.PerUserLimit}
+	}
+	_, err = mongoDB.Collection("promotion_usages").UpdateOne(ctx, usageFilter,
+		bson.M{
+			"$inc":         bson.M{"count": 1},
+			"$setOnInsert": bson.M{"promotion_id": promo.ID, "user_id": userID},
+		},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		// Outside a transaction the global count is not rolled back, so give it back explicitly
+		mongoDB.Collection("promotions").UpdateOne(ctx, bson.M{"_id": promo.ID}, bson.M{"$inc": bson.M{"redemption_count": -1}})
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrUserLimitReached
+		}
+		return err
+	}
+
+	_, err = mongoDB.Collection("promotion_redemptions").InsertOne(ctx, models.PromotionRedemption{
+		PromotionID:   promo.ID,
+		Code:          promo.Code,
+		UserID:        userID,
+		ReferenceType: kind,
+		ReferenceID:   referenceID,
+		Discount:      amount,
+		CreatedAt:     now,
+	})
+	return err
+}
+
+// Release gives back the redemption made for a booking or order that was cancelled, so the code
+// can be used again. It does nothing when no promotion was redeemed.
+func Release(ctx context.Context, kind string, referenceID primitive.ObjectID) error {
+	mongoDB := db.GetMongoDB()
+
+	var redemption models.PromotionRedemption
+	err := mongoDB.Collection("promotion_redemptions").FindOneAndDelete(ctx,
+		bson.M{"reference_type": kind, "reference_id": referenceID}).Decode(&redemption)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		return err
+	}
+
+	if _, err := mongoDB.Collection("promotions").UpdateOne(ctx,
+		bson.M{"_id": redemption.PromotionID},
+		bson.M{"$inc": bson.M{"redemption_count": -1}}); err != nil {
+		return err
+	}
+	_, err = mongoDB.Collection("promotion_usages").UpdateOne(ctx,
+		bson.M{"_id": usageID(redemption.PromotionID, redemption.UserID)},
+		bson.M{"$inc": bson.M{"count": -1}})
+	return err
+}
+
+// DiscountAmount returns how much the applied promotion took off a priced breakdown
+func DiscountAmount(applied *Applied, breakdown *models.PriceBreakdown) money.Money {
+	total := money.New(0, breakdown.Currency)
+	for _, discount := range breakdown.Discounts {
+		if discount.Code == applied.Promotion.Code {
+			total = total.Add(discount.Amount)
+		}
+	}
+	return total
+}
+
+// buyXGetYDiscount prices the free units: for every BuyQuantity+GetQuantity units of the menu
+// item, GetQuantity are free
+func buyXGetYDiscount(promo models.Promotion, items []pricing.Item) money.Money {
+	group := promo.BuyQuantity + promo.GetQuantity
+	if promo.BuyQuantity <= 0 || promo.GetQuantity <= 0 {
+		return money.Money{}
+	}
+
+	quantity := 0
+	var unitPrice money.Money
+	for _, item := range items {
+		if item.MenuItemID != promo.MenuItemID {
+			continue
+		}
+		quantity += item.Quantity
+		// Modifiers are charged even on free units; only the cheapest base price is given away
+		if unitPrice.IsZero() || item.UnitPrice.Cmp(unitPrice) < 0 {
+			unitPrice = item.UnitPrice
+		}
+	}
+
+	free := (quantity / group) * promo.GetQuantity
+	return unitPrice.Mul(free)
+}
+
+// usageID is the ID of the counter of one user's redemptions of a promotion
+func usageID(promotionID, userID primitive.ObjectID) string {
+	return promotionID.Hex() + ":" + userID.Hex()
+}
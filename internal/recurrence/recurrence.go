@@ -0,0 +1,212 @@
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/code-harsh006/food-delivery/internal/models"
+)
+
+// Frequencies a rule can repeat at
+const (
+	FrequencyWeekly  = "weekly"
+	FrequencyMonthly = "monthly"
+)
+
+// maxOccurrences bounds how many dates a rule is expanded to, so an open-ended rule cannot loop forever
+const maxOccurrences = 1000
+
+var weekdayCodes = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// Parse reads an RRULE such as "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;COUNT=10". FREQ, INTERVAL,
+// BYDAY, BYMONTHDAY, COUNT and UNTIL (YYYYMMDD) are supported.
+func Parse(value string) (models.RecurrenceRule, error) {
+	var rule models.RecurrenceRule
+	value = strings.TrimPrefix(strings.TrimSpace(value), "RRULE:")
+
+	for _, part := range strings.Split(value, ";") {
+		if part == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(part, "=")
+		if !ok {
+			return models.RecurrenceRule{}, fmt.Errorf("invalid rule part %q", part)
+		}
+
+		var err error
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			rule.Frequency = strings.ToLower(val)
+		case "INTERVAL":
+			rule.Interval, err = strconv.Atoi(val)
+		case "COUNT":
+			rule.Count, err = strconv.Atoi(val)
+		case "BYMONTHDAY":
+			rule.MonthDay, err = strconv.Atoi(val)
+		case "UNTIL":
+			var until time.Time
+			until, err = time.Parse("20060102", val[:min(len(val), 8)])
+			rule.Until = &until
+		case "BYDAY":
+			for _, code := range strings.Split(strings.ToUpper(val), ",") {
+				day := indexOf(weekdayCodes, code)
+				if day < 0 {
+					return models.RecurrenceRule{}, fmt.Errorf("invalid BYDAY value %q", code)
+				}
+				rule.Weekdays = append(rule.Weekdays, day)
+			}
+		default:
+			return models.RecurrenceRule{}, fmt.Errorf("unsupported rule part %q", key)
+		}
+		if err != nil {
+			return models.RecurrenceRule{}, fmt.Errorf("invalid %s value %q", strings.ToUpper(key), val)
+		}
+	}
+
+	return rule, nil
+}
+
+// Format formats a rule as an RRULE
+func Format(r models.RecurrenceRule) string {
+	parts := []string{"FREQ=" + strings.ToUpper(r.Frequency)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.Weekdays) > 0 {
+		codes := make([]string, 0, len(r.Weekdays))
+		for _, day := range r.Weekdays {
+			codes = append(codes, weekdayCodes[day])
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	if r.MonthDay > 0 {
+		parts = append(parts, "BYMONTHDAY="+strconv.Itoa(r.MonthDay))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.Format("20060102"))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Normalize validates a rule and fills in defaults from the first date of the series: an
+// interval of 1, the start's weekday for weekly rules and the start's day for monthly rules
+func Normalize(r models.RecurrenceRule, start time.Time) (models.RecurrenceRule, error) {
+	if r.Interval == 0 {
+		r.Interval = 1
+	}
+	if r.Interval < 1 {
+		return models.RecurrenceRule{}, errors.New("interval must be at least 1")
+	}
+	if r.Count < 0 {
+		return models.RecurrenceRule{}, errors.New("count must not be negative")
+	}
+	if r.Count == 0 && r.Until == nil {
+		return models.RecurrenceRule{}, errors.New("a recurring rule needs a count or an until date")
+	}
+	if r.Until != nil && r.Until.Before(start) {
+		return models.RecurrenceRule{}, errors.New("until must not be before the start date")
+	}
+
+	switch r.Frequency {
+	case FrequencyWeekly:
+		if len(r.Weekdays) == 0 {
+			r.Weekdays = []int{int(start.Weekday())}
+		}
+		seen := make(map[int]bool)
+		for _, day := range r.Weekdays {
+			if day < 0 || day > 6 {
+				return models.RecurrenceRule{}, errors.New("weekdays must be between 0 (Sunday) and 6 (Saturday)")
+			}
+			if seen[day] {
+				return models.RecurrenceRule{}, errors.New("weekdays must not repeat a day")
+			}
+			seen[day] = true
+		}
+		sort.Ints(r.Weekdays)
+		r.MonthDay = 0
+	case FrequencyMonthly:
+		if r.MonthDay == 0 {
+			r.MonthDay = start.Day()
+		}
+		if r.MonthDay < 1 || r.MonthDay > 31 {
+			return models.RecurrenceRule{}, errors.New("month_day must be between 1 and 31")
+		}
+		r.Weekdays = nil
+	default:
+		return models.RecurrenceRule{}, errors.New("frequency must be weekly or monthly")
+	}
+
+	return r, nil
+}
+
+// Dates returns the dates of a normalized rule starting on start, up to and including through.
+// Months without the rule's day (e.g. the 31st in April) are skipped, as in iCalendar.
+func Dates(r models.RecurrenceRule, start, through time.Time) []time.Time {
+	start = day(start)
+	if r.Until != nil && r.Until.Before(through) {
+		through = *r.Until
+	}
+	through = day(through)
+
+	var dates []time.Time
+	emit := func(date time.Time) bool {
+		if date.Before(start) {
+			return true
+		}
+		if date.After(through) || (r.Count > 0 && len(dates) >= r.Count) || len(dates) >= maxOccurrences {
+			return false
+		}
+		dates = append(dates, date)
+		return true
+	}
+
+	switch r.Frequency {
+	case FrequencyWeekly:
+		if len(r.Weekdays) == 0 {
+			return nil
+		}
+		weekStart := start.AddDate(0, 0, -int(start.Weekday()))
+		for week := 0; ; week += r.Interval {
+			for _, weekday := range r.Weekdays {
+				if !emit(weekStart.AddDate(0, 0, week*7+weekday)) {
+					return dates
+				}
+			}
+		}
+	case FrequencyMonthly:
+		for month := 0; month < maxOccurrences*r.Interval; month += r.Interval {
+			first := time.Date(start.Year(), start.Month()+time.Month(month), 1, 0, 0, 0, 0, start.Location())
+			date := first.AddDate(0, 0, r.MonthDay-1)
+			if date.Month() != first.Month() {
+				if first.After(through) {
+					return dates
+				}
+				continue
+			}
+			if !emit(date) {
+				return dates
+			}
+		}
+	}
+	return dates
+}
+
+func day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+func indexOf(values []string, value string) int {
+	for i, v := range values {
+		if v == value {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,230 @@
+package recurrence
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/code-harsh006/food-delivery/internal/models"
+)
+
+// date parses a YYYY-MM-DD date as midnight UTC
+func date(value string) time.Time {
+	parsed, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+// until returns a pointer to a date, for RecurrenceRule.Until
+func until(value string) *time.Time {
+	parsed := date(value)
+	return &parsed
+}
+
+// formatDates formats dates as YYYY-MM-DD so failures are readable
+func formatDates(dates []time.Time) []string {
+	formatted := make([]string, 0, len(dates))
+	for _, d := range dates {
+		formatted = append(formatted, d.Format("2006-01-02"))
+	}
+	return formatted
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    models.RecurrenceRule
+		wantErr bool
+	}{
+		{
+			name:  "weekly with interval and days",
+			value: "RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;COUNT=10",
+			want:  models.RecurrenceRule{Frequency: FrequencyWeekly, Interval: 2, Weekdays: []int{1, 4}, Count: 10},
+		},
+		{
+			name:  "monthly until a date-time",
+			value: "FREQ=MONTHLY;BYMONTHDAY=31;UNTIL=20251231T235959Z",
+			want:  models.RecurrenceRule{Frequency: FrequencyMonthly, MonthDay: 31, Until: until("2025-12-31")},
+		},
+		{
+			name:  "lower case and a trailing separator",
+			value: " freq=weekly;byday=su,sa;count=3; ",
+			want:  models.RecurrenceRule{Frequency: FrequencyWeekly, Weekdays: []int{0, 6}, Count: 3},
+		},
+		{name: "unknown weekday", value: "FREQ=WEEKLY;BYDAY=MO,XX", wantErr: true},
+		{name: "part without a value", value: "FREQ=WEEKLY;COUNT", wantErr: true},
+		{name: "unsupported part", value: "FREQ=MONTHLY;BYSETPOS=1", wantErr: true},
+		{name: "interval not a number", value: "FREQ=WEEKLY;INTERVAL=two", wantErr: true},
+		{name: "count empty", value: "FREQ=WEEKLY;COUNT=", wantErr: true},
+		{name: "until not a date", value: "FREQ=WEEKLY;UNTIL=2025", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Parse(%q) = %+v, want an error", tt.value, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.value, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	wednesday := date("2025-01-15")
+	tests := []struct {
+		name    string
+		rule    models.RecurrenceRule
+		want    models.RecurrenceRule
+		wantErr bool
+	}{
+		{
+			name: "weekly defaults to the start's weekday and an interval of 1",
+			rule: models.RecurrenceRule{Frequency: FrequencyWeekly, Count: 4},
+			want: models.RecurrenceRule{Frequency: FrequencyWeekly, Interval: 1, Weekdays: []int{3}, Count: 4},
+		},
+		{
+			name: "weekly sorts its days and drops a month day",
+			rule: models.RecurrenceRule{Frequency: FrequencyWeekly, Interval: 2, Weekdays: []int{5, 1}, MonthDay: 10, Count: 4},
+			want: models.RecurrenceRule{Frequency: FrequencyWeekly, Interval: 2, Weekdays: []int{1, 5}, Count: 4},
+		},
+		{
+			name: "monthly defaults to the start's day and drops weekdays",
+			rule: models.RecurrenceRule{Frequency: FrequencyMonthly, Weekdays: []int{1}, Until: until("2025-06-30")},
+			want: models.RecurrenceRule{Frequency: FrequencyMonthly, Interval: 1, MonthDay: 15, Until: until("2025-06-30")},
+		},
+		{name: "no count or until", rule: models.RecurrenceRule{Frequency: FrequencyWeekly}, wantErr: true},
+		{name: "negative interval", rule: models.RecurrenceRule{Frequency: FrequencyWeekly, Interval: -1, Count: 1}, wantErr: true},
+		{name: "negative count", rule: models.RecurrenceRule{Frequency: FrequencyWeekly, Count: -1}, wantErr: true},
+		{name: "until before start", rule: models.RecurrenceRule{Frequency: FrequencyWeekly, Until: until("2025-01-14")}, wantErr: true},
+		{name: "weekday out of range", rule: models.RecurrenceRule{Frequency: FrequencyWeekly, Weekdays: []int{7}, Count: 1}, wantErr: true},
+		{name: "repeated weekday", rule: models.RecurrenceRule{Frequency: FrequencyWeekly, Weekdays: []int{1, 1}, Count: 1}, wantErr: true},
+		{name: "month day out of range", rule: models.RecurrenceRule{Frequency: FrequencyMonthly, MonthDay: 32, Count: 1}, wantErr: true},
+		{name: "unknown frequency", rule: models.RecurrenceRule{Frequency: "daily", Count: 1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Normalize(tt.rule, wednesday)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Normalize(%+v) = %+v, want an error", tt.rule, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Normalize(%+v) error = %v", tt.rule, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Normalize(%+v) = %+v, want %+v", tt.rule, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDates(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    models.RecurrenceRule
+		start   string
+		through string
+		want    []string
+	}{
+		{
+			// Weeks are counted from the Sunday of the start's week, so Monday 2024-12-30 is
+			// before the start and skipped, and every other week follows from there
+			name:    "weekly every other week from midweek",
+			rule:    models.RecurrenceRule{Frequency: FrequencyWeekly, Interval: 2, Weekdays: []int{1, 4}, Count: 5},
+			start:   "2025-01-01",
+			through: "2025-12-31",
+			want:    []string{"2025-01-02", "2025-01-13", "2025-01-16", "2025-01-27", "2025-01-30"},
+		},
+		{
+			name:    "weekly every third week starting on a Sunday",
+			rule:    models.RecurrenceRule{Frequency: FrequencyWeekly, Interval: 3, Weekdays: []int{0, 6}, Count: 4},
+			start:   "2025-01-05",
+			through: "2025-12-31",
+			want:    []string{"2025-01-05", "2025-01-11", "2025-01-26", "2025-02-01"},
+		},
+		{
+			name:    "monthly skips months without the day",
+			rule:    models.RecurrenceRule{Frequency: FrequencyMonthly, Interval: 1, MonthDay: 31, Count: 4},
+			start:   "2025-01-31",
+			through: "2025-12-31",
+			want:    []string{"2025-01-31", "2025-03-31", "2025-05-31", "2025-07-31"},
+		},
+		{
+			name:    "monthly every other month skips short months",
+			rule:    models.RecurrenceRule{Frequency: FrequencyMonthly, Interval: 2, MonthDay: 31, Count: 5},
+			start:   "2025-01-31",
+			through: "2026-12-31",
+			want:    []string{"2025-01-31", "2025-03-31", "2025-05-31", "2025-07-31", "2026-01-31"},
+		},
+		{
+			name:    "monthly skips February in a leap year for the 30th",
+			rule:    models.RecurrenceRule{Frequency: FrequencyMonthly, Interval: 1, MonthDay: 30, Count: 3},
+			start:   "2024-01-30",
+			through: "2024-12-31",
+			want:    []string{"2024-01-30", "2024-03-30", "2024-04-30"},
+		},
+		{
+			name:    "monthly day before the start begins the next month",
+			rule:    models.RecurrenceRule{Frequency: FrequencyMonthly, Interval: 1, MonthDay: 10, Count: 2},
+			start:   "2025-01-15",
+			through: "2025-12-31",
+			want:    []string{"2025-02-10", "2025-03-10"},
+		},
+		{
+			name:    "until before count runs out",
+			rule:    models.RecurrenceRule{Frequency: FrequencyWeekly, Interval: 1, Weekdays: []int{1}, Count: 10, Until: until("2025-01-20")},
+			start:   "2025-01-06",
+			through: "2025-12-31",
+			want:    []string{"2025-01-06", "2025-01-13", "2025-01-20"},
+		},
+		{
+			name:    "count runs out before until",
+			rule:    models.RecurrenceRule{Frequency: FrequencyWeekly, Interval: 1, Weekdays: []int{1}, Count: 2, Until: until("2025-03-01")},
+			start:   "2025-01-06",
+			through: "2025-12-31",
+			want:    []string{"2025-01-06", "2025-01-13"},
+		},
+		{
+			name:    "through before count and until",
+			rule:    models.RecurrenceRule{Frequency: FrequencyWeekly, Interval: 1, Weekdays: []int{1}, Count: 10, Until: until("2025-03-01")},
+			start:   "2025-01-06",
+			through: "2025-01-19",
+			want:    []string{"2025-01-06", "2025-01-13"},
+		},
+		{
+			name:    "monthly until ends in a skipped month",
+			rule:    models.RecurrenceRule{Frequency: FrequencyMonthly, Interval: 1, MonthDay: 31, Until: until("2025-06-30")},
+			start:   "2025-01-31",
+			through: "2025-12-31",
+			want:    []string{"2025-01-31", "2025-03-31", "2025-05-31"},
+		},
+		{
+			name:    "start after through",
+			rule:    models.RecurrenceRule{Frequency: FrequencyWeekly, Interval: 1, Weekdays: []int{1}, Count: 3},
+			start:   "2025-02-03",
+			through: "2025-01-31",
+			want:    []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := formatDates(Dates(tt.rule, date(tt.start), date(tt.through)))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Dates(%s) from %s through %s = %v, want %v", Format(tt.rule), tt.start, tt.through, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,365 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/code-harsh006/food-delivery/internal/availability"
+	"github.com/code-harsh006/food-delivery/internal/localtime"
+	"github.com/code-harsh006/food-delivery/internal/models"
+	"github.com/code-harsh006/food-delivery/pkg/db"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GetServiceAvailability returns the bookable slots of a service on a local date. A booking
+// with more services or add-ons passes its total length in minutes as duration, so only the
+// start times it fits at are offered.
+func GetServiceAvailability(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	serviceID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service ID"})
+		return
+	}
+
+	date, err := time.Parse(availability.DateFormat, c.Query("date"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use YYYY-MM-DD"})
+		return
+	}
+
+	duration := 0
+	if value := c.Query("duration"); value != "" {
+		if duration, err = strconv.Atoi(value); err != nil || duration < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "duration must be a whole number of minutes"})
+			return
+		}
+	}
+
+	var service models.Service
+	err = mongoDB.Collection("services").FindOne(context.Background(), bson.M{"_id": serviceID, "is_active": true}).Decode(&service)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if duration == 0 {
+		duration = service.Duration
+	}
+
+	// Slot times are local to the vendor's or region's timezone
+	timezone, err := localtime.Resolve(context.Background(), service, c.Query("region"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	slots, scheduled, err := availability.Slots(context.Background(), service, localtime.Midnight(date, localtime.Location(timezone)), duration)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch availability"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"service_id": serviceID,
+		"date":       date.Format(availability.DateFormat),
+		"timezone":   timezone,
+		"duration":   duration,
+		"scheduled":  scheduled,
+		"slots":      slots,
+	})
+}
+
+// CreateSlotTemplate adds opening hours and capacity for a service or a vendor (admin only)
+func CreateSlotTemplate(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	var req models.CreateSlotTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	template := models.SlotTemplate{
+		Weekdays:    req.Weekdays,
+		StartTime:   req.StartTime,
+		EndTime:     req.EndTime,
+		SlotMinutes: req.SlotMinutes,
+		Capacity:    req.Capacity,
+		IsActive:    req.IsActive == nil || *req.IsActive,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+
+	if (req.ServiceID == "") == (req.VendorID == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Set exactly one of service_id or vendor_id"})
+		return
+	}
+	if req.ServiceID != "" {
+		serviceID, err := primitive.ObjectIDFromHex(req.ServiceID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service ID"})
+			return
+		}
+		count, err := mongoDB.Collection("services").CountDocuments(context.Background(), bson.M{"_id": serviceID})
+		if err != nil || count == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Service not found"})
+			return
+		}
+		template.ServiceID = serviceID
+	} else {
+		vendorID, err := primitive.ObjectIDFromHex(req.VendorID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid vendor ID"})
+			return
+		}
+		template.VendorID = vendorID
+	}
+
+	if err := validateSlotTemplate(template); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := mongoDB.Collection("slot_templates").InsertOne(context.Background(), template)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create slot template"})
+		return
+	}
+
+	template.ID = result.InsertedID.(primitive.ObjectID)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":       "Slot template created successfully",
+		"slot_template": template,
+	})
+}
+
+// GetSlotTemplates lists slot templates, optionally for one service or vendor (admin only)
+func GetSlotTemplates(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	filter := bson.M{}
+	if serviceID, err := primitive.ObjectIDFromHex(c.Query("service_id")); err == nil {
+		filter["service_id"] = serviceID
+	}
+	if vendorID, err := primitive.ObjectIDFromHex(c.Query("vendor_id")); err == nil {
+		filter["vendor_id"] = vendorID
+	}
+
+	cursor, err := mongoDB.Collection("slot_templates").Find(context.Background(), filter,
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch slot templates"})
+		return
+	}
+	defer cursor.Close(context.Background())
+
+	templates := []models.SlotTemplate{}
+	if err = cursor.All(context.Background(), &templates); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode slot templates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"slot_templates": templates,
+		"total":          len(templates),
+	})
+}
+
+// UpdateSlotTemplate changes the days, hours or capacity of a slot template (admin only).
+// Bookings already made keep their slots.
+func UpdateSlotTemplate(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	templateID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid slot template ID"})
+		return
+	}
+
+	var req models.UpdateSlotTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	collection := mongoDB.Collection("slot_templates")
+	var template models.SlotTemplate
+	if err := collection.FindOne(context.Background(), bson.M{"_id": templateID}).Decode(&template); err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Slot template not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	if req.Weekdays != nil {
+		template.Weekdays = req.Weekdays
+	}
+	if req.StartTime != nil {
+		template.StartTime = *req.StartTime
+	}
+	if req.EndTime != nil {
+		template.EndTime = *req.EndTime
+	}
+	if req.SlotMinutes != nil {
+		template.SlotMinutes = *req.SlotMinutes
+	}
+	if req.Capacity != nil {
+		template.Capacity = *req.Capacity
+	}
+	if req.IsActive != nil {
+		template.IsActive = *req.IsActive
+	}
+	template.UpdatedAt = time.Now()
+
+	if err := validateSlotTemplate(template); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	_, err = collection.UpdateOne(context.Background(), bson.M{"_id": templateID}, bson.M{"$set": bson.M{
+		"weekdays":     template.Weekdays,
+		"start_time":   template.StartTime,
+		"end_time":     template.EndTime,
+		"slot_minutes": template.SlotMinutes,
+		"capacity":     template.Capacity,
+		"is_active":    template.IsActive,
+		"updated_at":   template.UpdatedAt,
+	}})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update slot template"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Slot template updated successfully",
+		"slot_template": template,
+	})
+}
+
+// DeleteSlotTemplate removes a slot template (admin only). Bookings already made are kept.
+func DeleteSlotTemplate(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	templateID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid slot template ID"})
+		return
+	}
+
+	result, err := mongoDB.Collection("slot_templates").DeleteOne(context.Background(), bson.M{"_id": templateID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete slot template"})
+		return
+	}
+	if result.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Slot template not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Slot template deleted successfully"})
+}
+
+// validateSlotTemplate checks the days, hours and capacity of a slot template
+func validateSlotTemplate(template models.SlotTemplate) error {
+	if len(template.Weekdays) == 0 {
+		return errors.New("weekdays must list at least one day")
+	}
+	seen := make(map[int]bool)
+	for _, day := range template.Weekdays {
+		if day < 0 || day > 6 {
+			return errors.New("weekdays must be between 0 (Sunday) and 6 (Saturday)")
+		}
+		if seen[day] {
+			return errors.New("weekdays must not repeat a day")
+		}
+		seen[day] = true
+	}
+
+	start, err := availability.ParseClock(template.StartTime)
+	if err != nil {
+		return err
+	}
+	end, err := availability.ParseClock(template.EndTime)
+	if err != nil {
+		return err
+	}
+	if end <= start {
+		return errors.New("end_time must be after start_time")
+	}
+
+	if template.SlotMinutes < 0 || (template.SlotMinutes > 0 && template.SlotMinutes < 5) {
+		return errors.New("slot_minutes must be at least 5, or 0 to use the service duration (60 minutes for a vendor)")
+	}
+	if template.Capacity < 1 {
+		return errors.New("capacity must be at least 1")
+	}
+	return nil
+}
+
+// respondSlotError writes the response for a slot that cannot be booked and reports whether err was one
+func respondSlotError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, availability.ErrSlotFull):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, availability.ErrSlotUnavailable):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		return false
+	}
+	return true
+}
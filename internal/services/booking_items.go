@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/code-harsh006/food-delivery/internal/models"
+	"github.com/code-harsh006/food-delivery/internal/staffing"
+	"github.com/code-harsh006/food-delivery/pkg/db"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxBookingItems caps the number of services in one booking
+const maxBookingItems = 10
+
+// resolveBookingItems loads the services and add-ons a booking or quote asks for, either one
+// service with service_id and add_ons or several with items. It returns the first service, which
+// the booking is scheduled and staffed under, writing the response and returning false when the
+// request cannot be booked. All services must be active and belong to the same vendor.
+func resolveBookingItems(c *gin.Context, serviceID string, addOns []string, requested []models.BookingItemRequest) (models.Service, []models.BookingItem, bool) {
+	switch {
+	case len(requested) == 0 && serviceID == "":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "service_id or items is required"})
+		return models.Service{}, nil, false
+	case len(requested) > 0 && (serviceID != "" || len(addOns) > 0):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Set either service_id and add_ons or items, not both"})
+		return models.Service{}, nil, false
+	case len(requested) > maxBookingItems:
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("A booking can have at most %d services", maxBookingItems)})
+		return models.Service{}, nil, false
+	case len(requested) == 0:
+		requested = []models.BookingItemRequest{{ServiceID: serviceID, AddOns: addOns}}
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(requested))
+	for _, item := range requested {
+		id, err := primitive.ObjectIDFromHex(item.ServiceID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service ID", "service_id": item.ServiceID})
+			return models.Service{}, nil, false
+		}
+		ids = append(ids, id)
+	}
+
+	cursor, err := db.GetMongoDB().Collection("services").Find(context.Background(), bson.M{"_id": bson.M{"$in": ids}, "is_active": true})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return models.Service{}, nil, false
+	}
+	var found []models.Service
+	if err := cursor.All(context.Background(), &found); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return models.Service{}, nil, false
+	}
+	services := make(map[primitive.ObjectID]models.Service, len(found))
+	for _, service := range found {
+		services[service.ID] = service
+	}
+
+	items := make([]models.BookingItem, 0, len(requested))
+	for i, id := range ids {
+		service, ok := services[id]
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Service not found", "service_id": id.Hex()})
+			return models.Service{}, nil, false
+		}
+		if service.VendorID != services[ids[0]].VendorID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "All services in a booking must be from the same vendor", "service_id": id.Hex()})
+			return models.Service{}, nil, false
+		}
+
+		chosen := make([]models.ServiceAddOn, 0, len(requested[i].AddOns))
+		for _, name := range requested[i].AddOns {
+			addOn, ok := findAddOn(service, name)
+			if !ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": service.Name + " has no add-on named " + name, "service_id": id.Hex()})
+				return models.Service{}, nil, false
+			}
+			for _, previous := range chosen {
+				if previous.Name == addOn.Name {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "Add-on " + addOn.Name + " is chosen more than once", "service_id": id.Hex()})
+					return models.Service{}, nil, false
+				}
+			}
+			chosen = append(chosen, addOn)
+		}
+		items = append(items, bookingItem(service, chosen))
+	}
+
+	return services[ids[0]], items, true
+}
+
+// findAddOn looks up one of a service's add-ons by name, ignoring case
+func findAddOn(service models.Service, name string) (models.ServiceAddOn, bool) {
+	for _, addOn := range service.AddOns {
+		if strings.EqualFold(addOn.Name, strings.TrimSpace(name)) {
+			return addOn, true
+		}
+	}
+	return models.ServiceAddOn{}, false
+}
+
+// bookingItem is a service booked with the given add-ons, at its current price and duration
+func bookingItem(service models.Service, addOns []models.ServiceAddOn) models.BookingItem {
+	return models.BookingItem{
+		ServiceID: service.ID,
+		Name:      service.Name,
+		Price:     service.BasePrice,
+		Duration:  service.Duration,
+		Skills:    service.Skills,
+		AddOns:    addOns,
+	}
+}
+
+// bookingDuration adds up the minutes of every item and add-on. It is 0 when none of them has a
+// duration, leaving the length of the booking to its service.
+func bookingDuration(items []models.BookingItem) int {
+	total := 0
+	for _, item := range items {
+		total += item.Duration
+		for _, addOn := range item.AddOns {
+			total += addOn.Duration
+		}
+	}
+	return total
+}
+
+// staffedService returns the service a booking is staffed as: its first service, needing the
+// skills of every service in the booking
+func staffedService(booking models.Booking, service models.Service) models.Service {
+	if len(booking.Items) < 2 {
+		return service
+	}
+	skills := append([]string(nil), service.Skills...)
+	for _, item := range booking.Items {
+		skills = append(skills, item.Skills...)
+	}
+	service.Skills = staffing.NormalizeSkills(skills)
+	return service
+}
+
+// bookingName describes what was booked for notifications, e.g. "Deep cleaning + Fridge cleaning"
+func bookingName(booking models.Booking, service models.Service) string {
+	if len(booking.Items) < 2 {
+		return service.Name
+	}
+	names := make([]string, 0, len(booking.Items))
+	for _, item := range booking.Items {
+		names = append(names, item.Name)
+	}
+	return strings.Join(names, " + ")
+}
@@ -2,15 +2,22 @@ package services
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"time"
 
+	"github.com/code-harsh006/food-delivery/internal/availability"
+	"github.com/code-harsh006/food-delivery/internal/localtime"
 	"github.com/code-harsh006/food-delivery/internal/models"
+	"github.com/code-harsh006/food-delivery/internal/outbox"
+	"github.com/code-harsh006/food-delivery/internal/promotion"
+	"github.com/code-harsh006/food-delivery/pkg/config"
 	"github.com/code-harsh006/food-delivery/pkg/db"
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // CreateBooking handles booking creation
@@ -38,87 +45,137 @@ func CreateBooking(c *gin.Context) {
 		return
 	}
 
-	// Parse service ID
-	serviceID, err := primitive.ObjectIDFromHex(req.ServiceID)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service ID"})
+	// Verify the services exist; the booking is scheduled under the first one
+	service, bookedItems, ok := resolveBookingItems(c, req.ServiceID, req.AddOns, req.Items)
+	if !ok {
 		return
 	}
 
-	// Verify service exists
-	var service models.Service
-	serviceCollection := mongoDB.Collection("services")
-	err = serviceCollection.FindOne(context.Background(), bson.M{"_id": serviceID, "is_active": true}).Decode(&service)
+	// The date and time are local to the vendor's or region's timezone
+	timezone, err := localtime.Resolve(context.Background(), service, req.Region)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
-			return
-		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
 		return
 	}
+	var booking models.Booking
+	if err := setBookingTime(&booking, req.ScheduledDate, req.ScheduledTime, timezone); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	// Parse scheduled date
-	scheduledDate, err := time.Parse("2006-01-02", req.ScheduledDate)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use YYYY-MM-DD"})
+	items := bookingItems(bookedItems)
+	applied, ok := applyPromotion(c, req.PromoCode, userID, promotion.KindBooking, items)
+	if !ok {
 		return
 	}
 
-	// Check if the date is not in the past
-	if scheduledDate.Before(time.Now().Truncate(24 * time.Hour)) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot book services for past dates"})
+	quote, err := bookingQuote(items, req.Region, req.Tip, req.TipPercent, applied)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Create booking
-	booking := models.Booking{
-		UserID:          userID,
-		ServiceID:       serviceID,
-		ScheduledDate:   scheduledDate,
-		ScheduledTime:   req.ScheduledTime,
-		Status:          "pending",
-		TotalAmount:     service.BasePrice,
-		PaymentStatus:   "pending",
-		SpecialRequests: req.SpecialRequests,
-		CreatedAt:       time.Now(),
-		UpdatedAt:       time.Now(),
-	}
-
-	bookingCollection := mongoDB.Collection("bookings")
-	result, err := bookingCollection.InsertOne(context.Background(), booking)
-	if err != nil {
+	booking.UserID = userID
+	booking.ServiceID = service.ID
+	booking.Items = bookedItems
+	booking.Duration = bookingDuration(bookedItems)
+	booking.Status = models.BookingStatusPending
+	booking.TotalAmount = quote.Total
+	booking.Pricing = quote
+	booking.PromoCode = promotionCode(applied)
+	booking.PaymentStatus = models.PaymentStatusPending
+	booking.SpecialRequests = req.SpecialRequests
+	booking.CreatedAt = time.Now()
+	booking.UpdatedAt = time.Now()
+
+	if err := placeBooking(&booking, service, applied); err != nil {
+		if promotion.Rejected(err) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "promo_code": req.PromoCode})
+			return
+		}
+		if respondSlotError(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create booking"})
 		return
 	}
 
-	booking.ID = result.InsertedID.(primitive.ObjectID)
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Booking created successfully",
+		"booking": booking,
+	})
+}
+
+// placeBooking stores a new booking in one transaction together with its slot, the promotion
+// redemption, its first history entry and the customer's notification
+func placeBooking(booking *models.Booking, service models.Service, applied *promotion.Applied) error {
+	err := db.WithTransaction(context.Background(), func(ctx context.Context) error {
+		// Hold the slot first so the booking is only stored when there is room for it
+		slots, err := availability.Claim(ctx, service, bookingDay(*booking), booking.LocalTime(), booking.Duration, nil)
+		if err != nil {
+			return err
+		}
+		booking.CapacitySlots = slots
+
+		if err := storeBooking(ctx, booking, service, applied); err != nil {
+			// Outside a transaction nothing is rolled back, so give the slot back explicitly
+			availability.Release(ctx, slots)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	outbox.Wake()
+	return nil
+}
+
+// storeBooking redeems the promotion of a booking whose slot is already held, inserts the booking
+// and writes its first history entry and the customer's notification. Run it inside a transaction.
+func storeBooking(ctx context.Context, booking *models.Booking, service models.Service, applied *promotion.Applied) error {
+	mongoDB := db.GetMongoDB()
+
+	if booking.ID.IsZero() {
+		booking.ID = primitive.NewObjectID()
+	}
+	if len(booking.Items) == 0 {
+		booking.Items = []models.BookingItem{bookingItem(service, nil)}
+	}
+	booking.RemindersSent = skippedReminders(booking.ScheduledAt)
+
+	// Redeem first: without transactions a promotion that turns the booking down must not leave
+	// the booking behind
+	if applied != nil {
+		if err := promotion.Redeem(ctx, applied, booking.UserID, promotion.KindBooking, booking.ID, promotion.DiscountAmount(applied, booking.Pricing)); err != nil {
+			return err
+		}
+	}
+	if _, err := mongoDB.Collection("bookings").InsertOne(ctx, booking); err != nil {
+		promotion.Release(ctx, promotion.KindBooking, booking.ID)
+		return err
+	}
 
 	// Create initial booking status
 	status := models.BookingStatus{
 		BookingID: booking.ID,
-		Status:    "pending",
+		Status:    models.BookingStatusPending,
 		Message:   "Booking created successfully",
 		UpdatedBy: "system",
 		CreatedAt: time.Now(),
 	}
-	statusCollection := mongoDB.Collection("booking_statuses")
-	statusCollection.InsertOne(context.Background(), status)
-
-	// Send notification to user
-	notification := models.Notification{
-		UserID:    userID,
-		Title:     "Booking Confirmed",
-		Message:   "Your booking for " + service.Name + " has been created successfully",
-		Type:      "booking",
-		CreatedAt: time.Now(),
+	if _, err := mongoDB.Collection("booking_statuses").InsertOne(ctx, status); err != nil {
+		return err
 	}
-	notificationCollection := mongoDB.Collection("notifications")
-	notificationCollection.InsertOne(context.Background(), notification)
 
-	c.JSON(http.StatusCreated, gin.H{
-		"message": "Booking created successfully",
-		"booking": booking,
+	// Send notification to user
+	return outbox.EnqueueNotification(ctx, models.Notification{
+		UserID:  booking.UserID,
+		Title:   "Booking Confirmed",
+		Message: "Your booking for " + bookingName(*booking, service) + " on " + localBookingTime(*booking) + " has been created successfully",
+		Type:    "booking",
 	})
 }
 
@@ -225,20 +282,34 @@ func UpdateBooking(c *gin.Context) {
 		return
 	}
 
+	// Status changes go through the booking state machine
+	if req.Status != "" && req.Status != booking.Status {
+		if !isBookingStatus(req.Status) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking status"})
+			return
+		}
+		var updated *models.Booking
+		actor := bookingActor{role: actorCustomer, id: userID}
+		err := db.WithTransaction(context.Background(), func(ctx context.Context) error {
+			var err error
+			updated, err = transitionBooking(ctx, bson.M{"_id": bookingID, "user_id": userID}, req.Status, actor, "Booking updated by customer")
+			if err != nil || updated.Status != models.BookingStatusCancelled {
+				return err
+			}
+			return outbox.EnqueueNotification(ctx, cancellationNotification(*updated))
+		})
+		if err != nil {
+			respondBookingTransitionError(c, updated, req.Status, err)
+			return
+		}
+		outbox.Wake()
+	}
+
 	// Update booking fields
 	updateData := bson.M{"updated_at": time.Now()}
-	if req.Status != "" {
-		updateData["status"] = req.Status
-	}
 	if req.TechnicianNotes != "" {
 		updateData["technician_notes"] = req.TechnicianNotes
 	}
-	if req.Rating > 0 && req.Rating <= 5 {
-		updateData["rating"] = req.Rating
-	}
-	if req.Review != "" {
-		updateData["review"] = req.Review
-	}
 
 	_, err = collection.UpdateOne(context.Background(), bson.M{"_id": bookingID}, bson.M{"$set": updateData})
 	if err != nil {
@@ -264,15 +335,101 @@ func CancelBooking(c *gin.Context) {
 		return
 	}
 
+	var req models.CancelBookingRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.RefundTo == "" {
+		req.RefundTo = models.RefundToOriginal
+	}
+	if req.RefundTo != models.RefundToOriginal && req.RefundTo != models.RefundToWallet {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "refund_to must be original or wallet"})
+		return
+	}
+
 	userID := getUserIDFromContext(c)
 	if userID.IsZero() {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
 		return
 	}
 
+	var booking *models.Booking
+	actor := bookingActor{role: actorCustomer, id: userID}
+	err = db.WithTransaction(context.Background(), func(ctx context.Context) error {
+		booking, err = transitionBookingRefundingTo(ctx, bson.M{"_id": bookingID, "user_id": userID}, models.BookingStatusCancelled, actor, "Booking cancelled by user", req.RefundTo)
+		if err != nil {
+			return err
+		}
+
+		// Send notification
+		return outbox.EnqueueNotification(ctx, cancellationNotification(*booking))
+	})
+	if err != nil {
+		respondBookingTransitionError(c, booking, models.BookingStatusCancelled, err)
+		return
+	}
+	outbox.Wake()
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Booking cancelled successfully",
+		"cancellation": booking.Cancellation,
+	})
+}
+
+// cancellationNotification tells a customer their booking was cancelled, with any fee and where
+// the refund goes
+func cancellationNotification(booking models.Booking) models.Notification {
+	message := "Your booking has been cancelled successfully"
+	if booking.Cancellation != nil && booking.Cancellation.Fee.IsPositive() {
+		message += fmt.Sprintf(". A cancellation fee of %s applies and %s will be refunded", booking.Cancellation.Fee, booking.Cancellation.RefundAmount)
+	}
+	if booking.Cancellation != nil && booking.Cancellation.RefundTo == models.RefundToWallet {
+		message += ". The refund has been added to your wallet"
+	}
+	return models.Notification{
+		UserID:  booking.UserID,
+		Title:   "Booking Cancelled",
+		Message: message,
+		Type:    "booking",
+	}
+}
+
+// RescheduleBooking moves a pending or confirmed booking to another slot, keeping its place
+// until the new slot is secured
+func RescheduleBooking(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	bookingID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking ID"})
+		return
+	}
+
+	userID := getUserIDFromContext(c)
+	if userID.IsZero() {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	var req models.RescheduleBookingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	var booking models.Booking
-	collection := db.GetMongoDB().Collection("bookings")
-	err = collection.FindOne(context.Background(), bson.M{"_id": bookingID, "user_id": userID}).Decode(&booking)
+	err = mongoDB.Collection("bookings").FindOne(context.Background(), bson.M{"_id": bookingID, "user_id": userID}).Decode(&booking)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
@@ -282,56 +439,237 @@ func CancelBooking(c *gin.Context) {
 		return
 	}
 
-	// Check if booking can be cancelled
-	if booking.Status == "cancelled" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Booking is already cancelled"})
+	if booking.Status != models.BookingStatusPending && booking.Status != models.BookingStatusConfirmed {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":  "Only pending or confirmed bookings can be rescheduled",
+			"status": booking.Status,
+		})
 		return
 	}
 
-	if booking.Status == "completed" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot cancel completed booking"})
+	cfg := config.Load()
+	if cfg.RescheduleMaxCount > 0 && booking.RescheduleCount >= cfg.RescheduleMaxCount {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":            "This booking has been rescheduled the maximum number of times",
+			"reschedule_count": booking.RescheduleCount,
+		})
+		return
+	}
+	cutoff := time.Duration(cfg.RescheduleCutoffHours) * time.Hour
+	if time.Until(booking.ScheduledAt) < cutoff {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": fmt.Sprintf("Bookings can only be rescheduled up to %d hours before the booked time", cfg.RescheduleCutoffHours),
+		})
 		return
 	}
 
-	// Update booking status to cancelled
-	_, err = collection.UpdateOne(context.Background(), bson.M{"_id": bookingID}, bson.M{
-		"$set": bson.M{
-			"status":     "cancelled",
-			"updated_at": time.Now(),
-		},
+	var service models.Service
+	if err := mongoDB.Collection("services").FindOne(context.Background(), bson.M{"_id": booking.ServiceID}).Decode(&service); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch service"})
+		return
+	}
+
+	// The new time is local to the timezone the booking was made in
+	timezone := booking.Timezone
+	if timezone == "" {
+		if timezone, err = localtime.Resolve(context.Background(), service, ""); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+	}
+	moved := booking
+	if err := setBookingTime(&moved, req.ScheduledDate, req.ScheduledTime, timezone); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	previous := localBookingTime(booking)
+	next := localBookingTime(moved)
+	message := "Booking rescheduled from " + previous + " to " + next
+	if req.Reason != "" {
+		message += ": " + req.Reason
+	}
+	actor := bookingActor{role: actorCustomer, id: userID}
+	original := booking
+
+	err = db.WithTransaction(context.Background(), func(ctx context.Context) error {
+		// The new slot is claimed before the old one is given back, so a failed reschedule
+		// leaves the original booking untouched. Slots the booking keeps are not claimed twice.
+		slots, err := availability.Claim(ctx, service, bookingDay(moved), moved.LocalTime(), moved.Duration, original.CapacitySlots)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		err = mongoDB.Collection("bookings").FindOneAndUpdate(ctx,
+			bson.M{"_id": original.ID, "status": original.Status, "reschedule_count": original.RescheduleCount},
+			bson.M{
+				"$set": bson.M{
+					"scheduled_at":   moved.ScheduledAt,
+					"timezone":       moved.Timezone,
+					"capacity_slots": slots,
+					"reminders_sent": skippedReminders(moved.ScheduledAt), // reminders start over for the new time
+					"updated_at":     now,
+				},
+				"$inc": bson.M{"reschedule_count": 1, "event_sequence": 1},
+			},
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&booking)
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				return errBookingStatusConflict
+			}
+			return err
+		}
+
+		if err := releaseSlots(ctx, droppedSlots(original.CapacitySlots, slots)); err != nil {
+			return err
+		}
+
+		if err := keepTechnician(ctx, booking, staffedService(booking, service)); err != nil {
+			return err
+		}
+
+		if _, err := mongoDB.Collection("booking_statuses").InsertOne(ctx, models.BookingStatus{
+			BookingID: booking.ID,
+			Status:    booking.Status,
+			Message:   message,
+			UpdatedBy: actor.String(),
+			CreatedAt: now,
+		}); err != nil {
+			return err
+		}
+
+		if err := outbox.EnqueueNotification(ctx, models.Notification{
+			UserID:  userID,
+			Title:   "Booking Rescheduled",
+			Message: "Your booking for " + service.Name + " has been moved to " + next,
+			Type:    "booking",
+		}); err != nil {
+			return err
+		}
+
+		return notifyVendor(ctx, service.VendorID, models.Notification{
+			Title:   "Booking Rescheduled",
+			Message: "A booking for " + service.Name + " was moved from " + previous + " to " + next,
+			Type:    "booking",
+		})
 	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel booking"})
+		if respondSlotError(c, err) {
+			return
+		}
+		respondBookingTransitionError(c, &original, original.Status, err)
 		return
 	}
+	outbox.Wake()
 
-	// Create cancellation status
-	status := models.BookingStatus{
-		BookingID: bookingID,
-		Status:    "cancelled",
-		Message:   "Booking cancelled by user",
-		UpdatedBy: "user",
-		CreatedAt: time.Now(),
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Booking rescheduled successfully",
+		"booking": booking,
+	})
+}
+
+// BackfillBookingTimes gives bookings made by older versions, which only stored a local date and
+// time, the instant they start at (admin only). Those dates and times were in UTC. The stored
+// date and time are then dropped from every booking, as they are derived from scheduled_at.
+func BackfillBookingTimes(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
 	}
-	statusCollection := db.GetMongoDB().Collection("booking_statuses")
-	statusCollection.InsertOne(context.Background(), status)
-
-	// Send notification
-	notification := models.Notification{
-		UserID:    userID,
-		Title:     "Booking Cancelled",
-		Message:   "Your booking has been cancelled successfully",
-		Type:      "booking",
-		CreatedAt: time.Now(),
+
+	legacyStart := bson.M{"$dateFromString": bson.M{
+		"dateString": bson.M{"$concat": bson.A{
+			bson.M{"$dateToString": bson.M{"format": "%Y-%m-%d", "date": "$scheduled_date"}},
+			" ",
+			bson.M{"$ifNull": bson.A{"$scheduled_time", "00:00"}},
+		}},
+		"format": "%Y-%m-%d %H:%M",
+		// Times that are not HH:MM start at the beginning of the booked day
+		"onError": "$scheduled_date",
+		"onNull":  "$scheduled_date",
+	}}
+	result, err := mongoDB.Collection("bookings").UpdateMany(context.Background(),
+		bson.M{"$or": bson.A{
+			bson.M{"scheduled_date": bson.M{"$exists": true}},
+			bson.M{"scheduled_time": bson.M{"$exists": true}},
+		}},
+		bson.A{
+			bson.M{"$set": bson.M{"scheduled_at": bson.M{"$cond": bson.A{
+				bson.M{"$gt": bson.A{"$scheduled_at", time.Time{}}}, "$scheduled_at", legacyStart,
+			}}}},
+			bson.M{"$unset": bson.A{"scheduled_date", "scheduled_time"}},
+		},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update bookings"})
+		return
 	}
-	notificationCollection := db.GetMongoDB().Collection("notifications")
-	notificationCollection.InsertOne(context.Background(), notification)
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Booking cancelled successfully",
+		"message": "Booking time backfill completed",
+		"updated": result.ModifiedCount,
 	})
 }
 
+// setBookingTime schedules a booking at a local date (YYYY-MM-DD) and time (HH:MM) in timezone,
+// storing the instant together with the timezone. It fails when the time has already passed in
+// that timezone.
+func setBookingTime(booking *models.Booking, date, clock, timezone string) error {
+	loc := localtime.Location(timezone)
+	at, err := localtime.At(date, clock, loc)
+	if err != nil {
+		return err
+	}
+	if !at.After(time.Now()) {
+		return fmt.Errorf("cannot book %s %s, that time has already passed in %s", date, clock, loc)
+	}
+
+	booking.ScheduledAt = at
+	booking.Timezone = loc.String()
+	return nil
+}
+
+// bookingDay returns the start of a booking's local day in its timezone, the form the slot
+// functions of the availability package take dates in
+func bookingDay(booking models.Booking) time.Time {
+	return localtime.Midnight(booking.LocalDate(), localtime.Location(booking.Timezone))
+}
+
+// localBookingTime formats when a booking starts in its own timezone, e.g. "2025-07-20 14:00 IST"
+func localBookingTime(booking models.Booking) string {
+	return booking.ScheduledAt.In(localtime.Location(booking.Timezone)).Format(localtime.DateFormat + " 15:04 MST")
+}
+
+// notifyVendor queues a notification for the owner of a vendor. It does nothing when there is no
+// vendor or the vendor has no owner account.
+func notifyVendor(ctx context.Context, vendorID primitive.ObjectID, notification models.Notification) error {
+	if vendorID.IsZero() {
+		return nil
+	}
+
+	var vendor models.Vendor
+	err := db.GetMongoDB().Collection("vendors").FindOne(ctx, bson.M{"_id": vendorID}).Decode(&vendor)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		return err
+	}
+	if vendor.OwnerID.IsZero() {
+		return nil
+	}
+
+	notification.UserID = vendor.OwnerID
+	return outbox.EnqueueNotification(ctx, notification)
+}
+
 // getUserIDFromContext extracts user ID from context (placeholder - implement proper auth)
 func getUserIDFromContext(c *gin.Context) primitive.ObjectID {
 	// This is a placeholder - implement proper authentication middleware
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/code-harsh006/food-delivery/internal/models"
+	"github.com/code-harsh006/food-delivery/internal/promotion"
+	"github.com/code-harsh006/food-delivery/pkg/db"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Roles that can move a booking between statuses
+const (
+	actorCustomer   = "customer"
+	actorAdmin      = "admin"
+	actorVendor     = "vendor"
+	actorTechnician = "technician"
+	actorSystem     = "system"
+)
+
+// bookingTransitions lists, for every status, the statuses it may move to and who may make the move.
+// Completed, cancelled and no_show are final.
+var bookingTransitions = map[string]map[string][]string{
+	models.BookingStatusPending: {
+		models.BookingStatusConfirmed: {actorAdmin, actorSystem},
+		models.BookingStatusCancelled: {actorCustomer, actorAdmin, actorSystem},
+	},
+	models.BookingStatusConfirmed: {
+		models.BookingStatusInProgress: {actorAdmin, actorTechnician},
+		models.BookingStatusCancelled:  {actorCustomer, actorAdmin},
+		models.BookingStatusNoShow:     {actorAdmin, actorSystem},
+	},
+	models.BookingStatusInProgress: {
+		models.BookingStatusCompleted: {actorAdmin, actorTechnician},
+	},
+}
+
+var (
+	errBookingNotFound       = errors.New("booking not found")
+	errInvalidTransition     = errors.New("invalid booking status transition")
+	errTransitionNotAllowed  = errors.New("not allowed to make this booking status change")
+	errBookingStatusConflict = errors.New("booking status changed concurrently")
+)
+
+// bookingActor identifies who changes a booking's status
+type bookingActor struct {
+	role string
+	id   primitive.ObjectID
+}
+
+// String formats the actor as stored in BookingStatus.UpdatedBy, e.g. "customer:64f1..."
+func (a bookingActor) String() string {
+	if a.id.IsZero() {
+		return a.role
+	}
+	return a.role + ":" + a.id.Hex()
+}
+
+// isBookingStatus reports whether status is one of the known booking statuses
+func isBookingStatus(status string) bool {
+	switch status {
+	case models.BookingStatusPending, models.BookingStatusConfirmed, models.BookingStatusInProgress,
+		models.BookingStatusCompleted, models.BookingStatusCancelled, models.BookingStatusNoShow:
+		return true
+	}
+	return false
+}
+
+// checkBookingTransition validates that actor may move a booking from one status to another
+func checkBookingTransition(from, to string, actor bookingActor) error {
+	roles, ok := bookingTransitions[from][to]
+	if !ok {
+		return errInvalidTransition
+	}
+	for _, role := range roles {
+		if role == actor.role {
+			return nil
+		}
+	}
+	return errTransitionNotAllowed
+}
+
+// transitionBooking moves a booking to a new status and records the change in its history.
+// The update only applies if the status is still the one that was validated, so two concurrent
+// changes cannot both succeed. filter narrows which booking may be changed, e.g. by owner.
+// Run it inside db.WithTransaction so the status and its history entry are written together.
+func transitionBooking(ctx context.Context, filter bson.M, to string, actor bookingActor, message string) (*models.Booking, error) {
+	return transitionBookingRefundingTo(ctx, filter, to, actor, message, models.RefundToOriginal)
+}
+
+// transitionBookingRefundingTo is transitionBooking with a choice of where the refund of a
+// cancellation goes: back to how the booking was paid, or into the customer's wallet
+func transitionBookingRefundingTo(ctx context.Context, filter bson.M, to string, actor bookingActor, message, refundTo string) (*models.Booking, error) {
+	collection := db.GetMongoDB().Collection("bookings")
+
+	var booking models.Booking
+	if err := collection.FindOne(ctx, filter).Decode(&booking); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errBookingNotFound
+		}
+		return nil, err
+	}
+
+	if err := checkBookingTransition(booking.Status, to, actor); err != nil {
+		return &booking, err
+	}
+
+	now := time.Now()
+	update := bson.M{"status": to, "updated_at": now}
+
+	// A cancellation records the fee and refund under the policy in force and updates the payment
+	if to == models.BookingStatusCancelled {
+		cancellation, err := bookingCancellation(ctx, booking, actor, now)
+		if err != nil {
+			return nil, err
+		}
+		if refundsToWallet(booking, cancellation, refundTo) {
+			cancellation.RefundTo = models.RefundToWallet
+		}
+		update["cancellation"] = cancellation
+		update["payment_status"] = refundedPaymentStatus(booking, cancellation)
+	}
+
+	previous := booking
+	err := collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": booking.ID, "status": booking.Status},
+		bson.M{"$set": update, "$inc": bson.M{"event_sequence": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&booking)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errBookingStatusConflict
+		}
+		return nil, err
+	}
+
+	status := models.BookingStatus{
+		BookingID: booking.ID,
+		Status:    to,
+		Message:   message,
+		UpdatedBy: actor.String(),
+		CreatedAt: now,
+	}
+	if _, err := db.GetMongoDB().Collection("booking_statuses").InsertOne(ctx, status); err != nil {
+		return nil, err
+	}
+
+	// Money held or taken for the booking is captured, released or refunded
+	if action := paymentSettlement(previous, booking); action != nil {
+		if err := settleBookingPayment(ctx, booking, *action); err != nil {
+			return nil, err
+		}
+	}
+
+	// A cancelled booking frees its slot for the waitlist or other customers, and its promotion
+	// code so it can be used again
+	if to == models.BookingStatusCancelled {
+		if err := releaseSlots(ctx, booking.CapacitySlots); err != nil {
+			return nil, err
+		}
+		if err := promotion.Release(ctx, promotion.KindBooking, booking.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	return &booking, nil
+}
+
+// respondBookingTransitionError writes the HTTP response for a failed status change
+func respondBookingTransitionError(c *gin.Context, booking *models.Booking, to string, err error) {
+	switch err {
+	case errBookingNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+	case errInvalidTransition:
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Booking cannot move from " + booking.Status + " to " + to,
+			"status":  booking.Status,
+			"allowed": allowedBookingStatuses(booking.Status),
+		})
+	case errTransitionNotAllowed:
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not allowed to move this booking to " + to})
+	case errBookingStatusConflict:
+		c.JSON(http.StatusConflict, gin.H{"error": "Booking was updated by someone else, please retry"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update booking status"})
+	}
+}
+
+// allowedBookingStatuses lists the statuses a booking can move to next
+func allowedBookingStatuses(from string) []string {
+	allowed := []string{}
+	for to := range bookingTransitions[from] {
+		allowed = append(allowed, to)
+	}
+	sort.Strings(allowed)
+	return allowed
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/code-harsh006/food-delivery/internal/models"
+)
+
+func TestCheckBookingTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		role     string
+		want     error
+	}{
+		// Pending bookings are confirmed by payment or an admin, and can be cancelled by anyone
+		{models.BookingStatusPending, models.BookingStatusConfirmed, actorAdmin, nil},
+		{models.BookingStatusPending, models.BookingStatusConfirmed, actorSystem, nil},
+		{models.BookingStatusPending, models.BookingStatusConfirmed, actorCustomer, errTransitionNotAllowed},
+		{models.BookingStatusPending, models.BookingStatusConfirmed, actorTechnician, errTransitionNotAllowed},
+		{models.BookingStatusPending, models.BookingStatusCancelled, actorCustomer, nil},
+		{models.BookingStatusPending, models.BookingStatusCancelled, actorAdmin, nil},
+		{models.BookingStatusPending, models.BookingStatusCancelled, actorSystem, nil},
+		{models.BookingStatusPending, models.BookingStatusCancelled, actorVendor, errTransitionNotAllowed},
+		{models.BookingStatusPending, models.BookingStatusInProgress, actorAdmin, errInvalidTransition},
+		{models.BookingStatusPending, models.BookingStatusCompleted, actorAdmin, errInvalidTransition},
+		{models.BookingStatusPending, models.BookingStatusNoShow, actorSystem, errInvalidTransition},
+
+		// Confirmed bookings are started by their technician, or cancelled, or missed
+		{models.BookingStatusConfirmed, models.BookingStatusInProgress, actorTechnician, nil},
+		{models.BookingStatusConfirmed, models.BookingStatusInProgress, actorAdmin, nil},
+		{models.BookingStatusConfirmed, models.BookingStatusInProgress, actorCustomer, errTransitionNotAllowed},
+		{models.BookingStatusConfirmed, models.BookingStatusCancelled, actorCustomer, nil},
+		{models.BookingStatusConfirmed, models.BookingStatusCancelled, actorAdmin, nil},
+		{models.BookingStatusConfirmed, models.BookingStatusCancelled, actorSystem, errTransitionNotAllowed},
+		{models.BookingStatusConfirmed, models.BookingStatusCancelled, actorTechnician, errTransitionNotAllowed},
+		{models.BookingStatusConfirmed, models.BookingStatusNoShow, actorAdmin, nil},
+		{models.BookingStatusConfirmed, models.BookingStatusNoShow, actorSystem, nil},
+		{models.BookingStatusConfirmed, models.BookingStatusNoShow, actorCustomer, errTransitionNotAllowed},
+		{models.BookingStatusConfirmed, models.BookingStatusPending, actorAdmin, errInvalidTransition},
+		{models.BookingStatusConfirmed, models.BookingStatusCompleted, actorTechnician, errInvalidTransition},
+
+		// Jobs in progress can only be completed
+		{models.BookingStatusInProgress, models.BookingStatusCompleted, actorTechnician, nil},
+		{models.BookingStatusInProgress, models.BookingStatusCompleted, actorAdmin, nil},
+		{models.BookingStatusInProgress, models.BookingStatusCompleted, actorCustomer, errTransitionNotAllowed},
+		{models.BookingStatusInProgress, models.BookingStatusCancelled, actorAdmin, errInvalidTransition},
+		{models.BookingStatusInProgress, models.BookingStatusCancelled, actorCustomer, errInvalidTransition},
+
+		// Completed, cancelled and no_show are final
+		{models.BookingStatusCompleted, models.BookingStatusCancelled, actorAdmin, errInvalidTransition},
+		{models.BookingStatusCompleted, models.BookingStatusInProgress, actorTechnician, errInvalidTransition},
+		{models.BookingStatusCancelled, models.BookingStatusPending, actorAdmin, errInvalidTransition},
+		{models.BookingStatusCancelled, models.BookingStatusConfirmed, actorSystem, errInvalidTransition},
+		{models.BookingStatusNoShow, models.BookingStatusConfirmed, actorAdmin, errInvalidTransition},
+		{models.BookingStatusNoShow, models.BookingStatusCancelled, actorCustomer, errInvalidTransition},
+
+		// Unknown statuses and roles
+		{"unknown", models.BookingStatusCancelled, actorAdmin, errInvalidTransition},
+		{models.BookingStatusPending, "unknown", actorAdmin, errInvalidTransition},
+		{models.BookingStatusPending, models.BookingStatusCancelled, "", errTransitionNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.from+" to "+tt.to+" by "+tt.role, func(t *testing.T) {
+			if got := checkBookingTransition(tt.from, tt.to, bookingActor{role: tt.role}); got != tt.want {
+				t.Errorf("checkBookingTransition(%q, %q, %q) = %v, want %v", tt.from, tt.to, tt.role, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,319 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/code-harsh006/food-delivery/internal/ical"
+	"github.com/code-harsh006/food-delivery/internal/models"
+	"github.com/code-harsh006/food-delivery/internal/staffing"
+	"github.com/code-harsh006/food-delivery/pkg/db"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// calendarFeedLimit caps the number of events in one feed
+const calendarFeedLimit = 500
+
+// CreateCalendarFeed gives the caller a secret calendar feed URL, replacing any earlier one. The
+// URL is only shown once; only a hash of its token is stored.
+func CreateCalendarFeed(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	userID := getUserIDFromContext(c)
+	if userID.IsZero() {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	token := generateSessionToken()
+	result, err := mongoDB.Collection("users").UpdateOne(context.Background(), bson.M{"_id": userID}, bson.M{
+		"$set": bson.M{"calendar_token_hash": calendarTokenHash(token), "updated_at": time.Now()},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create calendar feed"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Calendar feed created successfully. Keep the URL secret; creating a new one disables it",
+		"url":     calendarFeedURL(c, token),
+	})
+}
+
+// DeleteCalendarFeed disables the caller's calendar feed URL
+func DeleteCalendarFeed(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	userID := getUserIDFromContext(c)
+	if userID.IsZero() {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	_, err := mongoDB.Collection("users").UpdateOne(context.Background(), bson.M{"_id": userID}, bson.M{
+		"$unset": bson.M{"calendar_token_hash": ""},
+		"$set":   bson.M{"updated_at": time.Now()},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete calendar feed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Calendar feed deleted successfully"})
+}
+
+// GetCalendarFeed serves the iCalendar feed of the user whose secret token is in the URL: their
+// bookings and, for technicians, the jobs assigned to them, from yesterday on. Cancelled
+// bookings stay in the feed as cancelled events so subscribed calendars remove them.
+func GetCalendarFeed(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+	if token == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Calendar feed not found"})
+		return
+	}
+	var user models.User
+	err := mongoDB.Collection("users").FindOne(context.Background(), bson.M{"calendar_token_hash": calendarTokenHash(token)}).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Calendar feed not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	ids, err := mongoDB.Collection("technicians").Distinct(context.Background(), "_id", bson.M{"user_id": user.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	technicianIDs := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		if technicianID, ok := id.(primitive.ObjectID); ok {
+			technicianIDs = append(technicianIDs, technicianID)
+		}
+	}
+
+	owners := []bson.M{{"user_id": user.ID}}
+	if len(technicianIDs) > 0 {
+		owners = append(owners, bson.M{"technician_id": bson.M{"$in": technicianIDs}})
+	}
+	// Two days back keeps the whole of yesterday in every timezone
+	from := time.Now().AddDate(0, 0, -2)
+	cursor, err := mongoDB.Collection("bookings").Find(context.Background(),
+		bson.M{"$or": owners, "scheduled_at": bson.M{"$gte": from}},
+		options.Find().SetSort(bson.D{{Key: "scheduled_at", Value: 1}}).SetLimit(calendarFeedLimit))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bookings"})
+		return
+	}
+	defer cursor.Close(context.Background())
+
+	var bookings []models.Booking
+	if err := cursor.All(context.Background(), &bookings); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode bookings"})
+		return
+	}
+
+	services, err := bookingServices(context.Background(), bookings)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch services"})
+		return
+	}
+
+	calendar := ical.Calendar{Name: "Bookings"}
+	for _, booking := range bookings {
+		calendar.Events = append(calendar.Events, bookingEvent(booking, services[booking.ServiceID], booking.UserID != user.ID))
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", calendar.Encode())
+}
+
+// GetBookingCalendar downloads a single booking as an .ics file, for its customer or its
+// technician. It carries the same UID as the feed event, so importing it updates that event.
+func GetBookingCalendar(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	bookingID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking ID"})
+		return
+	}
+
+	userID := getUserIDFromContext(c)
+	if userID.IsZero() {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	var booking models.Booking
+	err = mongoDB.Collection("bookings").FindOne(context.Background(), bson.M{"_id": bookingID}).Decode(&booking)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	asTechnician := false
+	if booking.UserID != userID {
+		assigned := int64(0)
+		if !booking.TechnicianID.IsZero() {
+			assigned, err = mongoDB.Collection("technicians").CountDocuments(context.Background(), bson.M{"_id": booking.TechnicianID, "user_id": userID})
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+				return
+			}
+		}
+		if assigned == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+			return
+		}
+		asTechnician = true
+	}
+
+	var service models.Service
+	if err := mongoDB.Collection("services").FindOne(context.Background(), bson.M{"_id": booking.ServiceID}).Decode(&service); err != nil && err != mongo.ErrNoDocuments {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	calendar := ical.Calendar{Events: []ical.Event{bookingEvent(booking, service, asTechnician)}}
+	c.Header("Content-Disposition", `attachment; filename="booking-`+booking.ID.Hex()+`.ics"`)
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", calendar.Encode())
+}
+
+// bookingEvent turns a booking into a calendar event, worded for its customer or its technician.
+// The UID never changes; the sequence goes up with every change to what the event shows: a
+// status change, a reschedule or another technician. Before event_sequence was kept, the sequence
+// only counted reschedules and the cancellation, so those still count to keep it from going down.
+func bookingEvent(booking models.Booking, service models.Service, forTechnician bool) ical.Event {
+	start, end := staffing.Window(booking, service)
+
+	sequence := booking.EventSequence + booking.RescheduleCount
+	status := ical.StatusConfirmed
+	switch booking.Status {
+	case models.BookingStatusPending:
+		status = ical.StatusTentative
+	case models.BookingStatusCancelled:
+		status = ical.StatusCancelled
+		sequence++
+	}
+
+	name := bookingName(booking, service)
+	if name == "" {
+		name = "Booking"
+	}
+	summary := name
+	if forTechnician {
+		summary = "Job: " + name
+	}
+
+	details := []string{"Booking " + booking.ID.Hex(), "Status: " + strings.ReplaceAll(booking.Status, "_", " ")}
+	if booking.TechnicianName != "" && !forTechnician {
+		details = append(details, "Technician: "+booking.TechnicianName)
+	}
+	if booking.SpecialRequests != "" {
+		details = append(details, "Special requests: "+booking.SpecialRequests)
+	}
+
+	return ical.Event{
+		UID:          "booking-" + booking.ID.Hex() + "@food-delivery",
+		Sequence:     sequence,
+		Start:        start,
+		End:          end,
+		Summary:      summary,
+		Description:  strings.Join(details, "\n"),
+		Status:       status,
+		LastModified: booking.UpdatedAt,
+	}
+}
+
+// bookingServices loads the services of a set of bookings by ID
+func bookingServices(ctx context.Context, bookings []models.Booking) (map[primitive.ObjectID]models.Service, error) {
+	services := make(map[primitive.ObjectID]models.Service)
+	if len(bookings) == 0 {
+		return services, nil
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(bookings))
+	for _, booking := range bookings {
+		ids = append(ids, booking.ServiceID)
+	}
+	cursor, err := db.GetMongoDB().Collection("services").Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	var found []models.Service
+	if err := cursor.All(ctx, &found); err != nil {
+		return nil, err
+	}
+	for _, service := range found {
+		services[service.ID] = service
+	}
+	return services, nil
+}
+
+// calendarTokenHash is the form a calendar feed token is stored and looked up in
+func calendarTokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// calendarFeedURL builds the absolute URL of a calendar feed from the request's host
+func calendarFeedURL(c *gin.Context, token string) string {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host + "/api/mongo/v1/calendar/" + token + ".ics"
+}
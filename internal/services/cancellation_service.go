@@ -0,0 +1,429 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/code-harsh006/food-delivery/internal/models"
+	"github.com/code-harsh006/food-delivery/pkg/db"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PreviewBookingCancellation shows the fee and refund the customer would get by cancelling now
+func PreviewBookingCancellation(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	bookingID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking ID"})
+		return
+	}
+
+	userID := getUserIDFromContext(c)
+	if userID.IsZero() {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	var booking models.Booking
+	err = mongoDB.Collection("bookings").FindOne(context.Background(), bson.M{"_id": bookingID, "user_id": userID}).Decode(&booking)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	actor := bookingActor{role: actorCustomer, id: userID}
+	if err := checkBookingTransition(booking.Status, models.BookingStatusCancelled, actor); err != nil {
+		respondBookingTransitionError(c, &booking, models.BookingStatusCancelled, err)
+		return
+	}
+
+	cancellation, err := bookingCancellation(context.Background(), booking, actor, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate cancellation fee"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"booking_id":   booking.ID,
+		"total_amount": booking.TotalAmount,
+		"cancellation": cancellation,
+	})
+}
+
+// CreateCancellationPolicy adds a tiered refund policy for a service or a vendor (admin only)
+func CreateCancellationPolicy(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	var req models.CreateCancellationPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	policy := models.CancellationPolicy{
+		Name:      req.Name,
+		IsActive:  req.IsActive == nil || *req.IsActive,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	if (req.ServiceID == "") == (req.VendorID == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Set exactly one of service_id or vendor_id"})
+		return
+	}
+	if req.ServiceID != "" {
+		serviceID, err := primitive.ObjectIDFromHex(req.ServiceID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service ID"})
+			return
+		}
+		policy.ServiceID = serviceID
+	} else {
+		vendorID, err := primitive.ObjectIDFromHex(req.VendorID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid vendor ID"})
+			return
+		}
+		policy.VendorID = vendorID
+	}
+
+	tiers, err := normalizeCancellationTiers(req.Tiers)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	policy.Tiers = tiers
+
+	// Only one active policy applies to a service or vendor, so activating a policy retires the old one
+	collection := mongoDB.Collection("cancellation_policies")
+	if policy.IsActive {
+		if _, err := collection.UpdateMany(context.Background(),
+			bson.M{"service_id": policyOwnerValue(policy.ServiceID), "vendor_id": policyOwnerValue(policy.VendorID), "is_active": true},
+			bson.M{"$set": bson.M{"is_active": false, "updated_at": time.Now()}},
+		); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update existing policies"})
+			return
+		}
+	}
+
+	result, err := collection.InsertOne(context.Background(), policy)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create cancellation policy"})
+		return
+	}
+
+	policy.ID = result.InsertedID.(primitive.ObjectID)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":             "Cancellation policy created successfully",
+		"cancellation_policy": policy,
+	})
+}
+
+// GetCancellationPolicies lists cancellation policies, optionally for one service or vendor (admin only)
+func GetCancellationPolicies(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	filter := bson.M{}
+	if serviceID, err := primitive.ObjectIDFromHex(c.Query("service_id")); err == nil {
+		filter["service_id"] = serviceID
+	}
+	if vendorID, err := primitive.ObjectIDFromHex(c.Query("vendor_id")); err == nil {
+		filter["vendor_id"] = vendorID
+	}
+
+	cursor, err := mongoDB.Collection("cancellation_policies").Find(context.Background(), filter,
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch cancellation policies"})
+		return
+	}
+	defer cursor.Close(context.Background())
+
+	policies := []models.CancellationPolicy{}
+	if err = cursor.All(context.Background(), &policies); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode cancellation policies"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"cancellation_policies": policies,
+		"total":                 len(policies),
+	})
+}
+
+// UpdateCancellationPolicy changes a policy's name, tiers or active flag (admin only).
+// Bookings already cancelled keep the fee they were charged.
+func UpdateCancellationPolicy(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	policyID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cancellation policy ID"})
+		return
+	}
+
+	var req models.UpdateCancellationPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	collection := mongoDB.Collection("cancellation_policies")
+	var policy models.CancellationPolicy
+	if err := collection.FindOne(context.Background(), bson.M{"_id": policyID}).Decode(&policy); err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Cancellation policy not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	updateData := bson.M{"updated_at": time.Now()}
+	if req.Name != nil {
+		updateData["name"] = *req.Name
+	}
+	if req.Tiers != nil {
+		tiers, err := normalizeCancellationTiers(req.Tiers)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		updateData["tiers"] = tiers
+	}
+	if req.IsActive != nil {
+		updateData["is_active"] = *req.IsActive
+		if *req.IsActive && !policy.IsActive {
+			if _, err := collection.UpdateMany(context.Background(),
+				bson.M{"service_id": policyOwnerValue(policy.ServiceID), "vendor_id": policyOwnerValue(policy.VendorID), "is_active": true},
+				bson.M{"$set": bson.M{"is_active": false, "updated_at": time.Now()}},
+			); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update existing policies"})
+				return
+			}
+		}
+	}
+
+	err = collection.FindOneAndUpdate(context.Background(),
+		bson.M{"_id": policyID},
+		bson.M{"$set": updateData},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&policy)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update cancellation policy"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":             "Cancellation policy updated successfully",
+		"cancellation_policy": policy,
+	})
+}
+
+// DeleteCancellationPolicy removes a cancellation policy (admin only)
+func DeleteCancellationPolicy(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	policyID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cancellation policy ID"})
+		return
+	}
+
+	result, err := mongoDB.Collection("cancellation_policies").DeleteOne(context.Background(), bson.M{"_id": policyID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete cancellation policy"})
+		return
+	}
+	if result.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cancellation policy not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Cancellation policy deleted successfully"})
+}
+
+// bookingCancellation works out the fee and refund for cancelling a booking at the given time.
+// Customers are charged according to the service's policy, or its vendor's when the service has
+// none; without a policy, and when an admin or the system cancels, the booking is refunded in full.
+func bookingCancellation(ctx context.Context, booking models.Booking, actor bookingActor, now time.Time) (*models.BookingCancellation, error) {
+	hoursBefore := booking.ScheduledAt.Sub(now).Hours()
+	cancellation := &models.BookingCancellation{
+		Policy:        "full refund",
+		HoursBefore:   math.Round(hoursBefore*100) / 100,
+		RefundPercent: 100,
+		CancelledBy:   actor.String(),
+		CancelledAt:   now,
+	}
+
+	if actor.role == actorCustomer {
+		policy, err := findCancellationPolicy(ctx, booking.ServiceID)
+		if err != nil {
+			return nil, err
+		}
+		if policy != nil {
+			cancellation.PolicyID = policy.ID
+			cancellation.Policy = policy.Name
+			cancellation.RefundPercent = 0
+			for _, tier := range policy.Tiers {
+				if hoursBefore >= tier.HoursBefore {
+					cancellation.RefundPercent = tier.RefundPercent
+					break
+				}
+			}
+		}
+	}
+
+	cancellation.RefundAmount = booking.TotalAmount.Percent(cancellation.RefundPercent)
+	cancellation.Fee = booking.TotalAmount.Sub(cancellation.RefundAmount)
+	return cancellation, nil
+}
+
+// refundedPaymentStatus returns a booking's payment status once a cancellation refund is recorded.
+// Unpaid bookings have nothing to refund and keep their status.
+func refundedPaymentStatus(booking models.Booking, cancellation *models.BookingCancellation) string {
+	// Only the fee is captured from an authorised payment; the rest is released
+	if booking.PaymentStatus == models.PaymentStatusAuthorized {
+		switch {
+		case !cancellation.Fee.IsPositive():
+			return models.PaymentStatusRefunded
+		case !cancellation.RefundAmount.IsPositive():
+			return models.PaymentStatusPaid
+		default:
+			return models.PaymentStatusPartiallyRefunded
+		}
+	}
+	if booking.PaymentStatus != models.PaymentStatusPaid {
+		return booking.PaymentStatus
+	}
+	switch {
+	case !cancellation.RefundAmount.IsPositive():
+		return booking.PaymentStatus
+	case cancellation.RefundAmount.Cmp(booking.TotalAmount) < 0:
+		return models.PaymentStatusPartiallyRefunded
+	default:
+		return models.PaymentStatusRefunded
+	}
+}
+
+// findCancellationPolicy returns the active policy of a service, falling back to its vendor's
+func findCancellationPolicy(ctx context.Context, serviceID primitive.ObjectID) (*models.CancellationPolicy, error) {
+	mongoDB := db.GetMongoDB()
+	collection := mongoDB.Collection("cancellation_policies")
+
+	var policy models.CancellationPolicy
+	err := collection.FindOne(ctx, bson.M{"service_id": serviceID, "is_active": true}).Decode(&policy)
+	if err == nil {
+		return &policy, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+
+	var service models.Service
+	if err := mongoDB.Collection("services").FindOne(ctx, bson.M{"_id": serviceID}).Decode(&service); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if service.VendorID.IsZero() {
+		return nil, nil
+	}
+
+	err = collection.FindOne(ctx, bson.M{
+		"vendor_id":  service.VendorID,
+		"service_id": bson.M{"$exists": false},
+		"is_active":  true,
+	}).Decode(&policy)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// normalizeCancellationTiers validates tiers and sorts them with the longest notice first
+func normalizeCancellationTiers(tiers []models.CancellationTier) ([]models.CancellationTier, error) {
+	seen := make(map[float64]bool)
+	for _, tier := range tiers {
+		if tier.HoursBefore < 0 {
+			return nil, errors.New("hours_before must not be negative")
+		}
+		if tier.RefundPercent < 0 || tier.RefundPercent > 100 {
+			return nil, errors.New("refund_percent must be between 0 and 100")
+		}
+		if seen[tier.HoursBefore] {
+			return nil, errors.New("each tier must have a different hours_before")
+		}
+		seen[tier.HoursBefore] = true
+	}
+
+	sorted := append([]models.CancellationTier(nil), tiers...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].HoursBefore > sorted[j].HoursBefore })
+	return sorted, nil
+}
+
+// policyOwnerValue matches a policy owner ID, or a missing one when the ID is zero
+func policyOwnerValue(id primitive.ObjectID) interface{} {
+	if id.IsZero() {
+		return bson.M{"$exists": false}
+	}
+	return id
+}
@@ -0,0 +1,865 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/code-harsh006/food-delivery/internal/models"
+	"github.com/code-harsh006/food-delivery/internal/staffing"
+	"github.com/code-harsh006/food-delivery/pkg/config"
+	"github.com/code-harsh006/food-delivery/pkg/db"
+	"github.com/code-harsh006/food-delivery/pkg/money"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	catalogTypeServices  = "services"
+	catalogTypeMenuItems = "menu_items"
+
+	catalogFormatCSV    = "csv"
+	catalogFormatNDJSON = "ndjson"
+
+	// catalogListSeparator separates list values such as dietary tags inside a CSV cell
+	catalogListSeparator = "|"
+)
+
+// nutritionColumns are the optional CSV columns mapped onto models.NutritionFacts
+var nutritionColumns = []string{"serving_size", "calories", "protein_g", "carbs_g", "fat_g", "fiber_g", "sugar_g", "sodium_mg"}
+
+// catalogImportRow is a parsed and validated import row ready to be upserted. Fields are set on
+// every upsert; defaults only when the row creates a new document.
+type catalogImportRow struct {
+	row      int
+	sku      string
+	fields   bson.M
+	defaults bson.M
+	errs     []string
+}
+
+// catalogSpec describes how one catalog type is imported and exported
+type catalogSpec struct {
+	collection string
+	columns    []string
+	parseCSV   func(values map[string]string) catalogImportRow
+	parseJSON  func(line []byte) catalogImportRow
+	export     func(cursor *mongo.Cursor) (interface{}, []string, error)
+}
+
+var catalogSpecs = map[string]catalogSpec{
+	catalogTypeServices: {
+		collection: "services",
+		columns: append([]string{"sku", "vendor_id", "name", "description", "category", "base_price", "duration", "skills", "dietary_tags", "allergens"},
+			append(nutritionColumns, "is_active")...),
+		parseCSV:  parseServiceCSV,
+		parseJSON: parseServiceJSON,
+		export:    exportService,
+	},
+	catalogTypeMenuItems: {
+		collection: "menu_items",
+		columns: append([]string{"sku", "vendor_id", "name", "description", "category", "price", "dietary_tags", "allergens"},
+			append(nutritionColumns, "is_available")...),
+		parseCSV:  parseMenuItemCSV,
+		parseJSON: parseMenuItemJSON,
+		export:    exportMenuItem,
+	},
+}
+
+// ImportCatalog bulk-upserts services or menu items from a CSV or NDJSON upload (admin only)
+func ImportCatalog(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	catalogType := c.DefaultQuery("type", catalogTypeServices)
+	spec, ok := catalogSpecs[catalogType]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type must be services or menu_items"})
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+
+	body, filename, err := readCatalogUpload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	format := detectCatalogFormat(c.Query("format"), c.ContentType(), filename)
+	if format == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or ndjson"})
+		return
+	}
+
+	var rows []catalogImportRow
+	if format == catalogFormatCSV {
+		rows, err = parseCatalogCSV(body, spec)
+	} else {
+		rows, err = parseCatalogNDJSON(body, spec)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if catalogType == catalogTypeServices {
+		if err := linkImportCategories(context.Background(), rows); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load categories"})
+			return
+		}
+	}
+
+	result := models.CatalogImportResult{
+		Type:      catalogType,
+		Format:    format,
+		DryRun:    dryRun,
+		TotalRows: len(rows),
+		Errors:    []models.CatalogImportRowError{},
+	}
+
+	// Reject SKUs that appear more than once in the same file
+	seen := make(map[string]int)
+	var valid []catalogImportRow
+	for _, row := range rows {
+		if first, dup := seen[row.sku]; dup && row.sku != "" {
+			row.errs = append(row.errs, fmt.Sprintf("duplicate sku, first seen on row %d", first))
+		} else if row.sku != "" {
+			seen[row.sku] = row.row
+		}
+
+		if len(row.errs) > 0 {
+			result.Errors = append(result.Errors, models.CatalogImportRowError{Row: row.row, SKU: row.sku, Errors: row.errs})
+			continue
+		}
+		valid = append(valid, row)
+	}
+	result.ValidRows = len(valid)
+
+	collection := mongoDB.Collection(spec.collection)
+	ctx := context.Background()
+
+	if dryRun {
+		existing, err := existingCatalogSKUs(ctx, collection, valid)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check existing SKUs"})
+			return
+		}
+		for _, row := range valid {
+			if existing[row.sku] {
+				result.Updated++
+			} else {
+				result.Created++
+			}
+		}
+		c.JSON(http.StatusOK, gin.H{"result": result})
+		return
+	}
+
+	if len(valid) > 0 {
+		now := time.Now()
+		writes := make([]mongo.WriteModel, 0, len(valid))
+		for _, row := range valid {
+			row.fields["updated_at"] = now
+			onInsert := bson.M{"created_at": now}
+			for field, value := range row.defaults {
+				onInsert[field] = value
+			}
+			writes = append(writes, mongo.NewUpdateOneModel().
+				SetFilter(bson.M{"sku": row.sku}).
+				SetUpdate(bson.M{"$set": row.fields, "$setOnInsert": onInsert}).
+				SetUpsert(true))
+		}
+
+		// Unordered, so a row that fails does not stop the rows after it
+		res, err := collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+		var failed mongo.BulkWriteException
+		if err != nil && !errors.As(err, &failed) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save catalog"})
+			return
+		}
+		for _, writeErr := range failed.WriteErrors {
+			row := valid[writeErr.Index]
+			result.Errors = append(result.Errors, models.CatalogImportRowError{
+				Row:    row.row,
+				SKU:    row.sku,
+				Errors: []string{"failed to save row: " + writeErr.Message},
+			})
+		}
+		if res != nil {
+			result.Created = int(res.UpsertedCount)
+		}
+		result.Updated = len(valid) - result.Created - len(failed.WriteErrors)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Catalog import completed",
+		"result":  result,
+	})
+}
+
+// ExportCatalog streams the current services or menu items as CSV or NDJSON (admin only)
+func ExportCatalog(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	catalogType := c.DefaultQuery("type", catalogTypeServices)
+	spec, ok := catalogSpecs[catalogType]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type must be services or menu_items"})
+		return
+	}
+
+	format := detectCatalogFormat(c.DefaultQuery("format", catalogFormatCSV), "", "")
+	if format == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or ndjson"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	cursor, err := mongoDB.Collection(spec.collection).Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "sku", Value: 1}}))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch catalog"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	contentType := "text/csv"
+	if format == catalogFormatNDJSON {
+		contentType = "application/x-ndjson"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, catalogType, format))
+	c.Status(http.StatusOK)
+
+	csvWriter := csv.NewWriter(c.Writer)
+	encoder := json.NewEncoder(c.Writer)
+	if format == catalogFormatCSV {
+		csvWriter.Write(spec.columns)
+	}
+
+	rows := 0
+	for cursor.Next(ctx) {
+		doc, record, err := spec.export(cursor)
+		if err != nil {
+			// Headers are already sent, so the best we can do is stop the stream
+			break
+		}
+
+		if format == catalogFormatCSV {
+			csvWriter.Write(record)
+		} else {
+			encoder.Encode(doc)
+		}
+
+		rows++
+		if rows%100 == 0 {
+			csvWriter.Flush()
+			c.Writer.Flush()
+		}
+	}
+
+	csvWriter.Flush()
+	c.Writer.Flush()
+}
+
+// readCatalogUpload returns the upload body from a multipart "file" field or the raw request body
+func readCatalogUpload(c *gin.Context) ([]byte, string, error) {
+	maxSize := config.Load().MaxFileSize
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize)
+
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			return nil, "", fmt.Errorf("file is required")
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read upload")
+		}
+		defer file.Close()
+
+		body, err := io.ReadAll(file)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read upload")
+		}
+		return body, fileHeader.Filename, nil
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("upload exceeds the %d byte limit", maxSize)
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil, "", fmt.Errorf("request body is empty")
+	}
+	return body, "", nil
+}
+
+// detectCatalogFormat resolves the upload format from the query, content type or file name
+func detectCatalogFormat(format, contentType, filename string) string {
+	switch strings.ToLower(format) {
+	case catalogFormatCSV:
+		return catalogFormatCSV
+	case catalogFormatNDJSON, "jsonl":
+		return catalogFormatNDJSON
+	case "":
+	default:
+		return ""
+	}
+
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		switch mediaType {
+		case "text/csv", "application/csv":
+			return catalogFormatCSV
+		case "application/x-ndjson", "application/jsonl", "application/json":
+			return catalogFormatNDJSON
+		}
+	}
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return catalogFormatCSV
+	case ".ndjson", ".jsonl":
+		return catalogFormatNDJSON
+	}
+
+	return ""
+}
+
+// parseCatalogCSV parses a CSV upload whose first line is a header row
+func parseCatalogCSV(body []byte, spec catalogSpec) ([]catalogImportRow, error) {
+	reader := csv.NewReader(bytes.NewReader(body))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	known := make(map[string]bool, len(spec.columns))
+	for _, column := range spec.columns {
+		known[column] = true
+	}
+	for i, column := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(column, "\ufeff")))
+		if !known[header[i]] {
+			return nil, fmt.Errorf("unknown CSV column %q", column)
+		}
+	}
+
+	var rows []catalogImportRow
+	for rowNumber := 1; ; rowNumber++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			rows = append(rows, catalogImportRow{row: rowNumber, errs: []string{err.Error()}})
+			continue
+		}
+		if len(record) != len(header) {
+			rows = append(rows, catalogImportRow{
+				row:  rowNumber,
+				errs: []string{fmt.Sprintf("expected %d columns, got %d", len(header), len(record))},
+			})
+			continue
+		}
+
+		values := make(map[string]string, len(header))
+		for i, column := range header {
+			values[column] = strings.TrimSpace(record[i])
+		}
+
+		row := spec.parseCSV(values)
+		row.row = rowNumber
+		deferEmptyColumns(&row, values)
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// deferEmptyColumns moves the fields of columns a CSV row leaves out or empty to its defaults, so
+// updating an existing document keeps what it has for them
+func deferEmptyColumns(row *catalogImportRow, values map[string]string) {
+	present := make(map[string]bool, len(values))
+	for column, value := range values {
+		if value == "" {
+			continue
+		}
+		field := column
+		for _, nutrition := range nutritionColumns {
+			if column == nutrition {
+				field = "nutrition"
+			}
+		}
+		present[field] = true
+	}
+
+	row.defaults = bson.M{}
+	for field, value := range row.fields {
+		if !present[field] {
+			row.defaults[field] = value
+			delete(row.fields, field)
+		}
+	}
+}
+
+// parseCatalogNDJSON parses an upload with one JSON object per line
+func parseCatalogNDJSON(body []byte, spec catalogSpec) ([]catalogImportRow, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var rows []catalogImportRow
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		row := spec.parseJSON(line)
+		row.row = lineNumber
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read NDJSON: %w", err)
+	}
+
+	return rows, nil
+}
+
+// linkImportCategories resolves each service row's category slug to a managed category
+func linkImportCategories(ctx context.Context, rows []catalogImportRow) error {
+	cursor, err := db.GetMongoDB().Collection("categories").Find(ctx, bson.M{},
+		options.Find().SetProjection(bson.M{"_id": 1, "slug": 1}))
+	if err != nil {
+		return err
+	}
+
+	var categories []models.Category
+	if err := cursor.All(ctx, &categories); err != nil {
+		return err
+	}
+
+	bySlug := make(map[string]primitive.ObjectID, len(categories))
+	for _, category := range categories {
+		bySlug[category.Slug] = category.ID
+	}
+
+	for i := range rows {
+		name, _ := rows[i].fields["category"].(string)
+		if name == "" {
+			continue
+		}
+
+		slug := slugify(name)
+		categoryID, ok := bySlug[slug]
+		if !ok {
+			rows[i].errs = append(rows[i].errs, fmt.Sprintf("unknown category %q", name))
+			continue
+		}
+		rows[i].fields["category"] = slug
+		rows[i].fields["category_id"] = categoryID
+	}
+
+	return nil
+}
+
+// existingCatalogSKUs returns which of the rows' SKUs are already stored
+func existingCatalogSKUs(ctx context.Context, collection *mongo.Collection, rows []catalogImportRow) (map[string]bool, error) {
+	existing := make(map[string]bool)
+	if len(rows) == 0 {
+		return existing, nil
+	}
+
+	skus := make([]string, 0, len(rows))
+	for _, row := range rows {
+		skus = append(skus, row.sku)
+	}
+
+	cursor, err := collection.Find(ctx, bson.M{"sku": bson.M{"$in": skus}}, options.Find().SetProjection(bson.M{"sku": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var doc struct {
+			SKU string `bson:"sku"`
+		}
+		if err := cursor.Decode(&doc); err == nil {
+			existing[doc.SKU] = true
+		}
+	}
+
+	return existing, cursor.Err()
+}
+
+// parseServiceCSV maps a CSV row onto a service
+func parseServiceCSV(values map[string]string) catalogImportRow {
+	var errs []string
+	service := models.Service{
+		SKU:         values["sku"],
+		Name:        values["name"],
+		Description: values["description"],
+		Category:    values["category"],
+		BasePrice:   parseCSVMoney(values, "base_price", &errs),
+		Duration:    parseCSVInt(values, "duration", &errs),
+		Skills:      splitCSVList(values["skills"]),
+		DietaryTags: splitCSVList(values["dietary_tags"]),
+		Allergens:   splitCSVList(values["allergens"]),
+		Nutrition:   parseCSVNutrition(values, &errs),
+		IsActive:    parseCSVBool(values, "is_active", true, &errs),
+	}
+	if vendorID := values["vendor_id"]; vendorID != "" {
+		id, err := primitive.ObjectIDFromHex(vendorID)
+		if err != nil {
+			errs = append(errs, "vendor_id is not a valid ID")
+		}
+		service.VendorID = id
+	}
+
+	return serviceImportRow(service, errs)
+}
+
+// parseServiceJSON maps an NDJSON line onto a service
+func parseServiceJSON(line []byte) catalogImportRow {
+	service := models.Service{IsActive: true}
+	if err := json.Unmarshal(line, &service); err != nil {
+		return catalogImportRow{errs: []string{"invalid JSON: " + err.Error()}}
+	}
+
+	return serviceImportRow(service, nil)
+}
+
+// serviceImportRow validates a service and converts it to upsert fields
+func serviceImportRow(service models.Service, errs []string) catalogImportRow {
+	service.SKU = strings.TrimSpace(service.SKU)
+	if service.SKU == "" {
+		errs = append(errs, "sku is required")
+	}
+	if strings.TrimSpace(service.Name) == "" {
+		errs = append(errs, "name is required")
+	}
+	if service.BasePrice.IsNegative() {
+		errs = append(errs, "base_price must not be negative")
+	}
+	if service.Duration < 0 {
+		errs = append(errs, "duration must not be negative")
+	}
+	service.DietaryTags, service.Allergens = validateCatalogDietary(service.DietaryTags, service.Allergens, &errs)
+	service.Skills = staffing.NormalizeSkills(service.Skills)
+	seen := make(map[string]bool)
+	for _, addOn := range service.AddOns {
+		name := strings.ToLower(strings.TrimSpace(addOn.Name))
+		switch {
+		case name == "":
+			errs = append(errs, "add-on name is required")
+		case seen[name]:
+			errs = append(errs, "add-on "+addOn.Name+" is listed more than once")
+		case addOn.Price.IsNegative() || addOn.Duration < 0:
+			errs = append(errs, "add-on "+addOn.Name+" must not have a negative price or duration")
+		}
+		seen[name] = true
+	}
+
+	fields := bson.M{
+		"sku":          service.SKU,
+		"name":         service.Name,
+		"description":  service.Description,
+		"category":     service.Category,
+		"base_price":   service.BasePrice,
+		"duration":     service.Duration,
+		"skills":       service.Skills,
+		"dietary_tags": service.DietaryTags,
+		"allergens":    service.Allergens,
+		"nutrition":    service.Nutrition,
+		"is_active":    service.IsActive,
+	}
+	if !service.VendorID.IsZero() {
+		fields["vendor_id"] = service.VendorID
+	}
+	// Add-ons only travel in NDJSON; CSV imports leave existing add-ons untouched
+	if service.AddOns != nil {
+		fields["add_ons"] = service.AddOns
+	}
+
+	return catalogImportRow{sku: service.SKU, errs: errs, fields: fields}
+}
+
+// exportService decodes a stored service into its NDJSON and CSV representations
+func exportService(cursor *mongo.Cursor) (interface{}, []string, error) {
+	var service models.Service
+	if err := cursor.Decode(&service); err != nil {
+		return nil, nil, err
+	}
+
+	vendorID := ""
+	if !service.VendorID.IsZero() {
+		vendorID = service.VendorID.Hex()
+	}
+
+	record := []string{
+		service.SKU,
+		vendorID,
+		service.Name,
+		service.Description,
+		service.Category,
+		service.BasePrice.String(),
+		strconv.Itoa(service.Duration),
+		strings.Join(service.Skills, catalogListSeparator),
+		strings.Join(service.DietaryTags, catalogListSeparator),
+		strings.Join(service.Allergens, catalogListSeparator),
+	}
+	record = append(record, formatCSVNutrition(service.Nutrition)...)
+	record = append(record, strconv.FormatBool(service.IsActive))
+
+	return service, record, nil
+}
+
+// parseMenuItemCSV maps a CSV row onto a menu item
+func parseMenuItemCSV(values map[string]string) catalogImportRow {
+	var errs []string
+	item := models.MenuItem{
+		SKU:         values["sku"],
+		Name:        values["name"],
+		Description: values["description"],
+		Category:    values["category"],
+		Price:       parseCSVMoney(values, "price", &errs),
+		DietaryTags: splitCSVList(values["dietary_tags"]),
+		Allergens:   splitCSVList(values["allergens"]),
+		Nutrition:   parseCSVNutrition(values, &errs),
+		IsAvailable: parseCSVBool(values, "is_available", true, &errs),
+	}
+	if vendorID := values["vendor_id"]; vendorID != "" {
+		id, err := primitive.ObjectIDFromHex(vendorID)
+		if err != nil {
+			errs = append(errs, "vendor_id is not a valid ID")
+		}
+		item.VendorID = id
+	}
+
+	return menuItemImportRow(item, errs)
+}
+
+// parseMenuItemJSON maps an NDJSON line onto a menu item
+func parseMenuItemJSON(line []byte) catalogImportRow {
+	item := models.MenuItem{IsAvailable: true}
+	if err := json.Unmarshal(line, &item); err != nil {
+		return catalogImportRow{errs: []string{"invalid JSON: " + err.Error()}}
+	}
+
+	return menuItemImportRow(item, nil)
+}
+
+// menuItemImportRow validates a menu item and converts it to upsert fields
+func menuItemImportRow(item models.MenuItem, errs []string) catalogImportRow {
+	item.SKU = strings.TrimSpace(item.SKU)
+	if item.SKU == "" {
+		errs = append(errs, "sku is required")
+	}
+	if strings.TrimSpace(item.Name) == "" {
+		errs = append(errs, "name is required")
+	}
+	if item.Price.IsNegative() {
+		errs = append(errs, "price must not be negative")
+	}
+	item.DietaryTags, item.Allergens = validateCatalogDietary(item.DietaryTags, item.Allergens, &errs)
+
+	fields := bson.M{
+		"sku":          item.SKU,
+		"name":         item.Name,
+		"description":  item.Description,
+		"category":     item.Category,
+		"price":        item.Price,
+		"dietary_tags": item.DietaryTags,
+		"allergens":    item.Allergens,
+		"nutrition":    item.Nutrition,
+		"is_available": item.IsAvailable,
+	}
+	if !item.VendorID.IsZero() {
+		fields["vendor_id"] = item.VendorID
+	}
+	// Modifier groups only travel in NDJSON; CSV imports leave existing groups untouched
+	if item.ModifierGroups != nil {
+		fields["modifier_groups"] = item.ModifierGroups
+	}
+
+	return catalogImportRow{sku: item.SKU, errs: errs, fields: fields}
+}
+
+// exportMenuItem decodes a stored menu item into its NDJSON and CSV representations
+func exportMenuItem(cursor *mongo.Cursor) (interface{}, []string, error) {
+	var item models.MenuItem
+	if err := cursor.Decode(&item); err != nil {
+		return nil, nil, err
+	}
+
+	vendorID := ""
+	if !item.VendorID.IsZero() {
+		vendorID = item.VendorID.Hex()
+	}
+
+	record := []string{
+		item.SKU,
+		vendorID,
+		item.Name,
+		item.Description,
+		item.Category,
+		item.Price.String(),
+		strings.Join(item.DietaryTags, catalogListSeparator),
+		strings.Join(item.Allergens, catalogListSeparator),
+	}
+	record = append(record, formatCSVNutrition(item.Nutrition)...)
+	record = append(record, strconv.FormatBool(item.IsAvailable))
+
+	return item, record, nil
+}
+
+// validateCatalogDietary normalizes dietary tags and allergens, collecting validation errors
+func validateCatalogDietary(tags, allergens []string, errs *[]string) ([]string, []string) {
+	normalizedTags, err := normalizeDietaryValues(tags, models.DietaryTags)
+	if err != nil {
+		*errs = append(*errs, "dietary_tags: "+err.Error())
+	}
+	normalizedAllergens, err := normalizeDietaryValues(allergens, models.Allergens)
+	if err != nil {
+		*errs = append(*errs, "allergens: "+err.Error())
+	}
+	return normalizedTags, normalizedAllergens
+}
+
+// parseCSVFloat parses an optional decimal column
+func parseCSVFloat(values map[string]string, column string, errs *[]string) float64 {
+	value := values[column]
+	if value == "" {
+		return 0
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		*errs = append(*errs, column+" must be a number")
+	}
+	return parsed
+}
+
+// parseCSVMoney parses an optional amount column in major units, e.g. 12.50
+func parseCSVMoney(values map[string]string, column string, errs *[]string) money.Money {
+	value := values[column]
+	if value == "" {
+		return money.New(0, money.Currency())
+	}
+	parsed, err := money.Parse(value, money.Currency())
+	if err != nil {
+		*errs = append(*errs, column+" must be an amount with at most "+strconv.Itoa(money.Exponent(money.Currency()))+" decimal places")
+	}
+	return parsed
+}
+
+// parseCSVInt parses an optional integer column
+func parseCSVInt(values map[string]string, column string, errs *[]string) int {
+	value := values[column]
+	if value == "" {
+		return 0
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		*errs = append(*errs, column+" must be a whole number")
+	}
+	return parsed
+}
+
+// parseCSVBool parses an optional boolean column
+func parseCSVBool(values map[string]string, column string, defaultValue bool, errs *[]string) bool {
+	value := values[column]
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		*errs = append(*errs, column+" must be true or false")
+		return defaultValue
+	}
+	return parsed
+}
+
+// parseCSVNutrition builds nutrition facts from the optional nutrition columns
+func parseCSVNutrition(values map[string]string, errs *[]string) *models.NutritionFacts {
+	present := false
+	for _, column := range nutritionColumns {
+		if values[column] != "" {
+			present = true
+			break
+		}
+	}
+	if !present {
+		return nil
+	}
+
+	return &models.NutritionFacts{
+		ServingSize:      values["serving_size"],
+		Calories:         parseCSVInt(values, "calories", errs),
+		ProteinGrams:     parseCSVFloat(values, "protein_g", errs),
+		CarbsGrams:       parseCSVFloat(values, "carbs_g", errs),
+		FatGrams:         parseCSVFloat(values, "fat_g", errs),
+		FiberGrams:       parseCSVFloat(values, "fiber_g", errs),
+		SugarGrams:       parseCSVFloat(values, "sugar_g", errs),
+		SodiumMilligrams: parseCSVFloat(values, "sodium_mg", errs),
+	}
+}
+
+// formatCSVNutrition renders nutrition facts in nutritionColumns order
+func formatCSVNutrition(nutrition *models.NutritionFacts) []string {
+	if nutrition == nil {
+		return make([]string, len(nutritionColumns))
+	}
+
+	format := func(value float64) string { return strconv.FormatFloat(value, 'f', -1, 64) }
+	return []string{
+		nutrition.ServingSize,
+		strconv.Itoa(nutrition.Calories),
+		format(nutrition.ProteinGrams),
+		format(nutrition.CarbsGrams),
+		format(nutrition.FatGrams),
+		format(nutrition.FiberGrams),
+		format(nutrition.SugarGrams),
+		format(nutrition.SodiumMilligrams),
+	}
+}
+
+// splitCSVList splits a multi-value CSV cell
+func splitCSVList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, catalogListSeparator)
+}
@@ -0,0 +1,554 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/code-harsh006/food-delivery/internal/models"
+	"github.com/code-harsh006/food-delivery/pkg/db"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var slugInvalidChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+var (
+	errCategoryNotFound = errors.New("parent category not found")
+	errCategoryCycle    = errors.New("a category cannot be nested under itself or its descendants")
+)
+
+// CreateCategory creates a managed category (admin only)
+func CreateCategory(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	var req models.CreateCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	category := models.Category{
+		Name:        strings.TrimSpace(req.Name),
+		Slug:        slugify(req.Slug),
+		Description: req.Description,
+		Icon:        req.Icon,
+		SortOrder:   req.SortOrder,
+		IsActive:    req.IsActive == nil || *req.IsActive,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+	if category.Slug == "" {
+		category.Slug = slugify(category.Name)
+	}
+	if category.Slug == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A slug could not be derived from the name"})
+		return
+	}
+
+	if req.ParentID != "" {
+		parentID, err := primitive.ObjectIDFromHex(req.ParentID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid parent ID"})
+			return
+		}
+		if err := validateCategoryParent(context.Background(), primitive.NilObjectID, parentID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		category.ParentID = parentID
+	}
+
+	collection := mongoDB.Collection("categories")
+	result, err := collection.InsertOne(context.Background(), category)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "A category with this slug already exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create category"})
+		return
+	}
+
+	category.ID = result.InsertedID.(primitive.ObjectID)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Category created successfully",
+		"category": category,
+	})
+}
+
+// UpdateCategory updates a managed category (admin only)
+func UpdateCategory(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	categoryID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		return
+	}
+
+	var req models.UpdateCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	set := bson.M{"updated_at": time.Now()}
+	unset := bson.M{}
+	if req.Name != nil {
+		if strings.TrimSpace(*req.Name) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Name must not be empty"})
+			return
+		}
+		set["name"] = strings.TrimSpace(*req.Name)
+	}
+	if req.Slug != nil {
+		slug := slugify(*req.Slug)
+		if slug == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Slug must not be empty"})
+			return
+		}
+		set["slug"] = slug
+	}
+	if req.Description != nil {
+		set["description"] = *req.Description
+	}
+	if req.Icon != nil {
+		set["icon"] = *req.Icon
+	}
+	if req.SortOrder != nil {
+		set["sort_order"] = *req.SortOrder
+	}
+	if req.IsActive != nil {
+		set["is_active"] = *req.IsActive
+	}
+	if req.ParentID != nil {
+		if *req.ParentID == "" {
+			unset["parent_id"] = ""
+		} else {
+			parentID, err := primitive.ObjectIDFromHex(*req.ParentID)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid parent ID"})
+				return
+			}
+			if err := validateCategoryParent(context.Background(), categoryID, parentID); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			set["parent_id"] = parentID
+		}
+	}
+
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	var category models.Category
+	err = db.WithTransaction(context.Background(), func(ctx context.Context) error {
+		err := mongoDB.Collection("categories").FindOneAndUpdate(ctx, bson.M{"_id": categoryID}, update,
+			options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&category)
+		if err != nil {
+			return err
+		}
+
+		// Keep the denormalized slug on services in step with the category
+		if slug, ok := set["slug"]; ok {
+			_, err = mongoDB.Collection("services").UpdateMany(ctx,
+				bson.M{"category_id": categoryID},
+				bson.M{"$set": bson.M{"category": slug}},
+			)
+		}
+		return err
+	})
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+			return
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "A category with this slug already exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update category"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Category updated successfully",
+		"category": category,
+	})
+}
+
+// DeleteCategory removes a category that has no children and no services (admin only)
+func DeleteCategory(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	categoryID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		return
+	}
+
+	children, err := mongoDB.Collection("categories").CountDocuments(context.Background(), bson.M{"parent_id": categoryID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if children > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Category has child categories; move or delete them first"})
+		return
+	}
+
+	services, err := mongoDB.Collection("services").CountDocuments(context.Background(), bson.M{"category_id": categoryID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if services > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Category still has services; reassign them or deactivate the category instead"})
+		return
+	}
+
+	result, err := mongoDB.Collection("categories").DeleteOne(context.Background(), bson.M{"_id": categoryID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete category"})
+		return
+	}
+	if result.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Category deleted successfully"})
+}
+
+// SetServiceCategory assigns a service to a managed category (admin only)
+func SetServiceCategory(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	serviceID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service ID"})
+		return
+	}
+
+	var req struct {
+		CategoryID string `json:"category_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	categoryID, err := primitive.ObjectIDFromHex(req.CategoryID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		return
+	}
+
+	var category models.Category
+	err = mongoDB.Collection("categories").FindOne(context.Background(), bson.M{"_id": categoryID}).Decode(&category)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	result, err := mongoDB.Collection("services").UpdateOne(context.Background(), bson.M{"_id": serviceID}, bson.M{
+		"$set": bson.M{
+			"category_id": category.ID,
+			"category":    category.Slug,
+			"updated_at":  time.Now(),
+		},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update service"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Service category updated successfully",
+		"category": category,
+	})
+}
+
+// BackfillCategories creates managed categories for the free-text category values on
+// services that are not linked to a category yet, and links those services (admin only)
+func BackfillCategories(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	ctx := context.Background()
+	servicesCollection := mongoDB.Collection("services")
+	categoriesCollection := mongoDB.Collection("categories")
+
+	values, err := servicesCollection.Distinct(ctx, "category", bson.M{
+		"category_id": bson.M{"$exists": false},
+		"category":    bson.M{"$nin": bson.A{"", nil}},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read service categories"})
+		return
+	}
+
+	created, linked := 0, int64(0)
+	for _, value := range values {
+		name, ok := value.(string)
+		if !ok {
+			continue
+		}
+		slug := slugify(name)
+		if slug == "" {
+			continue
+		}
+
+		// Variants such as "Cleaning" and "cleaning " collapse onto the same slug
+		var category models.Category
+		res, err := categoriesCollection.UpdateOne(ctx,
+			bson.M{"slug": slug},
+			bson.M{"$setOnInsert": models.Category{
+				Name:      strings.TrimSpace(name),
+				Slug:      slug,
+				IsActive:  true,
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+			}},
+			options.Update().SetUpsert(true),
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create category " + slug})
+			return
+		}
+		if res.UpsertedCount > 0 {
+			created++
+		}
+		if err := categoriesCollection.FindOne(ctx, bson.M{"slug": slug}).Decode(&category); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load category " + slug})
+			return
+		}
+
+		update, err := servicesCollection.UpdateMany(ctx,
+			bson.M{"category": name, "category_id": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"category_id": category.ID, "category": category.Slug}},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to link services to " + slug})
+			return
+		}
+		linked += update.ModifiedCount
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":            "Category backfill completed",
+		"categories_created": created,
+		"services_linked":    linked,
+	})
+}
+
+// loadCategoryTree builds the category tree with active service counts rolled up to each ancestor
+func loadCategoryTree(ctx context.Context, includeInactive bool) ([]*models.CategoryNode, error) {
+	mongoDB := db.GetMongoDB()
+
+	filter := bson.M{}
+	if !includeInactive {
+		filter["is_active"] = true
+	}
+
+	var categories []models.Category
+	cursor, err := mongoDB.Collection("categories").Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &categories); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[primitive.ObjectID]int)
+	countCursor, err := mongoDB.Collection("services").Aggregate(ctx, []bson.M{
+		{"$match": bson.M{"is_active": true, "category_id": bson.M{"$exists": true}}},
+		{"$group": bson.M{"_id": "$category_id", "count": bson.M{"$sum": 1}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var countResults []struct {
+		ID    primitive.ObjectID `bson:"_id"`
+		Count int                `bson:"count"`
+	}
+	if err := countCursor.All(ctx, &countResults); err != nil {
+		return nil, err
+	}
+	for _, result := range countResults {
+		counts[result.ID] = result.Count
+	}
+
+	return buildCategoryTree(categories, counts), nil
+}
+
+// buildCategoryTree nests categories under their parents and sorts each level by sort order.
+// Categories whose parent is missing from the input (e.g. an inactive parent) are dropped.
+func buildCategoryTree(categories []models.Category, counts map[primitive.ObjectID]int) []*models.CategoryNode {
+	nodes := make(map[primitive.ObjectID]*models.CategoryNode, len(categories))
+	for _, category := range categories {
+		nodes[category.ID] = &models.CategoryNode{
+			Category:  category,
+			ItemCount: counts[category.ID],
+			Children:  []*models.CategoryNode{},
+		}
+	}
+
+	roots := []*models.CategoryNode{}
+	for _, category := range categories {
+		node := nodes[category.ID]
+		if category.ParentID.IsZero() {
+			roots = append(roots, node)
+			continue
+		}
+		if parent, ok := nodes[category.ParentID]; ok {
+			parent.Children = append(parent.Children, node)
+		}
+	}
+
+	var finalize func(level []*models.CategoryNode) int
+	finalize = func(level []*models.CategoryNode) int {
+		sort.SliceStable(level, func(i, j int) bool {
+			if level[i].SortOrder != level[j].SortOrder {
+				return level[i].SortOrder < level[j].SortOrder
+			}
+			return level[i].Name < level[j].Name
+		})
+
+		total := 0
+		for _, node := range level {
+			node.ItemCount += finalize(node.Children)
+			total += node.ItemCount
+		}
+		return total
+	}
+	finalize(roots)
+
+	return roots
+}
+
+// resolveCategoryIDs returns the ID of the active category with the given slug plus all of its
+// active descendants. ok is false when no such category exists.
+func resolveCategoryIDs(ctx context.Context, slug string) ([]primitive.ObjectID, bool, error) {
+	mongoDB := db.GetMongoDB()
+
+	var categories []models.Category
+	cursor, err := mongoDB.Collection("categories").Find(ctx, bson.M{"is_active": true},
+		options.Find().SetProjection(bson.M{"_id": 1, "slug": 1, "parent_id": 1}))
+	if err != nil {
+		return nil, false, err
+	}
+	if err := cursor.All(ctx, &categories); err != nil {
+		return nil, false, err
+	}
+
+	children := make(map[primitive.ObjectID][]primitive.ObjectID)
+	var root primitive.ObjectID
+	for _, category := range categories {
+		if category.Slug == slug {
+			root = category.ID
+		}
+		if !category.ParentID.IsZero() {
+			children[category.ParentID] = append(children[category.ParentID], category.ID)
+		}
+	}
+	if root.IsZero() {
+		return nil, false, nil
+	}
+
+	ids := []primitive.ObjectID{root}
+	for i := 0; i < len(ids); i++ {
+		ids = append(ids, children[ids[i]]...)
+	}
+
+	return ids, true, nil
+}
+
+// validateCategoryParent checks that parentID exists and is not categoryID or one of its descendants
+func validateCategoryParent(ctx context.Context, categoryID, parentID primitive.ObjectID) error {
+	collection := db.GetMongoDB().Collection("categories")
+
+	// Walk up from the proposed parent; reaching categoryID would create a cycle
+	current := parentID
+	for depth := 0; !current.IsZero(); depth++ {
+		if current == categoryID || depth > 32 {
+			return errCategoryCycle
+		}
+
+		var category models.Category
+		err := collection.FindOne(ctx, bson.M{"_id": current},
+			options.FindOne().SetProjection(bson.M{"parent_id": 1})).Decode(&category)
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				return errCategoryNotFound
+			}
+			return err
+		}
+		current = category.ParentID
+	}
+
+	return nil
+}
+
+// slugify lowercases a value and replaces runs of non-alphanumeric characters with hyphens
+func slugify(value string) string {
+	return strings.Trim(slugInvalidChars.ReplaceAllString(strings.ToLower(strings.TrimSpace(value)), "-"), "-")
+}
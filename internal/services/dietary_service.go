@@ -0,0 +1,219 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/code-harsh006/food-delivery/internal/models"
+	"github.com/code-harsh006/food-delivery/pkg/db"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// errDietaryPreferences is returned when the saved preferences of a user cannot be read
+var errDietaryPreferences = errors.New("failed to load dietary preferences")
+
+// GetDietaryOptions returns the supported dietary tags and allergens
+func GetDietaryOptions(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"dietary_tags": models.DietaryTags,
+		"allergens":    models.Allergens,
+	})
+}
+
+// GetDietaryPreferences returns the user's saved dietary preferences
+func GetDietaryPreferences(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	userID := getUserIDFromContext(c)
+	if userID.IsZero() {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	var user models.User
+	collection := mongoDB.Collection("users")
+	err := collection.FindOne(context.Background(), bson.M{"_id": userID}).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"dietary_preferences": user.DietaryPreferences})
+}
+
+// UpdateDietaryPreferences replaces the user's saved dietary preferences
+func UpdateDietaryPreferences(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	userID := getUserIDFromContext(c)
+	if userID.IsZero() {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	var req models.UpdateDietaryPreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	diets, err := normalizeDietaryValues(req.Diets, models.DietaryTags)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	allergens, err := normalizeDietaryValues(req.ExcludeAllergens, models.Allergens)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	prefs := models.DietaryPreferences{Diets: diets, ExcludeAllergens: allergens}
+	collection := mongoDB.Collection("users")
+	result, err := collection.UpdateOne(context.Background(), bson.M{"_id": userID}, bson.M{
+		"$set": bson.M{
+			"dietary_preferences": prefs,
+			"updated_at":          time.Now(),
+		},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update dietary preferences"})
+		return
+	}
+
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":             "Dietary preferences updated successfully",
+		"dietary_preferences": prefs,
+	})
+}
+
+// applyDietaryFilter adds dietary tag and allergen conditions to a catalog filter.
+// Explicit diet/exclude_allergens query parameters win; otherwise the saved
+// preferences of the requesting user are used unless apply_preferences=false.
+func applyDietaryFilter(c *gin.Context, filter bson.M) error {
+	dietParam, hasDiet := c.GetQuery("diet")
+	allergenParam, hasAllergens := c.GetQuery("exclude_allergens")
+
+	var prefs models.DietaryPreferences
+	if (!hasDiet || !hasAllergens) && c.Query("apply_preferences") != "false" {
+		var err error
+		if prefs, err = loadDietaryPreferences(getUserIDFromContext(c)); err != nil {
+			return err
+		}
+	}
+
+	diets := prefs.Diets
+	if hasDiet {
+		var err error
+		if diets, err = normalizeDietaryValues(splitQueryList(dietParam), models.DietaryTags); err != nil {
+			return err
+		}
+	}
+
+	allergens := prefs.ExcludeAllergens
+	if hasAllergens {
+		var err error
+		if allergens, err = normalizeDietaryValues(splitQueryList(allergenParam), models.Allergens); err != nil {
+			return err
+		}
+	}
+
+	if len(diets) > 0 {
+		filter["dietary_tags"] = bson.M{"$all": diets}
+	}
+	if len(allergens) > 0 {
+		filter["allergens"] = bson.M{"$nin": allergens}
+	}
+
+	return nil
+}
+
+// loadDietaryPreferences returns the saved preferences for a user, or none for anonymous
+// requests and unknown users
+func loadDietaryPreferences(userID primitive.ObjectID) (models.DietaryPreferences, error) {
+	var user models.User
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil || userID.IsZero() {
+		return user.DietaryPreferences, nil
+	}
+
+	err := mongoDB.Collection("users").FindOne(context.Background(), bson.M{"_id": userID}).Decode(&user)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return models.DietaryPreferences{}, fmt.Errorf("%w: %v", errDietaryPreferences, err)
+	}
+	return user.DietaryPreferences, nil
+}
+
+// respondDietaryFilterError writes the response for a catalog filter applyDietaryFilter rejected
+func respondDietaryFilterError(c *gin.Context, err error) {
+	if errors.Is(err, errDietaryPreferences) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load dietary preferences"})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
+// normalizeDietaryValues lowercases, de-duplicates and validates values against an allowed list
+func normalizeDietaryValues(values []string, allowed []string) ([]string, error) {
+	known := make(map[string]bool, len(allowed))
+	for _, value := range allowed {
+		known[value] = true
+	}
+
+	seen := make(map[string]bool, len(values))
+	normalized := make([]string, 0, len(values))
+	for _, value := range values {
+		value = strings.ToLower(strings.TrimSpace(value))
+		value = strings.ReplaceAll(strings.ReplaceAll(value, "-", "_"), " ", "_")
+		if value == "" || seen[value] {
+			continue
+		}
+		if !known[value] {
+			return nil, fmt.Errorf("unsupported value %q", value)
+		}
+		seen[value] = true
+		normalized = append(normalized, value)
+	}
+
+	return normalized, nil
+}
+
+// splitQueryList splits a comma-separated query parameter
+func splitQueryList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
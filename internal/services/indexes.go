@@ -0,0 +1,256 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/code-harsh006/food-delivery/pkg/db"
+	"github.com/code-harsh006/food-delivery/pkg/middleware"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// collectionIndexes groups the index definitions for a single collection
+type collectionIndexes struct {
+	collection string
+	indexes    []mongo.IndexModel
+}
+
+// requiredIndexes lists the indexes the MongoDB handlers rely on
+var requiredIndexes = []collectionIndexes{
+	{
+		collection: "services",
+		indexes: []mongo.IndexModel{
+			{
+				Keys: bson.D{{Key: "sku", Value: 1}},
+				Options: options.Index().SetUnique(true).
+					SetPartialFilterExpression(bson.M{"sku": bson.M{"$type": "string"}}),
+			},
+			{Keys: bson.D{{Key: "category_id", Value: 1}}},
+		},
+	},
+	{
+		collection: "categories",
+		indexes: []mongo.IndexModel{
+			{Keys: bson.D{{Key: "slug", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "parent_id", Value: 1}, {Key: "sort_order", Value: 1}}},
+		},
+	},
+	{
+		collection: "bookings",
+		indexes: []mongo.IndexModel{
+			{Keys: bson.D{{Key: "created_at", Value: 1}, {Key: "user_id", Value: 1}}},
+			// A recurring booking has at most one booking at a time
+			{
+				Keys: bson.D{{Key: "series_id", Value: 1}, {Key: "scheduled_at", Value: 1}},
+				Options: options.Index().SetUnique(true).
+					SetPartialFilterExpression(bson.M{"series_id": bson.M{"$exists": true}, "scheduled_at": bson.M{"$type": "date"}}),
+			},
+			// Technician schedules: overlap checks and the daily job list
+			{Keys: bson.D{{Key: "technician_id", Value: 1}, {Key: "job_start", Value: 1}}},
+			{Keys: bson.D{{Key: "technician_id", Value: 1}, {Key: "scheduled_at", Value: 1}}},
+			// Upcoming bookings the reminder scheduler looks at
+			{Keys: bson.D{{Key: "status", Value: 1}, {Key: "scheduled_at", Value: 1}}},
+			// Payment webhooks that do not carry the booking ID
+			{
+				Keys:    bson.D{{Key: "payment.provider", Value: 1}, {Key: "payment.intent_id", Value: 1}},
+				Options: options.Index().SetPartialFilterExpression(bson.M{"payment": bson.M{"$exists": true}}),
+			},
+		},
+	},
+	{
+		collection: "menu_items",
+		indexes: []mongo.IndexModel{
+			// Items without a SKU, or with an empty one, do not collide
+			{
+				Keys: bson.D{{Key: "sku", Value: 1}},
+				Options: options.Index().SetName("sku_1_present").SetUnique(true).
+					SetPartialFilterExpression(bson.M{"sku": bson.M{"$type": "string", "$gt": ""}}),
+			},
+			{Keys: bson.D{{Key: "vendor_id", Value: 1}}},
+		},
+	},
+	{
+		collection: "carts",
+		indexes: []mongo.IndexModel{
+			{
+				Keys: bson.D{{Key: "user_id", Value: 1}},
+				Options: options.Index().SetUnique(true).
+					SetPartialFilterExpression(bson.M{"user_id": bson.M{"$type": "objectId"}}),
+			},
+			{
+				Keys: bson.D{{Key: "token", Value: 1}},
+				Options: options.Index().SetUnique(true).
+					SetPartialFilterExpression(bson.M{"token": bson.M{"$type": "string"}}),
+			},
+			// Abandoned carts are removed once they pass expires_at
+			{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+	},
+	{
+		collection: "orders",
+		indexes: []mongo.IndexModel{
+			{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+			{Keys: bson.D{{Key: "vendor_id", Value: 1}, {Key: "status", Value: 1}}},
+		},
+	},
+	{
+		collection: "outbox",
+		indexes: []mongo.IndexModel{
+			{Keys: bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}}},
+			// Delivered messages are kept for a week for troubleshooting
+			{Keys: bson.D{{Key: "delivered_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(7 * 24 * 60 * 60)},
+		},
+	},
+	{
+		collection: "promotions",
+		indexes: []mongo.IndexModel{
+			{Keys: bson.D{{Key: "code", Value: 1}}, Options: options.Index().SetUnique(true)},
+		},
+	},
+	{
+		collection: "promotion_redemptions",
+		indexes: []mongo.IndexModel{
+			{Keys: bson.D{{Key: "promotion_id", Value: 1}, {Key: "created_at", Value: -1}}},
+			// A booking or order redeems at most one promotion
+			{Keys: bson.D{{Key: "reference_type", Value: 1}, {Key: "reference_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		},
+	},
+	{
+		collection: "slot_templates",
+		indexes: []mongo.IndexModel{
+			{Keys: bson.D{{Key: "service_id", Value: 1}, {Key: "is_active", Value: 1}}},
+			{Keys: bson.D{{Key: "vendor_id", Value: 1}, {Key: "is_active", Value: 1}}},
+		},
+	},
+	{
+		collection: "slot_capacity",
+		indexes: []mongo.IndexModel{
+			{Keys: bson.D{{Key: "scope", Value: 1}, {Key: "date", Value: 1}}},
+		},
+	},
+	{
+		collection: "cancellation_policies",
+		indexes: []mongo.IndexModel{
+			{Keys: bson.D{{Key: "service_id", Value: 1}, {Key: "is_active", Value: 1}}},
+			{Keys: bson.D{{Key: "vendor_id", Value: 1}, {Key: "is_active", Value: 1}}},
+		},
+	},
+	{
+		collection: "booking_series",
+		indexes: []mongo.IndexModel{
+			{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+			{Keys: bson.D{{Key: "status", Value: 1}}},
+		},
+	},
+	{
+		collection: "technicians",
+		indexes: []mongo.IndexModel{
+			{Keys: bson.D{{Key: "vendor_id", Value: 1}, {Key: "is_active", Value: 1}}},
+			{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		},
+	},
+	{
+		collection: "reviews",
+		indexes: []mongo.IndexModel{
+			// A booking is reviewed at most once
+			{Keys: bson.D{{Key: "booking_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "service_id", Value: 1}, {Key: "created_at", Value: -1}}},
+			{Keys: bson.D{{Key: "vendor_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		},
+	},
+	{
+		collection: "users",
+		indexes: []mongo.IndexModel{
+			// Calendar feeds are looked up by the hash of their secret token
+			{
+				Keys: bson.D{{Key: "calendar_token_hash", Value: 1}},
+				Options: options.Index().SetUnique(true).
+					SetPartialFilterExpression(bson.M{"calendar_token_hash": bson.M{"$type": "string"}}),
+			},
+		},
+	},
+	{
+		collection: "waitlist",
+		indexes: []mongo.IndexModel{
+			// Places are offered to the longest waiting entry first
+			{Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: 1}}},
+			{Keys: bson.D{{Key: "status", Value: 1}, {Key: "hold_expires_at", Value: 1}}},
+			{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		},
+	},
+	{
+		collection: "payment_events",
+		indexes: []mongo.IndexModel{
+			{Keys: bson.D{{Key: "booking_id", Value: 1}, {Key: "created_at", Value: 1}}},
+		},
+	},
+	{
+		collection: "wallet_transactions",
+		indexes: []mongo.IndexModel{
+			// Statements and balances, and the transactions of one purchase
+			{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}},
+			{
+				Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "reference", Value: 1}},
+				Options: options.Index().SetPartialFilterExpression(bson.M{"reference": bson.M{"$type": "string"}}),
+			},
+			// Promotional credit the expiry job looks at
+			{Keys: bson.D{{Key: "entries.expires_at", Value: 1}}, Options: options.Index().SetSparse(true)},
+		},
+	},
+	{
+		collection: middleware.IdempotencyCollection,
+		indexes: []mongo.IndexModel{
+			// Stored responses are removed once they pass expires_at
+			{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+	},
+}
+
+// obsoleteIndexes names indexes created by older versions that are dropped before the required
+// ones are created. Bookings no longer store scheduled_date, so every booking of a series would
+// collide on the old unique index, and the old SKU index on menu_items also covered items
+// without a SKU.
+var obsoleteIndexes = []struct {
+	collection string
+	name       string
+}{
+	{"bookings", "series_id_1_scheduled_date_1"},
+	{"bookings", "technician_id_1_scheduled_date_1"},
+	{"menu_items", "sku_1"},
+}
+
+// EnsureIndexes drops obsolete indexes and creates the indexes the MongoDB handlers rely on
+func EnsureIndexes() error {
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		return fmt.Errorf("database not available: MongoDB connection is not established")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	for _, entry := range obsoleteIndexes {
+		if _, err := mongoDB.Collection(entry.collection).Indexes().DropOne(ctx, entry.name); err != nil && !indexNotFound(err) {
+			return fmt.Errorf("failed to drop index %s on %s: %w", entry.name, entry.collection, err)
+		}
+	}
+
+	for _, entry := range requiredIndexes {
+		if _, err := mongoDB.Collection(entry.collection).Indexes().CreateMany(ctx, entry.indexes); err != nil {
+			return fmt.Errorf("failed to create indexes on %s: %w", entry.collection, err)
+		}
+	}
+
+	return nil
+}
+
+// indexNotFound reports whether dropping an index failed because it, or its collection, does not
+// exist
+func indexNotFound(err error) bool {
+	var cmdErr mongo.CommandError
+	return errors.As(err, &cmdErr) && (cmdErr.Code == 26 || cmdErr.Code == 27)
+}
@@ -0,0 +1,222 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/code-harsh006/food-delivery/internal/models"
+	"github.com/code-harsh006/food-delivery/pkg/db"
+	"github.com/code-harsh006/food-delivery/pkg/money"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// pricingMoneyFields are the amounts of a stored price breakdown
+var pricingMoneyFields = []string{
+	"pricing.subtotal", "pricing.modifiers", "pricing.delivery_fee", "pricing.service_fee",
+	"pricing.discount", "pricing.tax", "pricing.tip", "pricing.total",
+	"pricing.lines[].unit_price", "pricing.lines[].modifiers", "pricing.lines[].total",
+	"pricing.discounts[].amount",
+}
+
+// moneyFields lists the amounts of each collection that used to be stored as plain numbers.
+// A "[]" suffix steps into every element of an array.
+var moneyFields = []struct {
+	collection string
+	fields     []string
+}{
+	{"services", []string{"base_price", "add_ons[].price"}},
+	{"menu_items", []string{"price", "modifier_groups[].options[].price"}},
+	{"carts", []string{"items[].unit_price"}},
+	{"orders", append([]string{"total", "items[].unit_price", "items[].line_total"}, pricingMoneyFields...)},
+	{"bookings", append([]string{
+		"total_amount", "items[].price", "items[].add_ons[].price",
+		"cancellation.fee", "cancellation.refund_amount",
+		"payment.amount", "payment.captured", "payment.refunded",
+	}, pricingMoneyFields...)},
+	{"promotions", []string{"amount_off", "max_discount", "min_spend"}},
+	{"promotion_redemptions", []string{"discount"}},
+	{"outbox", []string{"payment.amount"}},
+}
+
+// BackfillMoney rewrites amounts stored as decimal numbers by older versions into integer minor
+// units of the configured currency (admin only). Each document is only changed when it has not
+// been written since it was read; running it again converts whatever is left.
+func BackfillMoney(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	ctx := context.Background()
+	currency := money.Currency()
+	collections := gin.H{}
+	converted, failed := 0, 0
+
+	// Fixed promotions used to keep their amount in value; move it to amount_off, which is
+	// converted with the other amounts below
+	if _, err := mongoDB.Collection("promotions").UpdateMany(ctx,
+		bson.M{"type": models.PromotionTypeFixed, "amount_off": bson.M{"$exists": false}, "value": bson.M{"$gt": 0}},
+		bson.A{bson.M{"$set": bson.M{"amount_off": "$value", "value": 0}}},
+	); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update promotions"})
+		return
+	}
+
+	for _, entry := range moneyFields {
+		collection := mongoDB.Collection(entry.collection)
+
+		// Only the top-level fields holding amounts are read and written back
+		projection := bson.M{}
+		for _, field := range entry.fields {
+			projection[moneyFieldRoot(field)] = 1
+		}
+		cursor, err := collection.Find(ctx, bson.M{}, options.Find().SetProjection(projection))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch " + entry.collection})
+			return
+		}
+
+		done, skipped := 0, 0
+		for cursor.Next(ctx) {
+			// The document is converted in place; the original is kept to match it on
+			var doc, original bson.D
+			if err := cursor.Decode(&doc); err != nil {
+				skipped++
+				continue
+			}
+			if err := bson.Unmarshal(cursor.Current, &original); err != nil {
+				skipped++
+				continue
+			}
+
+			filter := bson.D{}
+			set := bson.D{}
+			for i, element := range doc {
+				if element.Key == "_id" {
+					filter = append(filter, element)
+					continue
+				}
+				value, changed := convertMoneyFields(element.Value, entry.fields, element.Key, currency)
+				if changed {
+					filter = append(filter, original[i])
+					set = append(set, bson.E{Key: element.Key, Value: value})
+				}
+			}
+			if len(set) == 0 {
+				continue
+			}
+
+			result, err := collection.UpdateOne(ctx, filter, bson.M{"$set": set})
+			if err != nil || result.MatchedCount == 0 {
+				skipped++
+				continue
+			}
+			done++
+		}
+		cursor.Close(ctx)
+
+		collections[entry.collection] = gin.H{"converted": done, "failed": skipped}
+		converted += done
+		failed += skipped
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Money backfill completed",
+		"currency":    currency,
+		"converted":   converted,
+		"failed":      failed,
+		"collections": collections,
+	})
+}
+
+// moneyFieldRoot returns the top-level field of a field path, e.g. "pricing" for "pricing.tax"
+func moneyFieldRoot(field string) string {
+	root, _, _ := strings.Cut(field, ".")
+	return strings.TrimSuffix(root, "[]")
+}
+
+// convertMoneyFields converts the amounts below a top-level field that are still plain numbers
+// and reports whether any was converted
+func convertMoneyFields(value interface{}, fields []string, root, currency string) (interface{}, bool) {
+	changed := false
+	for _, field := range fields {
+		if moneyFieldRoot(field) != root {
+			continue
+		}
+		var ok bool
+		value, ok = convertMoneyPath(value, strings.Split(field, "."), currency)
+		changed = changed || ok
+	}
+	return value, changed
+}
+
+// convertMoneyPath converts the amount at path, whose first step names value itself
+func convertMoneyPath(value interface{}, path []string, currency string) (interface{}, bool) {
+	if !strings.HasSuffix(path[0], "[]") {
+		return convertMoneyChild(value, path[1:], currency)
+	}
+
+	items, ok := value.(primitive.A)
+	if !ok {
+		return value, false
+	}
+	changed := false
+	for i, item := range items {
+		var converted bool
+		items[i], converted = convertMoneyChild(item, path[1:], currency)
+		changed = changed || converted
+	}
+	return items, changed
+}
+
+// convertMoneyChild converts the amount at the rest of a path below a document, or the value
+// itself at the end of the path
+func convertMoneyChild(value interface{}, rest []string, currency string) (interface{}, bool) {
+	if len(rest) == 0 {
+		return legacyMoney(value, currency)
+	}
+
+	doc, ok := value.(primitive.D)
+	if !ok {
+		return value, false
+	}
+	changed := false
+	for i, element := range doc {
+		if element.Key != strings.TrimSuffix(rest[0], "[]") {
+			continue
+		}
+		var converted bool
+		doc[i].Value, converted = convertMoneyPath(element.Value, rest, currency)
+		changed = changed || converted
+	}
+	return doc, changed
+}
+
+// legacyMoney converts an amount stored as a number of major units
+func legacyMoney(value interface{}, currency string) (interface{}, bool) {
+	switch amount := value.(type) {
+	case float64:
+		return money.FromFloat(amount, currency), true
+	case int32:
+		return money.FromFloat(float64(amount), currency), true
+	case int64:
+		return money.FromFloat(float64(amount), currency), true
+	case primitive.Decimal128:
+		parsed, err := strconv.ParseFloat(amount.String(), 64)
+		if err != nil {
+			return value, false
+		}
+		return money.FromFloat(parsed, currency), true
+	}
+	return value, false
+}
@@ -9,13 +9,16 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/code-harsh006/food-delivery/internal/cart"
 	"github.com/code-harsh006/food-delivery/internal/models"
 	"github.com/code-harsh006/food-delivery/pkg/db"
+	"github.com/code-harsh006/food-delivery/pkg/logger"
 	"github.com/code-harsh006/food-delivery/pkg/middleware"
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
 )
 
 // GetServices returns all active services
@@ -38,7 +41,22 @@ func GetServices(c *gin.Context) {
 	collection := mongoDB.Collection("services")
 	filter := bson.M{"is_active": true}
 	if category != "" {
-		filter["category"] = category
+		// Match the category and everything nested below it; unknown slugs fall back
+		// to the legacy free-text category field
+		categoryIDs, found, err := resolveCategoryIDs(context.Background(), slugify(category))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve category"})
+			return
+		}
+		if found {
+			filter["category_id"] = bson.M{"$in": categoryIDs}
+		} else {
+			filter["category"] = category
+		}
+	}
+	if err := applyDietaryFilter(c, filter); err != nil {
+		respondDietaryFilterError(c, err)
+		return
 	}
 
 	cursor, err := collection.Find(context.Background(), filter)
@@ -59,7 +77,7 @@ func GetServices(c *gin.Context) {
 	})
 }
 
-// GetServiceByID returns a specific service by ID
+// GetServiceByID returns a specific service by ID with a page of its reviews (page, limit)
 func GetServiceByID(c *gin.Context) {
 	// Check if MongoDB is connected
 	mongoDB := db.GetMongoDB()
@@ -90,10 +108,19 @@ func GetServiceByID(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"service": service})
+	reviews, err := serviceReviews(context.Background(), c, service.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reviews"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"service": service,
+		"reviews": reviews,
+	})
 }
 
-// GetServiceCategories returns all service categories
+// GetServiceCategories returns the managed category tree with active service counts
 func GetServiceCategories(c *gin.Context) {
 	// Check if MongoDB is connected
 	mongoDB := db.GetMongoDB()
@@ -105,34 +132,12 @@ func GetServiceCategories(c *gin.Context) {
 		return
 	}
 
-	collection := mongoDB.Collection("services")
-
-	// Use aggregation to get distinct categories
-	pipeline := []bson.M{
-		{"$match": bson.M{"is_active": true}},
-		{"$group": bson.M{"_id": "$category"}},
-		{"$project": bson.M{"category": "$_id", "_id": 0}},
-	}
-
-	cursor, err := collection.Aggregate(context.Background(), pipeline)
+	includeInactive := c.Query("include_inactive") == "true"
+	categories, err := loadCategoryTree(context.Background(), includeInactive)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch categories"})
 		return
 	}
-	defer cursor.Close(context.Background())
-
-	var results []bson.M
-	if err = cursor.All(context.Background(), &results); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode categories"})
-		return
-	}
-
-	var categories []string
-	for _, result := range results {
-		if category, ok := result["category"].(string); ok {
-			categories = append(categories, category)
-		}
-	}
 
 	c.JSON(http.StatusOK, gin.H{"categories": categories})
 }
@@ -166,6 +171,10 @@ func SearchServices(c *gin.Context) {
 			{"description": bson.M{"$regex": query, "$options": "i"}},
 		},
 	}
+	if err := applyDietaryFilter(c, filter); err != nil {
+		respondDietaryFilterError(c, err)
+		return
+	}
 
 	cursor, err := collection.Find(context.Background(), filter)
 	if err != nil {
@@ -337,6 +346,15 @@ func VerifyOTP(c *gin.Context) {
 		userCollection.UpdateOne(context.Background(), bson.M{"_id": user.ID}, bson.M{"$set": bson.M{"is_verified": true}})
 	}
 
+	// Carry over anything added to the cart before logging in
+	cartToken := req.CartToken
+	if cartToken == "" {
+		cartToken = c.GetHeader(cart.TokenHeader)
+	}
+	if err := cart.MergeAnonymousCart(context.Background(), cartToken, user.ID); err != nil {
+		logger.Error("Failed to merge anonymous cart", zap.Error(err))
+	}
+
 	// Generate JWT token
 	token, err := middleware.GenerateToken(0, user.Email, "user") // Using 0 as placeholder for userID
 	if err != nil {
@@ -0,0 +1,491 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/code-harsh006/food-delivery/internal/models"
+	"github.com/code-harsh006/food-delivery/internal/outbox"
+	"github.com/code-harsh006/food-delivery/internal/payment"
+	"github.com/code-harsh006/food-delivery/internal/wallet"
+	"github.com/code-harsh006/food-delivery/pkg/config"
+	"github.com/code-harsh006/food-delivery/pkg/db"
+	"github.com/code-harsh006/food-delivery/pkg/logger"
+	"github.com/code-harsh006/food-delivery/pkg/money"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+)
+
+// maxWebhookBytes caps the size of a webhook payload
+const maxWebhookBytes = 1 << 20
+
+// CreateBookingPayment starts paying for a booking: it creates a payment intent for the booking's
+// total with the configured provider and returns the client secret the app completes the payment
+// with. Asking again while the booking is unpaid returns the same intent. With the wallet method
+// the total is held in the customer's wallet instead and the booking is confirmed at once.
+func CreateBookingPayment(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	bookingID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking ID"})
+		return
+	}
+
+	var req models.CreateBookingPaymentRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.Method != "" && req.Method != models.PaymentMethodCard && req.Method != models.PaymentMethodWallet {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Payment method must be card or wallet"})
+		return
+	}
+
+	userID := getUserIDFromContext(c)
+	if userID.IsZero() {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	var booking models.Booking
+	err = mongoDB.Collection("bookings").FindOne(context.Background(), bson.M{"_id": bookingID, "user_id": userID}).Decode(&booking)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	if booking.Status != models.BookingStatusPending && booking.Status != models.BookingStatusConfirmed {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only pending or confirmed bookings can be paid", "status": booking.Status})
+		return
+	}
+	if booking.PaymentStatus != models.PaymentStatusPending && booking.PaymentStatus != models.PaymentStatusFailed {
+		c.JSON(http.StatusConflict, gin.H{"error": "Booking has already been paid", "payment_status": booking.PaymentStatus})
+		return
+	}
+	if !booking.TotalAmount.IsPositive() {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Booking has nothing to pay"})
+		return
+	}
+
+	if req.Method == models.PaymentMethodWallet {
+		// A card payment the customer may still complete is not replaced
+		if booking.Payment != nil && booking.PaymentStatus == models.PaymentStatusPending {
+			c.JSON(http.StatusConflict, gin.H{"error": "A card payment has already been started for this booking"})
+			return
+		}
+
+		var paid *models.Booking
+		err := db.WithTransaction(context.Background(), func(ctx context.Context) error {
+			var err error
+			paid, err = payBookingFromWallet(ctx, booking.ID, userID)
+			return err
+		})
+		if err != nil {
+			switch {
+			case errors.Is(err, wallet.ErrInsufficientFunds):
+				c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Wallet balance is too low", "amount": booking.TotalAmount})
+			case errors.Is(err, errBookingStatusConflict):
+				c.JSON(http.StatusConflict, gin.H{"error": "Booking has already been paid or cancelled"})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to pay from wallet"})
+			}
+			return
+		}
+		outbox.Wake()
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Booking paid from wallet",
+			"payment": gin.H{
+				"provider": wallet.Provider,
+				"amount":   paid.TotalAmount,
+				"currency": paid.TotalAmount.Currency,
+				"status":   paid.PaymentStatus,
+			},
+			"booking": paid,
+		})
+		return
+	}
+
+	provider, err := payment.Default()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Payments are not available"})
+		return
+	}
+
+	cfg := config.Load()
+	intent, err := provider.CreateIntent(context.Background(), payment.IntentRequest{
+		Amount:         booking.TotalAmount,
+		Reference:      booking.ID.Hex(),
+		Description:    "Booking " + booking.ID.Hex(),
+		IdempotencyKey: "booking-" + booking.ID.Hex(),
+	})
+	if err != nil {
+		logger.Error("Failed to create payment intent", zap.String("booking_id", booking.ID.Hex()), zap.Error(err))
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to start payment"})
+		return
+	}
+
+	now := time.Now()
+	result, err := mongoDB.Collection("bookings").UpdateOne(context.Background(),
+		bson.M{"_id": booking.ID, "payment_status": bson.M{"$in": []string{models.PaymentStatusPending, models.PaymentStatusFailed}}},
+		bson.M{"$set": bson.M{
+			"payment": models.BookingPayment{
+				Provider:  provider.Name(),
+				IntentID:  intent.ID,
+				Amount:    intent.Amount,
+				Captured:  money.New(0, intent.Amount.Currency),
+				Refunded:  money.New(0, intent.Amount.Currency),
+				UpdatedAt: now,
+			},
+			"updated_at": now,
+		}},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update booking"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Booking has already been paid"})
+		return
+	}
+
+	response := gin.H{
+		"provider":      provider.Name(),
+		"intent_id":     intent.ID,
+		"client_secret": intent.ClientSecret,
+		"amount":        intent.Amount,
+		"currency":      intent.Amount.Currency,
+		"status":        intent.Status,
+	}
+	if provider.Name() == payment.ProviderStripe {
+		response["publishable_key"] = cfg.StripePublishableKey
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Payment started successfully",
+		"payment": response,
+	})
+}
+
+// HandlePaymentWebhook applies a payment provider's webhook event to its booking. The provider
+// verifies the event; each event is applied once and a redelivery is acknowledged unchanged.
+func HandlePaymentWebhook(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	provider, err := payment.Get(c.Param("provider"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Payment provider not found"})
+		return
+	}
+
+	payload, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookBytes))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read webhook"})
+		return
+	}
+	event, err := provider.ParseWebhook(payload, c.Request.Header)
+	if err != nil {
+		if errors.Is(err, payment.ErrInvalidSignature) {
+			logger.Info("Rejected payment webhook", zap.String("provider", provider.Name()), zap.Error(err))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook signature"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Amounts are only ever charged in the configured currency, so anything else is not ours to apply
+	if !event.Amount.IsZero() && event.Amount.Currency != money.Currency() {
+		logger.Info("Rejected payment webhook in another currency", zap.String("event_id", event.ID), zap.String("currency", event.Amount.Currency))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Payment is not in " + money.Currency()})
+		return
+	}
+
+	applied, err := applyPaymentEvent(context.Background(), provider.Name(), *event)
+	if err != nil {
+		logger.Error("Failed to apply payment webhook", zap.String("event_id", event.ID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply webhook"})
+		return
+	}
+	outbox.Wake()
+
+	c.JSON(http.StatusOK, gin.H{"received": true, "applied": applied})
+}
+
+// applyPaymentEvent updates the booking an event is about and reports whether it changed
+// anything. The event is recorded in the same transaction, so it is only applied once.
+func applyPaymentEvent(ctx context.Context, providerName string, event payment.Event) (bool, error) {
+	if event.Type == "" {
+		return false, nil
+	}
+	eventID := providerName + ":" + event.ID
+
+	applied := false
+	err := db.WithTransaction(ctx, func(ctx context.Context) error {
+		mongoDB := db.GetMongoDB()
+		applied = false
+
+		seen, err := mongoDB.Collection("payment_events").CountDocuments(ctx, bson.M{"_id": eventID})
+		if err != nil || seen > 0 {
+			return err
+		}
+
+		filter := bson.M{"payment.provider": providerName, "payment.intent_id": event.IntentID}
+		if bookingID, err := primitive.ObjectIDFromHex(event.Reference); err == nil {
+			filter = bson.M{"_id": bookingID}
+		}
+		var booking models.Booking
+		if err := mongoDB.Collection("bookings").FindOne(ctx, filter).Decode(&booking); err != nil {
+			if err == mongo.ErrNoDocuments {
+				// Not one of ours, e.g. a payment taken outside the app
+				return nil
+			}
+			return err
+		}
+
+		if err := applyBookingPayment(ctx, booking, providerName, event); err != nil {
+			return err
+		}
+
+		_, err = mongoDB.Collection("payment_events").InsertOne(ctx, models.PaymentEvent{
+			ID:        eventID,
+			Type:      event.Type,
+			BookingID: booking.ID,
+			CreatedAt: time.Now(),
+		})
+		applied = err == nil
+		return err
+	})
+	return applied, err
+}
+
+// applyBookingPayment moves a booking's payment on for an event. An authorised or captured
+// payment confirms a pending booking; money that arrives for a booking that has been cancelled
+// meanwhile is released or refunded, and so is money that arrives on an intent the booking is no
+// longer paid with. Run it inside a transaction.
+func applyBookingPayment(ctx context.Context, booking models.Booking, providerName string, event payment.Event) error {
+	if booking.Payment != nil && (booking.Payment.Provider != providerName || booking.Payment.IntentID != event.IntentID) {
+		return returnStrayPayment(ctx, booking, providerName, event)
+	}
+
+	collection := db.GetMongoDB().Collection("bookings")
+	now := time.Now()
+	unpaid := booking.PaymentStatus == models.PaymentStatusPending || booking.PaymentStatus == models.PaymentStatusFailed
+	cancelled := booking.Status == models.BookingStatusCancelled
+
+	switch event.Type {
+	case payment.EventAuthorized, payment.EventSucceeded:
+		set := bson.M{
+			"payment.provider":   providerName,
+			"payment.intent_id":  event.IntentID,
+			"payment.updated_at": now,
+			"updated_at":         now,
+		}
+		status := models.PaymentStatusAuthorized
+		action := models.PaymentAction{BookingID: booking.ID, Action: models.PaymentActionCancel}
+		if event.Type == payment.EventSucceeded {
+			status = models.PaymentStatusPaid
+			set["payment.captured"] = event.Amount
+			action = models.PaymentAction{BookingID: booking.ID, Action: models.PaymentActionRefund, Amount: event.Amount}
+		} else {
+			set["payment.amount"] = event.Amount
+		}
+
+		switch {
+		case !unpaid:
+			// A capture the app made itself, or an event that arrived after a later one
+			if booking.PaymentStatus != models.PaymentStatusAuthorized || status != models.PaymentStatusPaid {
+				return nil
+			}
+			set["payment_status"] = status
+			_, err := collection.UpdateOne(ctx, bson.M{"_id": booking.ID}, bson.M{"$set": set})
+			return err
+
+		case cancelled:
+			// Nothing is owed on a cancelled booking, so the money goes straight back
+			set["payment_status"] = models.PaymentStatusRefunded
+			if _, err := collection.UpdateOne(ctx, bson.M{"_id": booking.ID}, bson.M{"$set": set}); err != nil {
+				return err
+			}
+			return outbox.EnqueuePayment(ctx, action)
+		}
+
+		set["payment_status"] = status
+		if _, err := collection.UpdateOne(ctx, bson.M{"_id": booking.ID}, bson.M{"$set": set}); err != nil {
+			return err
+		}
+		if booking.Status == models.BookingStatusPending {
+			actor := bookingActor{role: actorSystem}
+			if _, err := transitionBooking(ctx, bson.M{"_id": booking.ID}, models.BookingStatusConfirmed, actor, "Payment received"); err != nil {
+				return err
+			}
+		}
+		return outbox.EnqueueNotification(ctx, models.Notification{
+			UserID:  booking.UserID,
+			Title:   "Payment Received",
+			Message: "We received your payment for your booking on " + localBookingTime(booking) + ", it is now confirmed",
+			Type:    "payment",
+		})
+
+	case payment.EventFailed:
+		if booking.PaymentStatus != models.PaymentStatusPending || cancelled {
+			return nil
+		}
+		if _, err := collection.UpdateOne(ctx, bson.M{"_id": booking.ID}, bson.M{
+			"$set": bson.M{"payment_status": models.PaymentStatusFailed, "updated_at": now},
+		}); err != nil {
+			return err
+		}
+		return outbox.EnqueueNotification(ctx, models.Notification{
+			UserID:  booking.UserID,
+			Title:   "Payment Failed",
+			Message: "Your payment for your booking on " + localBookingTime(booking) + " did not go through, please try again",
+			Type:    "payment",
+		})
+
+	case payment.EventCanceled:
+		// An authorisation that expires before the booking is finished has to be paid again
+		if booking.PaymentStatus != models.PaymentStatusAuthorized || cancelled {
+			return nil
+		}
+		if _, err := collection.UpdateOne(ctx, bson.M{"_id": booking.ID}, bson.M{
+			"$set": bson.M{"payment_status": models.PaymentStatusFailed, "updated_at": now},
+		}); err != nil {
+			return err
+		}
+		return outbox.EnqueueNotification(ctx, models.Notification{
+			UserID:  booking.UserID,
+			Title:   "Payment Expired",
+			Message: "The payment held for your booking on " + localBookingTime(booking) + " has expired, please pay again",
+			Type:    "payment",
+		})
+
+	case payment.EventRefunded:
+		if booking.Payment == nil {
+			return nil
+		}
+		set := bson.M{"payment.updated_at": now, "updated_at": now}
+		switch booking.PaymentStatus {
+		case models.PaymentStatusPaid, models.PaymentStatusPartiallyRefunded:
+			set["payment_status"] = models.PaymentStatusPartiallyRefunded
+			if event.Amount.Cmp(booking.Payment.Captured) >= 0 {
+				set["payment_status"] = models.PaymentStatusRefunded
+			}
+		}
+		_, err := collection.UpdateOne(ctx, bson.M{"_id": booking.ID}, bson.M{
+			"$max": bson.M{"payment.refunded.minor": event.Amount.Minor},
+			"$set": set,
+		})
+		return err
+	}
+	return nil
+}
+
+// returnStrayPayment gives back money authorised or captured on an intent a booking is not paid
+// with, e.g. a card payment that went through after the booking was paid from the wallet. Other
+// events about such an intent are ignored.
+func returnStrayPayment(ctx context.Context, booking models.Booking, providerName string, event payment.Event) error {
+	action := models.PaymentAction{BookingID: booking.ID, Provider: providerName, IntentID: event.IntentID}
+	switch event.Type {
+	case payment.EventAuthorized:
+		action.Action = models.PaymentActionCancel
+	case payment.EventSucceeded:
+		action.Action = models.PaymentActionRefund
+		action.Amount = event.Amount
+	default:
+		return nil
+	}
+	logger.Info("Returning payment on a superseded intent",
+		zap.String("booking_id", booking.ID.Hex()),
+		zap.String("provider", providerName),
+		zap.String("intent_id", event.IntentID),
+	)
+	return outbox.EnqueuePayment(ctx, action)
+}
+
+// paymentSettlement returns the provider call a booking's status change needs, if any. An
+// authorised payment is captured when the booking is completed or a no-show; when the booking is
+// cancelled its fee is captured and the rest released, and a captured payment gets back the
+// refund the cancellation policy allows.
+func paymentSettlement(before, after models.Booking) *models.PaymentAction {
+	if before.Payment == nil || before.Status == after.Status {
+		return nil
+	}
+
+	switch after.Status {
+	case models.BookingStatusCompleted, models.BookingStatusNoShow:
+		if before.PaymentStatus == models.PaymentStatusAuthorized {
+			return &models.PaymentAction{BookingID: after.ID, Action: models.PaymentActionCapture, Amount: before.Payment.Amount}
+		}
+	case models.BookingStatusCancelled:
+		if after.Cancellation == nil {
+			return nil
+		}
+		switch before.PaymentStatus {
+		case models.PaymentStatusAuthorized:
+			if after.Cancellation.Fee.IsPositive() {
+				return &models.PaymentAction{BookingID: after.ID, Action: models.PaymentActionCapture, Amount: after.Cancellation.Fee}
+			}
+			return &models.PaymentAction{BookingID: after.ID, Action: models.PaymentActionCancel}
+		case models.PaymentStatusPaid:
+			if after.Cancellation.RefundAmount.IsPositive() {
+				return &models.PaymentAction{BookingID: after.ID, Action: models.PaymentActionRefund, Amount: after.Cancellation.RefundAmount}
+			}
+		}
+	}
+	return nil
+}
+
+// settleBookingPayment carries out the payment action a booking's status change needs. A booking
+// paid from the wallet, and a card refund paid into the wallet, are settled in the transaction
+// that changes the booking; the provider is called through the outbox once it commits.
+func settleBookingPayment(ctx context.Context, booking models.Booking, action models.PaymentAction) error {
+	switch {
+	case booking.Payment != nil && booking.Payment.Provider == wallet.Provider:
+		return settleWalletPayment(ctx, booking, action)
+	case action.Action == models.PaymentActionRefund && booking.Cancellation != nil && booking.Cancellation.RefundTo == models.RefundToWallet:
+		return refundCardToWallet(ctx, booking, action)
+	}
+	return outbox.EnqueuePayment(ctx, action)
+}
+
+// refundsToWallet reports whether a cancellation's refund goes into the customer's wallet: always
+// for a booking paid from the wallet, and for money captured by card when the customer asks
+func refundsToWallet(booking models.Booking, cancellation *models.BookingCancellation, refundTo string) bool {
+	if booking.Payment == nil || !cancellation.RefundAmount.IsPositive() {
+		return false
+	}
+	if booking.Payment.Provider == wallet.Provider {
+		return booking.PaymentStatus == models.PaymentStatusAuthorized || booking.PaymentStatus == models.PaymentStatusPaid
+	}
+	return refundTo == models.RefundToWallet && booking.PaymentStatus == models.PaymentStatusPaid
+}
@@ -0,0 +1,308 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/code-harsh006/food-delivery/internal/models"
+	"github.com/code-harsh006/food-delivery/internal/promotion"
+	"github.com/code-harsh006/food-delivery/pkg/db"
+	"github.com/code-harsh006/food-delivery/pkg/money"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CreatePromotion creates a coupon code (admin only)
+func CreatePromotion(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	var req models.CreatePromotionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	promo := models.Promotion{
+		Code:           promotion.NormalizeCode(req.Code),
+		Description:    req.Description,
+		Type:           req.Type,
+		Value:          req.Value,
+		AmountOff:      req.AmountOff,
+		MaxDiscount:    req.MaxDiscount,
+		AppliesTo:      req.AppliesTo,
+		BuyQuantity:    req.BuyQuantity,
+		GetQuantity:    req.GetQuantity,
+		MinSpend:       req.MinSpend,
+		UsageLimit:     req.UsageLimit,
+		PerUserLimit:   req.PerUserLimit,
+		FirstOrderOnly: req.FirstOrderOnly,
+		StartsAt:       req.StartsAt,
+		EndsAt:         req.EndsAt,
+		IsActive:       req.IsActive == nil || *req.IsActive,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	}
+	if promo.AppliesTo == "" {
+		promo.AppliesTo = models.PromotionAppliesToAll
+	}
+
+	switch {
+	case promo.Code == "":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Code is required"})
+		return
+	case promo.AppliesTo != models.PromotionAppliesToAll && promo.AppliesTo != models.PromotionAppliesToBooking && promo.AppliesTo != models.PromotionAppliesToOrder:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "applies_to must be all, booking or order"})
+		return
+	case promo.Type == models.PromotionTypePercentage && (promo.Value <= 0 || promo.Value > 100):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Percentage must be between 0 and 100"})
+		return
+	case promo.Type == models.PromotionTypeFixed && !promo.AmountOff.IsPositive():
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Amount must be greater than 0"})
+		return
+	case promo.UsageLimit < 0 || promo.PerUserLimit < 0 || promo.MinSpend.IsNegative():
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Limits and minimum spend cannot be negative"})
+		return
+	case promo.StartsAt != nil && promo.EndsAt != nil && !promo.EndsAt.After(*promo.StartsAt):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ends_at must be after starts_at"})
+		return
+	}
+
+	if promo.Type == models.PromotionTypeBuyXGetY {
+		menuItemID, err := primitive.ObjectIDFromHex(req.MenuItemID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid menu item ID"})
+			return
+		}
+		if promo.BuyQuantity <= 0 || promo.GetQuantity <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "buy_quantity and get_quantity must be greater than 0"})
+			return
+		}
+		promo.MenuItemID = menuItemID
+		promo.AppliesTo = models.PromotionAppliesToOrder
+	}
+	if promo.Type == models.PromotionTypeFreeDelivery {
+		promo.AppliesTo = models.PromotionAppliesToOrder
+	}
+
+	result, err := mongoDB.Collection("promotions").InsertOne(context.Background(), promo)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "A promotion with this code already exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create promotion"})
+		return
+	}
+
+	promo.ID = result.InsertedID.(primitive.ObjectID)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":   "Promotion created successfully",
+		"promotion": promo,
+	})
+}
+
+// GetPromotions lists promotions with their redemption counts (admin only)
+func GetPromotions(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	filter := bson.M{}
+	if c.Query("active") == "true" {
+		filter["is_active"] = true
+	}
+
+	cursor, err := mongoDB.Collection("promotions").Find(context.Background(), filter,
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch promotions"})
+		return
+	}
+	defer cursor.Close(context.Background())
+
+	promotions := []models.Promotion{}
+	if err = cursor.All(context.Background(), &promotions); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode promotions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"promotions": promotions,
+		"total":      len(promotions),
+	})
+}
+
+// GetPromotion returns a promotion with its redemption statistics (admin only)
+func GetPromotion(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	promotionID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid promotion ID"})
+		return
+	}
+
+	var promo models.Promotion
+	err = mongoDB.Collection("promotions").FindOne(context.Background(), bson.M{"_id": promotionID}).Decode(&promo)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Promotion not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	cursor, err := mongoDB.Collection("promotion_redemptions").Aggregate(context.Background(), []bson.M{
+		{"$match": bson.M{"promotion_id": promotionID}},
+		{"$group": bson.M{
+			"_id":            "$reference_type",
+			"redemptions":    bson.M{"$sum": 1},
+			"total_discount": bson.M{"$sum": "$discount.minor"},
+			"users":          bson.M{"$addToSet": "$user_id"},
+		}},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch redemptions"})
+		return
+	}
+
+	var groups []struct {
+		Type          string               `bson:"_id"`
+		Redemptions   int                  `bson:"redemptions"`
+		TotalDiscount int64                `bson:"total_discount"`
+		Users         []primitive.ObjectID `bson:"users"`
+	}
+	if err := cursor.All(context.Background(), &groups); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode redemptions"})
+		return
+	}
+
+	byType := gin.H{}
+	users := make(map[primitive.ObjectID]bool)
+	totalDiscount := money.New(0, money.Currency())
+	for _, group := range groups {
+		byType[group.Type] = group.Redemptions
+		totalDiscount = totalDiscount.Add(money.New(group.TotalDiscount, money.Currency()))
+		for _, userID := range group.Users {
+			users[userID] = true
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"promotion": promo,
+		"redemptions": gin.H{
+			"count":          promo.RedemptionCount,
+			"remaining":      remainingRedemptions(promo),
+			"unique_users":   len(users),
+			"total_discount": totalDiscount,
+			"by_type":        byType,
+		},
+	})
+}
+
+// UpdatePromotion changes a promotion's limits, validity window or active flag (admin only)
+func UpdatePromotion(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	promotionID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid promotion ID"})
+		return
+	}
+
+	var req models.UpdatePromotionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updateData := bson.M{"updated_at": time.Now()}
+	if req.Description != nil {
+		updateData["description"] = *req.Description
+	}
+	if req.MinSpend != nil {
+		updateData["min_spend"] = *req.MinSpend
+	}
+	if req.UsageLimit != nil {
+		updateData["usage_limit"] = *req.UsageLimit
+	}
+	if req.PerUserLimit != nil {
+		updateData["per_user_limit"] = *req.PerUserLimit
+	}
+	if req.StartsAt != nil {
+		updateData["starts_at"] = *req.StartsAt
+	}
+	if req.EndsAt != nil {
+		updateData["ends_at"] = *req.EndsAt
+	}
+	if req.IsActive != nil {
+		updateData["is_active"] = *req.IsActive
+	}
+
+	var promo models.Promotion
+	err = mongoDB.Collection("promotions").FindOneAndUpdate(context.Background(),
+		bson.M{"_id": promotionID},
+		bson.M{"$set": updateData},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&promo)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Promotion not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update promotion"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Promotion updated successfully",
+		"promotion": promo,
+	})
+}
+
+// remainingRedemptions returns how many more times a promotion can be redeemed, or nil when unlimited
+func remainingRedemptions(promo models.Promotion) interface{} {
+	if promo.UsageLimit == 0 {
+		return nil
+	}
+	if promo.RedemptionCount >= promo.UsageLimit {
+		return 0
+	}
+	return promo.UsageLimit - promo.RedemptionCount
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/code-harsh006/food-delivery/internal/cart"
+	"github.com/code-harsh006/food-delivery/internal/models"
+	"github.com/code-harsh006/food-delivery/internal/pricing"
+	"github.com/code-harsh006/food-delivery/internal/promotion"
+	"github.com/code-harsh006/food-delivery/pkg/db"
+	"github.com/code-harsh006/food-delivery/pkg/money"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CreateQuote returns an itemised price for a service booking or for the user's cart
+func CreateQuote(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	var req models.QuoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var quote *models.PriceBreakdown
+	var err error
+	response := gin.H{}
+
+	switch req.Type {
+	case "booking":
+		_, bookedItems, ok := resolveBookingItems(c, req.ServiceID, req.AddOns, req.Items)
+		if !ok {
+			return
+		}
+
+		items := bookingItems(bookedItems)
+		response["duration"] = bookingDuration(bookedItems)
+		applied, ok := applyPromotion(c, req.PromoCode, getUserIDFromContext(c), promotion.KindBooking, items)
+		if !ok {
+			return
+		}
+		quote, err = bookingQuote(items, req.Region, req.Tip, req.TipPercent, applied)
+
+	case "order":
+		// The cart is the one the cart routes and placing the order use, including anonymous carts
+		userCart, ok := cart.Current(c)
+		if !ok {
+			return
+		}
+		userID := userCart.UserID
+		view, priceErr := cart.Price(context.Background(), userCart)
+		if priceErr != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to price cart"})
+			return
+		}
+
+		items := cart.PricingItems(view)
+		applied, ok := applyPromotion(c, req.PromoCode, userID, promotion.KindOrder, items)
+		if !ok {
+			return
+		}
+		quote, err = pricing.Quote(pricing.Request{
+			Items:      items,
+			Delivery:   &pricing.Delivery{DistanceKM: req.DistanceKM, Zone: req.Zone},
+			Region:     req.Region,
+			Tip:        req.Tip,
+			TipPercent: req.TipPercent,
+			Discounts:  promotionDiscounts(applied),
+		})
+	}
+
+	if err != nil {
+		if errors.Is(err, pricing.ErrDeliveryOutOfRange) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response["quote"] = quote
+	c.JSON(http.StatusOK, response)
+}
+
+// bookingItems prices a booking with one line for each service and one for each add-on, named
+// after the service it was chosen with
+func bookingItems(items []models.BookingItem) []pricing.Item {
+	lines := make([]pricing.Item, 0, len(items))
+	for _, item := range items {
+		lines = append(lines, pricing.Item{Name: item.Name, Quantity: 1, UnitPrice: item.Price})
+		for _, addOn := range item.AddOns {
+			lines = append(lines, pricing.Item{Name: item.Name + ": " + addOn.Name, Quantity: 1, UnitPrice: addOn.Price})
+		}
+	}
+	return lines
+}
+
+// bookingQuote prices a service booking; services are performed on site, so there is no delivery fee
+func bookingQuote(items []pricing.Item, region string, tip money.Money, tipPercent float64, applied *promotion.Applied) (*models.PriceBreakdown, error) {
+	return pricing.Quote(pricing.Request{
+		Items:      items,
+		Region:     region,
+		Tip:        tip,
+		TipPercent: tipPercent,
+		Discounts:  promotionDiscounts(applied),
+	})
+}
+
+// applyPromotion validates an optional promotion code, writing the error response when it cannot be used
+func applyPromotion(c *gin.Context, code string, userID primitive.ObjectID, kind string, items []pricing.Item) (*promotion.Applied, bool) {
+	if code == "" {
+		return nil, true
+	}
+
+	applied, err := promotion.Apply(context.Background(), code, userID, kind, items)
+	if err != nil {
+		if promotion.Rejected(err) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "promo_code": code})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check promotion code"})
+		}
+		return nil, false
+	}
+	return applied, true
+}
+
+// promotionDiscounts returns the pricing discounts for an optional applied promotion
+func promotionDiscounts(applied *promotion.Applied) []pricing.Discount {
+	if applied == nil {
+		return nil
+	}
+	return []pricing.Discount{applied.Discount}
+}
+
+// promotionCode returns the code of an optional applied promotion
+func promotionCode(applied *promotion.Applied) string {
+	if applied == nil {
+		return ""
+	}
+	return applied.Promotion.Code
+}
@@ -0,0 +1,348 @@
+package services
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/code-harsh006/food-delivery/internal/models"
+	"github.com/code-harsh006/food-delivery/pkg/config"
+	"github.com/code-harsh006/food-delivery/pkg/db"
+	"github.com/code-harsh006/food-delivery/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+// GetServiceRecommendations returns services frequently booked together with the given service
+func GetServiceRecommendations(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	serviceID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service ID"})
+		return
+	}
+
+	var precomputed models.ServiceRecommendations
+	err = mongoDB.Collection("service_recommendations").FindOne(context.Background(), bson.M{"_id": serviceID}).Decode(&precomputed)
+	if err != nil && err != mongo.ErrNoDocuments {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch recommendations"})
+		return
+	}
+
+	recommendations, err := hydrateRecommendations(context.Background(), precomputed.Recommendations)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch recommended services"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"service_id":      serviceID,
+		"recommendations": recommendations,
+		"total":           len(recommendations),
+		"computed_at":     nullableTime(precomputed.ComputedAt),
+	})
+}
+
+// GetUserRecommendations returns personalised recommendations for the current user
+func GetUserRecommendations(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	userID := getUserIDFromContext(c)
+	if userID.IsZero() {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	var precomputed models.UserRecommendations
+	err := mongoDB.Collection("user_recommendations").FindOne(context.Background(), bson.M{"_id": userID}).Decode(&precomputed)
+	if err != nil && err != mongo.ErrNoDocuments {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch recommendations"})
+		return
+	}
+
+	recommendations, err := hydrateRecommendations(context.Background(), precomputed.Recommendations)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch recommended services"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"recommendations": recommendations,
+		"total":           len(recommendations),
+		"computed_at":     nullableTime(precomputed.ComputedAt),
+	})
+}
+
+// hydrateRecommendations loads the active services behind precomputed suggestions, keeping their order
+func hydrateRecommendations(ctx context.Context, suggestions []models.RecommendedService) ([]gin.H, error) {
+	results := []gin.H{}
+	if len(suggestions) == 0 {
+		return results, nil
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(suggestions))
+	for _, suggestion := range suggestions {
+		ids = append(ids, suggestion.ServiceID)
+	}
+
+	var services []models.Service
+	cursor, err := db.GetMongoDB().Collection("services").Find(ctx, bson.M{"_id": bson.M{"$in": ids}, "is_active": true})
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &services); err != nil {
+		return nil, err
+	}
+
+	byID := make(map[primitive.ObjectID]models.Service, len(services))
+	for _, service := range services {
+		byID[service.ID] = service
+	}
+
+	for _, suggestion := range suggestions {
+		service, ok := byID[suggestion.ServiceID]
+		if !ok {
+			continue
+		}
+		results = append(results, gin.H{
+			"service":     service,
+			"score":       suggestion.Score,
+			"co_bookings": suggestion.CoBookings,
+		})
+	}
+
+	return results, nil
+}
+
+// StartRecommendationRefresher recomputes the recommendation collections now and then on every interval
+func StartRecommendationRefresher(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := RefreshRecommendations(ctx); err != nil {
+				logger.Error("Failed to refresh recommendations", zap.Error(err))
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// RefreshRecommendations computes service co-occurrence from bookings made by the same users over
+// the configured rolling window and stores the results for the recommendation endpoints
+func RefreshRecommendations(ctx context.Context) error {
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		return nil
+	}
+
+	cfg := config.Load()
+	windowDays := cfg.RecommendationWindowDays
+	limit := cfg.RecommendationLimit
+	// Mongo stores milliseconds, so truncate to keep the stale-document cleanup exact
+	startedAt := time.Now().Truncate(time.Millisecond)
+
+	// One document per user with the distinct services they booked in the window, leaving out
+	// bookings that were cancelled or missed. Every item of a booking counts; bookings made before
+	// items existed only have service_id.
+	cursor, err := mongoDB.Collection("bookings").Aggregate(ctx, []bson.M{
+		{"$match": bson.M{
+			"created_at": bson.M{"$gte": startedAt.AddDate(0, 0, -windowDays)},
+			"status":     bson.M{"$nin": bson.A{models.BookingStatusCancelled, models.BookingStatusNoShow}},
+		}},
+		{"$unwind": bson.M{"path": "$items", "preserveNullAndEmptyArrays": true}},
+		{"$group": bson.M{
+			"_id":      "$user_id",
+			"services": bson.M{"$addToSet": bson.M{"$ifNull": bson.A{"$items.service_id", "$service_id"}}},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	var baskets []struct {
+		UserID   primitive.ObjectID   `bson:"_id"`
+		Services []primitive.ObjectID `bson:"services"`
+	}
+	if err := cursor.All(ctx, &baskets); err != nil {
+		return err
+	}
+
+	// Count how many users booked each service and each pair of services
+	bookedBy := make(map[primitive.ObjectID]int)
+	pairs := make(map[primitive.ObjectID]map[primitive.ObjectID]int)
+	for _, basket := range baskets {
+		for i, a := range basket.Services {
+			bookedBy[a]++
+			for _, b := range basket.Services[i+1:] {
+				incrementPair(pairs, a, b)
+				incrementPair(pairs, b, a)
+			}
+		}
+	}
+
+	// Cosine similarity keeps very popular services from dominating every list
+	serviceRecs := make(map[primitive.ObjectID][]models.RecommendedService, len(pairs))
+	for serviceID, related := range pairs {
+		recs := make([]models.RecommendedService, 0, len(related))
+		for otherID, together := range related {
+			score := float64(together) / math.Sqrt(float64(bookedBy[serviceID]*bookedBy[otherID]))
+			recs = append(recs, models.RecommendedService{
+				ServiceID:  otherID,
+				Score:      math.Round(score*10000) / 10000,
+				CoBookings: together,
+			})
+		}
+		serviceRecs[serviceID] = topRecommendations(recs, limit)
+	}
+
+	serviceWrites := make([]mongo.WriteModel, 0, len(serviceRecs))
+	for serviceID, recs := range serviceRecs {
+		serviceWrites = append(serviceWrites, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"_id": serviceID}).
+			SetReplacement(models.ServiceRecommendations{
+				ServiceID:       serviceID,
+				Recommendations: recs,
+				WindowDays:      windowDays,
+				ComputedAt:      startedAt,
+			}).
+			SetUpsert(true))
+	}
+
+	// Personalised lists sum the scores of everything related to what the user already booked
+	userWrites := make([]mongo.WriteModel, 0, len(baskets))
+	for _, basket := range baskets {
+		booked := make(map[primitive.ObjectID]bool, len(basket.Services))
+		for _, serviceID := range basket.Services {
+			booked[serviceID] = true
+		}
+
+		combined := make(map[primitive.ObjectID]*models.RecommendedService)
+		for _, serviceID := range basket.Services {
+			for _, rec := range serviceRecs[serviceID] {
+				if booked[rec.ServiceID] {
+					continue
+				}
+				entry, ok := combined[rec.ServiceID]
+				if !ok {
+					entry = &models.RecommendedService{ServiceID: rec.ServiceID}
+					combined[rec.ServiceID] = entry
+				}
+				entry.Score += rec.Score
+				entry.CoBookings += rec.CoBookings
+			}
+		}
+		if len(combined) == 0 {
+			continue
+		}
+
+		recs := make([]models.RecommendedService, 0, len(combined))
+		for _, entry := range combined {
+			entry.Score = math.Round(entry.Score*10000) / 10000
+			recs = append(recs, *entry)
+		}
+
+		userWrites = append(userWrites, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"_id": basket.UserID}).
+			SetReplacement(models.UserRecommendations{
+				UserID:          basket.UserID,
+				Recommendations: topRecommendations(recs, limit),
+				WindowDays:      windowDays,
+				ComputedAt:      startedAt,
+			}).
+			SetUpsert(true))
+	}
+
+	for collection, writes := range map[string][]mongo.WriteModel{
+		"service_recommendations": serviceWrites,
+		"user_recommendations":    userWrites,
+	} {
+		if len(writes) > 0 {
+			if _, err := mongoDB.Collection(collection).BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+				return err
+			}
+		}
+
+		// Anything not rewritten in this run has dropped out of the window
+		if _, err := mongoDB.Collection(collection).DeleteMany(ctx, bson.M{"computed_at": bson.M{"$lt": startedAt}}); err != nil {
+			return err
+		}
+	}
+
+	logger.Info("Recommendations refreshed",
+		zap.Int("services", len(serviceWrites)),
+		zap.Int("users", len(userWrites)),
+		zap.Duration("took", time.Since(startedAt)),
+	)
+
+	return nil
+}
+
+// incrementPair counts one more user who booked both a and b
+func incrementPair(pairs map[primitive.ObjectID]map[primitive.ObjectID]int, a, b primitive.ObjectID) {
+	related, ok := pairs[a]
+	if !ok {
+		related = make(map[primitive.ObjectID]int)
+		pairs[a] = related
+	}
+	related[b]++
+}
+
+// topRecommendations sorts suggestions by score and keeps the best limit entries
+func topRecommendations(recs []models.RecommendedService, limit int) []models.RecommendedService {
+	sort.Slice(recs, func(i, j int) bool {
+		if recs[i].Score != recs[j].Score {
+			return recs[i].Score > recs[j].Score
+		}
+		if recs[i].CoBookings != recs[j].CoBookings {
+			return recs[i].CoBookings > recs[j].CoBookings
+		}
+		return recs[i].ServiceID.Hex() < recs[j].ServiceID.Hex()
+	})
+
+	if limit > 0 && len(recs) > limit {
+		recs = recs[:limit]
+	}
+	return recs
+}
+
+// nullableTime returns nil for the zero time so it serialises as null
+func nullableTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
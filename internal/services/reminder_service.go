@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/code-harsh006/food-delivery/internal/models"
+	"github.com/code-harsh006/food-delivery/internal/outbox"
+	"github.com/code-harsh006/food-delivery/pkg/config"
+	"github.com/code-harsh006/food-delivery/pkg/db"
+	"github.com/code-harsh006/food-delivery/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.uber.org/zap"
+)
+
+// StartReminderScheduler sends the reminders of upcoming bookings that have come due, now and
+// then on every interval, until ctx is cancelled
+func StartReminderScheduler(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := SendReminders(ctx); err != nil {
+				logger.Error("Failed to send booking reminders", zap.Error(err))
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// SendReminders notifies customers, and the technicians assigned, of pending and confirmed
+// bookings whose reminder time has passed. Each reminder is recorded on the booking in the same
+// transaction that queues it, so however many servers run this only one sends it.
+func SendReminders(ctx context.Context) error {
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		return nil
+	}
+
+	cfg := config.Load()
+	longest := time.Duration(0)
+	for _, offset := range append(append([]time.Duration(nil), cfg.ReminderOffsets...), cfg.TechnicianReminderOffsets...) {
+		if offset > longest {
+			longest = offset
+		}
+	}
+	if longest == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	cursor, err := mongoDB.Collection("bookings").Find(ctx, bson.M{
+		"status":       bson.M{"$in": []string{models.BookingStatusPending, models.BookingStatusConfirmed}},
+		"scheduled_at": bson.M{"$gt": now, "$lte": now.Add(longest)},
+	})
+	if err != nil {
+		return err
+	}
+	var bookings []models.Booking
+	if err := cursor.All(ctx, &bookings); err != nil {
+		return err
+	}
+
+	services, err := bookingServices(ctx, bookings)
+	if err != nil {
+		return err
+	}
+
+	sent := false
+	for _, booking := range bookings {
+		reminded, err := remindBooking(ctx, booking, services[booking.ServiceID], now)
+		if err != nil {
+			logger.Error("Failed to send booking reminder", zap.String("booking_id", booking.ID.Hex()), zap.Error(err))
+			continue
+		}
+		sent = sent || reminded
+	}
+	if sent {
+		outbox.Wake()
+	}
+	return nil
+}
+
+// remindBooking queues the reminders of one booking that are due and not yet sent, and reports
+// whether it queued any. Only the reminder closest to the start is sent when several are due.
+func remindBooking(ctx context.Context, booking models.Booking, service models.Service, now time.Time) (bool, error) {
+	cfg := config.Load()
+	customer := unsent(dueReminders(cfg.ReminderOffsets, "customer", booking.ScheduledAt, now), booking.RemindersSent)
+	var technician []string
+	if !booking.TechnicianID.IsZero() {
+		prefix := "technician:" + booking.TechnicianID.Hex()
+		technician = unsent(dueReminders(cfg.TechnicianReminderOffsets, prefix, booking.ScheduledAt, now), booking.RemindersSent)
+	}
+	keys := append(append([]string(nil), customer...), technician...)
+	if len(keys) == 0 {
+		return false, nil
+	}
+
+	name := service.Name
+	if name == "" {
+		name = "service"
+	}
+	when := localBookingTime(booking)
+
+	sent := false
+	err := db.WithTransaction(ctx, func(ctx context.Context) error {
+		// The booking must still be at the same time, with the same technician, and none of these
+		// reminders may have been claimed by another server
+		filter := bson.M{
+			"_id":            booking.ID,
+			"status":         bson.M{"$in": []string{models.BookingStatusPending, models.BookingStatusConfirmed}},
+			"scheduled_at":   booking.ScheduledAt,
+			"reminders_sent": bson.M{"$nin": keys},
+		}
+		if len(technician) > 0 {
+			filter["technician_id"] = booking.TechnicianID
+		}
+		result, err := db.GetMongoDB().Collection("bookings").UpdateOne(ctx, filter,
+			bson.M{"$addToSet": bson.M{"reminders_sent": bson.M{"$each": keys}}},
+		)
+		if err != nil {
+			return err
+		}
+		sent = result.MatchedCount > 0
+		if !sent {
+			return nil
+		}
+
+		if len(customer) > 0 {
+			if err := outbox.EnqueueNotification(ctx, models.Notification{
+				UserID:  booking.UserID,
+				Title:   "Booking Reminder",
+				Message: "Reminder: your " + name + " booking is on " + when,
+				Type:    "reminder",
+			}); err != nil {
+				return err
+			}
+		}
+		if len(technician) > 0 {
+			return notifyTechnician(ctx, booking.TechnicianID, models.Notification{
+				Title:   "Upcoming Job",
+				Message: "Reminder: your " + name + " job is on " + when,
+				Type:    "reminder",
+			})
+		}
+		return nil
+	})
+	return sent, err
+}
+
+// skippedReminders lists the customer reminders that are already due for a booking starting at
+// start. They are recorded as sent when a booking is made or moved, so nobody is reminded of a
+// booking they have only just made.
+func skippedReminders(start time.Time) []string {
+	return dueReminders(config.Load().ReminderOffsets, "customer", start, time.Now())
+}
+
+// dueReminders returns the keys of the reminders whose time has passed for a booking starting at
+// start, e.g. "customer:60" for the reminder an hour before
+func dueReminders(offsets []time.Duration, prefix string, start, now time.Time) []string {
+	sorted := append([]time.Duration(nil), offsets...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	keys := make([]string, 0, len(sorted))
+	for _, offset := range sorted {
+		if !start.Add(-offset).After(now) {
+			keys = append(keys, fmt.Sprintf("%s:%d", prefix, int(offset/time.Minute)))
+		}
+	}
+	return keys
+}
+
+// unsent returns the keys that are not in sent
+func unsent(keys, sent []string) []string {
+	result := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if !containsString(sent, key) {
+			result = append(result, key)
+		}
+	}
+	return result
+}
@@ -0,0 +1,448 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/code-harsh006/food-delivery/internal/models"
+	"github.com/code-harsh006/food-delivery/internal/outbox"
+	"github.com/code-harsh006/food-delivery/pkg/config"
+	"github.com/code-harsh006/food-delivery/pkg/db"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CreateBookingReview rates a completed booking. Each booking can be reviewed once, within
+// REVIEW_WINDOW_DAYS of its completion.
+func CreateBookingReview(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	bookingID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking ID"})
+		return
+	}
+
+	userID := getUserIDFromContext(c)
+	if userID.IsZero() {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	var req models.CreateReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var booking models.Booking
+	err = mongoDB.Collection("bookings").FindOne(context.Background(), bson.M{"_id": bookingID, "user_id": userID}).Decode(&booking)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	if booking.Status != models.BookingStatusCompleted {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":  "Only completed bookings can be reviewed",
+			"status": booking.Status,
+		})
+		return
+	}
+
+	completedAt, err := bookingCompletedAt(context.Background(), booking)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	windowDays := config.Load().ReviewWindowDays
+	if windowDays > 0 && time.Since(completedAt) > time.Duration(windowDays)*24*time.Hour {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": fmt.Sprintf("Bookings can only be reviewed within %d days of completion", windowDays),
+		})
+		return
+	}
+
+	var service models.Service
+	if err := mongoDB.Collection("services").FindOne(context.Background(), bson.M{"_id": booking.ServiceID}).Decode(&service); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch service"})
+		return
+	}
+
+	review := models.Review{
+		BookingID: booking.ID,
+		UserID:    userID,
+		ServiceID: booking.ServiceID,
+		VendorID:  service.VendorID,
+		Rating:    req.Rating,
+		Comment:   strings.TrimSpace(req.Comment),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	err = db.WithTransaction(context.Background(), func(ctx context.Context) error {
+		result, err := mongoDB.Collection("reviews").InsertOne(ctx, review)
+		if err != nil {
+			return err
+		}
+		review.ID = result.InsertedID.(primitive.ObjectID)
+
+		if err := addRating(ctx, "services", service.ID, review.Rating); err != nil {
+			return err
+		}
+		if !service.VendorID.IsZero() {
+			if err := addRating(ctx, "vendors", service.VendorID, review.Rating); err != nil {
+				return err
+			}
+		}
+
+		return notifyVendor(ctx, service.VendorID, models.Notification{
+			Title:   "New Review",
+			Message: fmt.Sprintf("A customer rated %s %d out of 5", service.Name, review.Rating),
+			Type:    "review",
+		})
+	})
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "This booking has already been reviewed"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save review"})
+		return
+	}
+	outbox.Wake()
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Review added successfully",
+		"review":  review,
+	})
+}
+
+// GetVendorReviews lists the reviews of a vendor's services, newest first, optionally only
+// those without a reply (vendor)
+func GetVendorReviews(c *gin.Context) {
+	// Check if MongoDB is connected
+	if db.GetMongoDB() == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	filter := bson.M{"vendor_id": staffVendorScope(c)}
+	if c.Query("unreplied") == "true" {
+		filter["reply"] = bson.M{"$exists": false}
+	}
+
+	page, limit := reviewPage(c)
+	reviews, total, err := findReviews(context.Background(), filter, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reviews"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"reviews": reviews,
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
+	})
+}
+
+// ReplyToReview adds or replaces the vendor's reply to a review of one of its services (vendor)
+func ReplyToReview(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	reviewID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid review ID"})
+		return
+	}
+
+	var req models.ReplyToReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reply := models.ReviewReply{
+		Message:   strings.TrimSpace(req.Message),
+		RepliedBy: getUserIDFromContext(c),
+		RepliedAt: time.Now(),
+	}
+	if reply.Message == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Reply message is required"})
+		return
+	}
+
+	var review models.Review
+	err = db.WithTransaction(context.Background(), func(ctx context.Context) error {
+		err := mongoDB.Collection("reviews").FindOneAndUpdate(ctx,
+			bson.M{"_id": reviewID, "vendor_id": staffVendorScope(c)},
+			bson.M{"$set": bson.M{"reply": reply, "updated_at": reply.RepliedAt}},
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&review)
+		if err != nil {
+			return err
+		}
+
+		return outbox.EnqueueNotification(ctx, models.Notification{
+			UserID:  review.UserID,
+			Title:   "Reply to Your Review",
+			Message: "The provider has replied to your review: " + reply.Message,
+			Type:    "review",
+		})
+	})
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save reply"})
+		return
+	}
+	outbox.Wake()
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Reply saved successfully",
+		"review":  review,
+	})
+}
+
+// BackfillReviews moves the ratings stored on bookings before reviews had their own collection
+// into reviews (admin only). Ratings of completed bookings become reviews dated at the
+// booking's completion and count towards the averages; ratings left on bookings that were
+// never completed are discarded. Running it again only picks up what is left.
+func BackfillReviews(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	ctx := context.Background()
+	bookings := mongoDB.Collection("bookings")
+	cursor, err := bookings.Find(ctx, bson.M{"$or": []bson.M{
+		{"rating": bson.M{"$exists": true}},
+		{"review": bson.M{"$exists": true}},
+	}})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bookings"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	// The legacy fields are no longer part of models.Booking
+	type legacyBooking struct {
+		models.Booking `bson:",inline"`
+		Rating         int    `bson:"rating"`
+		Review         string `bson:"review"`
+	}
+
+	services := make(map[primitive.ObjectID]models.Service)
+	migrated, discarded, failed := 0, 0, 0
+	for cursor.Next(ctx) {
+		var legacy legacyBooking
+		if err := cursor.Decode(&legacy); err != nil {
+			failed++
+			continue
+		}
+		booking := legacy.Booking
+
+		outcome := ""
+		err := db.WithTransaction(ctx, func(ctx context.Context) error {
+			outcome = ""
+			if booking.Status == models.BookingStatusCompleted && legacy.Rating >= 1 && legacy.Rating <= 5 {
+				// A booking reviewed since keeps its newer review
+				existing, err := mongoDB.Collection("reviews").CountDocuments(ctx, bson.M{"booking_id": booking.ID})
+				if err != nil {
+					return err
+				}
+				if existing == 0 {
+					if err := migrateBookingRating(ctx, booking, legacy.Rating, legacy.Review, services); err != nil {
+						return err
+					}
+					outcome = "migrated"
+				}
+			} else if legacy.Rating != 0 || legacy.Review != "" {
+				outcome = "discarded"
+			}
+
+			_, err := bookings.UpdateOne(ctx, bson.M{"_id": booking.ID}, bson.M{"$unset": bson.M{"rating": "", "review": ""}})
+			return err
+		})
+		switch {
+		case err != nil:
+			failed++
+		case outcome == "migrated":
+			migrated++
+		case outcome == "discarded":
+			discarded++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Review backfill completed",
+		"migrated":  migrated,
+		"discarded": discarded,
+		"failed":    failed,
+	})
+}
+
+// migrateBookingRating turns a rating stored on a completed booking into a review dated at its
+// completion and adds it to the service and vendor averages
+func migrateBookingRating(ctx context.Context, booking models.Booking, rating int, comment string, services map[primitive.ObjectID]models.Service) error {
+	mongoDB := db.GetMongoDB()
+
+	service, ok := services[booking.ServiceID]
+	if !ok {
+		if err := mongoDB.Collection("services").FindOne(ctx, bson.M{"_id": booking.ServiceID}).Decode(&service); err != nil {
+			return err
+		}
+		services[booking.ServiceID] = service
+	}
+
+	completedAt, err := bookingCompletedAt(ctx, booking)
+	if err != nil {
+		return err
+	}
+	_, err = mongoDB.Collection("reviews").InsertOne(ctx, models.Review{
+		BookingID: booking.ID,
+		UserID:    booking.UserID,
+		ServiceID: booking.ServiceID,
+		VendorID:  service.VendorID,
+		Rating:    rating,
+		Comment:   strings.TrimSpace(comment),
+		CreatedAt: completedAt,
+		UpdatedAt: completedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := addRating(ctx, "services", service.ID, rating); err != nil {
+		return err
+	}
+	if !service.VendorID.IsZero() {
+		return addRating(ctx, "vendors", service.VendorID, rating)
+	}
+	return nil
+}
+
+// serviceReviews returns a page of a service's reviews, newest first, for the service detail
+func serviceReviews(ctx context.Context, c *gin.Context, serviceID primitive.ObjectID) (gin.H, error) {
+	page, limit := reviewPage(c)
+	reviews, total, err := findReviews(ctx, bson.M{"service_id": serviceID}, page, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return gin.H{
+		"items": reviews,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	}, nil
+}
+
+// findReviews returns one page of the reviews matching filter, newest first, and how many match
+func findReviews(ctx context.Context, filter bson.M, page, limit int) ([]models.Review, int64, error) {
+	collection := db.GetMongoDB().Collection("reviews")
+	total, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+	cursor, err := collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	reviews := []models.Review{}
+	if err := cursor.All(ctx, &reviews); err != nil {
+		return nil, 0, err
+	}
+	return reviews, total, nil
+}
+
+// reviewPage reads the page and limit query parameters of a review listing
+func reviewPage(c *gin.Context) (int, int) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 50 {
+		limit = 10
+	}
+	return page, limit
+}
+
+// addRating adds one rating to the summary of a service or vendor. The count and total are
+// incremented and the average recomputed from them in a single update, so concurrent reviews
+// never lose a rating.
+func addRating(ctx context.Context, collection string, id primitive.ObjectID, rating int) error {
+	_, err := db.GetMongoDB().Collection(collection).UpdateOne(ctx, bson.M{"_id": id}, mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"rating.count": bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$rating.count", 0}}, 1}},
+			"rating.total": bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$rating.total", 0}}, rating}},
+		}}},
+		{{Key: "$set", Value: bson.M{
+			"rating.average": bson.M{"$round": bson.A{bson.M{"$divide": bson.A{"$rating.total", "$rating.count"}}, 2}},
+		}}},
+	})
+	return err
+}
+
+// bookingCompletedAt returns when a booking was completed according to its status history,
+// falling back to its last update
+func bookingCompletedAt(ctx context.Context, booking models.Booking) (time.Time, error) {
+	var status models.BookingStatus
+	err := db.GetMongoDB().Collection("booking_statuses").FindOne(ctx,
+		bson.M{"booking_id": booking.ID, "status": models.BookingStatusCompleted},
+		options.FindOne().SetSort(bson.D{{Key: "created_at", Value: -1}}),
+	).Decode(&status)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return booking.UpdatedAt, nil
+		}
+		return time.Time{}, err
+	}
+	return status.CreatedAt, nil
+}
@@ -0,0 +1,659 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/code-harsh006/food-delivery/internal/availability"
+	"github.com/code-harsh006/food-delivery/internal/localtime"
+	"github.com/code-harsh006/food-delivery/internal/models"
+	"github.com/code-harsh006/food-delivery/internal/outbox"
+	"github.com/code-harsh006/food-delivery/internal/recurrence"
+	"github.com/code-harsh006/food-delivery/pkg/config"
+	"github.com/code-harsh006/food-delivery/pkg/db"
+	"github.com/code-harsh006/food-delivery/pkg/logger"
+	"github.com/code-harsh006/food-delivery/pkg/money"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+// CreateBookingSeries starts a recurring booking and books its first occurrences right away
+func CreateBookingSeries(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	var req models.CreateBookingSeriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := getUserIDFromContext(c)
+	if userID.IsZero() {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	serviceID, err := primitive.ObjectIDFromHex(req.ServiceID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service ID"})
+		return
+	}
+
+	startDate, err := time.Parse("2006-01-02", req.StartDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use YYYY-MM-DD"})
+		return
+	}
+	if _, err := availability.ParseClock(req.ScheduledTime); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule, err := seriesRule(req)
+	if err == nil {
+		rule, err = recurrence.Normalize(rule, startDate)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var service models.Service
+	err = mongoDB.Collection("services").FindOne(context.Background(), bson.M{"_id": serviceID, "is_active": true}).Decode(&service)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	timezone, err := localtime.Resolve(context.Background(), service, req.Region)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if startDate.Before(localtime.Today(localtime.Location(timezone))) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot book services for past dates"})
+		return
+	}
+
+	series := models.BookingSeries{
+		UserID:          userID,
+		ServiceID:       serviceID,
+		Rule:            rule,
+		RRule:           recurrence.Format(rule),
+		StartDate:       startDate,
+		ScheduledTime:   req.ScheduledTime,
+		Timezone:        timezone,
+		SpecialRequests: req.SpecialRequests,
+		Region:          req.Region,
+		Status:          models.SeriesStatusActive,
+		SkippedDates:    []string{},
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
+	}
+
+	result, err := mongoDB.Collection("booking_series").InsertOne(context.Background(), series)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create booking series"})
+		return
+	}
+	series.ID = result.InsertedID.(primitive.ObjectID)
+
+	if err := materializeSeries(context.Background(), &series, service); err != nil {
+		logger.Error("Failed to book series occurrences", zap.String("series_id", series.ID.Hex()), zap.Error(err))
+	}
+
+	bookings, err := seriesBookings(context.Background(), series.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch series bookings"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Booking series created successfully",
+		"series":   series,
+		"bookings": bookings,
+	})
+}
+
+// GetUserBookingSeries lists the user's recurring bookings
+func GetUserBookingSeries(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	userID := getUserIDFromContext(c)
+	if userID.IsZero() {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	cursor, err := mongoDB.Collection("booking_series").Find(context.Background(), bson.M{"user_id": userID},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch booking series"})
+		return
+	}
+	defer cursor.Close(context.Background())
+
+	series := []models.BookingSeries{}
+	if err = cursor.All(context.Background(), &series); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode booking series"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"series": series,
+		"total":  len(series),
+	})
+}
+
+// GetBookingSeriesByID returns a recurring booking with its booked occurrences and the dates
+// still to come
+func GetBookingSeriesByID(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	series, ok := loadUserSeries(c)
+	if !ok {
+		return
+	}
+
+	bookings, err := seriesBookings(context.Background(), series.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch series bookings"})
+		return
+	}
+
+	upcoming := []string{}
+	if series.Status == models.SeriesStatusActive {
+		from := localtime.Today(localtime.Location(series.Timezone))
+		if series.MaterializedTo != nil {
+			from = series.MaterializedTo.AddDate(0, 0, 1)
+		}
+		for _, date := range recurrence.Dates(series.Rule, series.StartDate, from.AddDate(0, 3, 0)) {
+			day := date.Format("2006-01-02")
+			if !date.Before(from) && !containsString(series.SkippedDates, day) {
+				upcoming = append(upcoming, day)
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"series":   series,
+		"bookings": bookings,
+		"upcoming": upcoming,
+	})
+}
+
+// SkipSeriesOccurrence skips one date of a recurring booking, cancelling its booking when it has
+// already been made. The cancellation policy applies as for any other cancellation.
+func SkipSeriesOccurrence(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	var req models.SkipOccurrenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	series, ok := loadUserSeries(c)
+	if !ok {
+		return
+	}
+	if series.Status == models.SeriesStatusCancelled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Booking series is cancelled"})
+		return
+	}
+
+	date, err := time.Parse("2006-01-02", req.Date)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use YYYY-MM-DD"})
+		return
+	}
+	if date.Before(localtime.Today(localtime.Location(series.Timezone))) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot skip past occurrences"})
+		return
+	}
+	if !isSeriesOccurrence(series, date) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The series has no occurrence on " + req.Date})
+		return
+	}
+
+	var cancelled *models.Booking
+	actor := bookingActor{role: actorCustomer, id: series.UserID}
+	err = db.WithTransaction(context.Background(), func(ctx context.Context) error {
+		cancelled = nil
+		if _, err := mongoDB.Collection("booking_series").UpdateOne(ctx,
+			bson.M{"_id": series.ID},
+			bson.M{"$addToSet": bson.M{"skipped_dates": req.Date}, "$set": bson.M{"updated_at": time.Now()}},
+		); err != nil {
+			return err
+		}
+
+		booking, err := transitionBooking(ctx,
+			bson.M{"series_id": series.ID, "scheduled_at": seriesDay(series, date), "status": bson.M{"$in": []string{models.BookingStatusPending, models.BookingStatusConfirmed}}},
+			models.BookingStatusCancelled, actor, "Occurrence skipped by user")
+		if err == errBookingNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		cancelled = booking
+
+		return outbox.EnqueueNotification(ctx, models.Notification{
+			UserID:  series.UserID,
+			Title:   "Booking Skipped",
+			Message: "Your recurring booking on " + req.Date + " has been skipped",
+			Type:    "booking",
+		})
+	})
+	if err != nil {
+		respondSeriesError(c, err)
+		return
+	}
+	outbox.Wake()
+
+	response := gin.H{
+		"message": "Occurrence skipped successfully",
+		"date":    req.Date,
+	}
+	if cancelled != nil {
+		response["booking"] = cancelled
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// CancelBookingSeries stops a recurring booking and cancels all of its upcoming bookings
+func CancelBookingSeries(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	series, ok := loadUserSeries(c)
+	if !ok {
+		return
+	}
+	if series.Status == models.SeriesStatusCancelled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Booking series is already cancelled"})
+		return
+	}
+
+	var cancelled []models.Booking
+	actor := bookingActor{role: actorCustomer, id: series.UserID}
+	err := db.WithTransaction(context.Background(), func(ctx context.Context) error {
+		cancelled = nil
+		result, err := mongoDB.Collection("booking_series").UpdateOne(ctx,
+			bson.M{"_id": series.ID, "status": bson.M{"$ne": models.SeriesStatusCancelled}},
+			bson.M{"$set": bson.M{"status": models.SeriesStatusCancelled, "updated_at": time.Now()}},
+		)
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return errBookingStatusConflict
+		}
+
+		// Bookings from the start of today in the series' timezone are still upcoming
+		loc := localtime.Location(series.Timezone)
+		cursor, err := mongoDB.Collection("bookings").Find(ctx, bson.M{
+			"series_id":    series.ID,
+			"scheduled_at": bson.M{"$gte": localtime.Midnight(localtime.Today(loc), loc)},
+			"status":       bson.M{"$in": []string{models.BookingStatusPending, models.BookingStatusConfirmed}},
+		})
+		if err != nil {
+			return err
+		}
+		var upcoming []models.Booking
+		if err := cursor.All(ctx, &upcoming); err != nil {
+			return err
+		}
+
+		for _, booking := range upcoming {
+			updated, err := transitionBooking(ctx, bson.M{"_id": booking.ID}, models.BookingStatusCancelled, actor, "Booking series cancelled by user")
+			if err != nil {
+				return err
+			}
+			cancelled = append(cancelled, *updated)
+		}
+
+		return outbox.EnqueueNotification(ctx, models.Notification{
+			UserID:  series.UserID,
+			Title:   "Booking Series Cancelled",
+			Message: "Your recurring booking has been cancelled",
+			Type:    "booking",
+		})
+	})
+	if err != nil {
+		respondSeriesError(c, err)
+		return
+	}
+	outbox.Wake()
+
+	if cancelled == nil {
+		cancelled = []models.Booking{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":            "Booking series cancelled successfully",
+		"cancelled_bookings": cancelled,
+	})
+}
+
+// StartSeriesScheduler books the occurrences of active recurring bookings that fall within the
+// booking horizon, now and then on every interval, until ctx is cancelled
+func StartSeriesScheduler(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := MaterializeSeries(ctx); err != nil {
+				logger.Error("Failed to book recurring occurrences", zap.Error(err))
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// MaterializeSeries books the upcoming occurrences of every active recurring booking
+func MaterializeSeries(ctx context.Context) error {
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		return nil
+	}
+
+	cursor, err := mongoDB.Collection("booking_series").Find(ctx, bson.M{"status": models.SeriesStatusActive})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var series models.BookingSeries
+		if err := cursor.Decode(&series); err != nil {
+			return err
+		}
+
+		var service models.Service
+		err := mongoDB.Collection("services").FindOne(ctx, bson.M{"_id": series.ServiceID, "is_active": true}).Decode(&service)
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				continue
+			}
+			return err
+		}
+
+		if err := materializeSeries(ctx, &series, service); err != nil {
+			logger.Error("Failed to book series occurrences", zap.String("series_id", series.ID.Hex()), zap.Error(err))
+		}
+	}
+
+	return cursor.Err()
+}
+
+// materializeSeries creates a booking for every occurrence of a series up to the horizon that has
+// not been processed yet. Skipped and past dates are passed over, and an occurrence whose slot is
+// taken is reported to the customer instead of being booked. Progress is saved after each date,
+// so a failure is retried from that date on the next run.
+func materializeSeries(ctx context.Context, series *models.BookingSeries, service models.Service) error {
+	mongoDB := db.GetMongoDB()
+	today := localtime.Today(localtime.Location(series.Timezone))
+	through := today.AddDate(0, 0, config.Load().SeriesHorizonDays)
+
+	dates := recurrence.Dates(series.Rule, series.StartDate, through)
+	for _, date := range dates {
+		if series.MaterializedTo != nil && !date.After(*series.MaterializedTo) {
+			continue
+		}
+
+		day := date.Format("2006-01-02")
+		booked := false
+		if !date.Before(today) && !containsString(series.SkippedDates, day) {
+			var err error
+			booked, err = bookOccurrence(ctx, series, service, date)
+			if err != nil {
+				return err
+			}
+		}
+
+		update := bson.M{"$set": bson.M{"materialized_to": date, "updated_at": time.Now()}}
+		if booked {
+			update["$inc"] = bson.M{"occurrences": 1}
+			series.Occurrences++
+		}
+		if _, err := mongoDB.Collection("booking_series").UpdateOne(ctx, bson.M{"_id": series.ID}, update); err != nil {
+			return err
+		}
+		processed := date
+		series.MaterializedTo = &processed
+	}
+
+	// Once the last occurrence has been booked there is nothing left for the scheduler to do
+	rule := series.Rule
+	finished := (rule.Count > 0 && len(dates) >= rule.Count) || (rule.Until != nil && !rule.Until.After(through))
+	if finished {
+		series.Status = models.SeriesStatusCompleted
+		_, err := mongoDB.Collection("booking_series").UpdateOne(ctx,
+			bson.M{"_id": series.ID, "status": models.SeriesStatusActive},
+			bson.M{"$set": bson.M{"status": models.SeriesStatusCompleted, "updated_at": time.Now()}})
+		return err
+	}
+	return nil
+}
+
+// bookOccurrence books one date of a series and reports whether a booking was made
+func bookOccurrence(ctx context.Context, series *models.BookingSeries, service models.Service, date time.Time) (bool, error) {
+	mongoDB := db.GetMongoDB()
+
+	// The booking may already exist when an earlier run stopped before saving its progress
+	existing, err := mongoDB.Collection("bookings").CountDocuments(ctx, bson.M{"series_id": series.ID, "scheduled_at": seriesDay(series, date)})
+	if err != nil {
+		return false, err
+	}
+	if existing > 0 {
+		return true, nil
+	}
+
+	quote, err := bookingQuote(bookingItems([]models.BookingItem{bookingItem(service, nil)}), series.Region, money.Money{}, 0, nil)
+	if err != nil {
+		return false, err
+	}
+
+	booking := models.Booking{
+		UserID:          series.UserID,
+		ServiceID:       series.ServiceID,
+		SeriesID:        series.ID,
+		Status:          models.BookingStatusPending,
+		TotalAmount:     quote.Total,
+		Pricing:         quote,
+		PaymentStatus:   models.PaymentStatusPending,
+		SpecialRequests: series.SpecialRequests,
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
+	}
+
+	// Today's occurrence may already have started by the time it is booked
+	if err := setBookingTime(&booking, date.Format(localtime.DateFormat), series.ScheduledTime, series.Timezone); err != nil {
+		err = outbox.EnqueueNotification(ctx, models.Notification{
+			UserID:  series.UserID,
+			Title:   "Recurring Booking Not Made",
+			Message: "We could not book " + service.Name + " on " + date.Format(localtime.DateFormat) + " at " + series.ScheduledTime + ": " + err.Error(),
+			Type:    "booking",
+		})
+		outbox.Wake()
+		return false, err
+	}
+
+	err = placeBooking(&booking, service, nil)
+	if errors.Is(err, availability.ErrSlotFull) || errors.Is(err, availability.ErrSlotUnavailable) {
+		err = outbox.EnqueueNotification(ctx, models.Notification{
+			UserID:  series.UserID,
+			Title:   "Recurring Booking Not Made",
+			Message: "We could not book " + service.Name + " on " + date.Format("2006-01-02") + " at " + series.ScheduledTime + ": " + err.Error(),
+			Type:    "booking",
+		})
+		outbox.Wake()
+		return false, err
+	}
+	return err == nil, err
+}
+
+// seriesDay matches the bookings starting on a date of a series, in the series' timezone
+func seriesDay(series *models.BookingSeries, date time.Time) bson.M {
+	start := localtime.Midnight(date, localtime.Location(series.Timezone))
+	return bson.M{"$gte": start, "$lt": start.AddDate(0, 0, 1)}
+}
+
+// respondSeriesError writes the response for a failed change to a series' bookings
+func respondSeriesError(c *gin.Context, err error) {
+	switch err {
+	case errBookingStatusConflict, errInvalidTransition:
+		c.JSON(http.StatusConflict, gin.H{"error": "Booking series was updated by someone else, please retry"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update booking series"})
+	}
+}
+
+// loadUserSeries loads the series in the :id parameter if it belongs to the caller, writing the
+// error response otherwise
+func loadUserSeries(c *gin.Context) (*models.BookingSeries, bool) {
+	seriesID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid series ID"})
+		return nil, false
+	}
+
+	userID := getUserIDFromContext(c)
+	if userID.IsZero() {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return nil, false
+	}
+
+	var series models.BookingSeries
+	err = db.GetMongoDB().Collection("booking_series").FindOne(context.Background(), bson.M{"_id": seriesID, "user_id": userID}).Decode(&series)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Booking series not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		}
+		return nil, false
+	}
+	return &series, true
+}
+
+// seriesBookings returns the bookings made for a series in date order
+func seriesBookings(ctx context.Context, seriesID primitive.ObjectID) ([]models.Booking, error) {
+	cursor, err := db.GetMongoDB().Collection("bookings").Find(ctx, bson.M{"series_id": seriesID},
+		options.Find().SetSort(bson.D{{Key: "scheduled_at", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	bookings := []models.Booking{}
+	if err := cursor.All(ctx, &bookings); err != nil {
+		return nil, err
+	}
+	return bookings, nil
+}
+
+// seriesRule builds the recurrence rule of a new series from its RRULE or its separate fields
+func seriesRule(req models.CreateBookingSeriesRequest) (models.RecurrenceRule, error) {
+	if req.RRule != "" {
+		return recurrence.Parse(req.RRule)
+	}
+
+	rule := models.RecurrenceRule{
+		Frequency: strings.ToLower(req.Frequency),
+		Interval:  req.Interval,
+		Weekdays:  req.Weekdays,
+		MonthDay:  req.MonthDay,
+		Count:     req.Count,
+	}
+	if rule.Frequency == "biweekly" {
+		rule.Frequency = recurrence.FrequencyWeekly
+		rule.Interval = 2
+	}
+	if req.Until != "" {
+		until, err := time.Parse("2006-01-02", req.Until)
+		if err != nil {
+			return models.RecurrenceRule{}, errors.New("invalid until date. Use YYYY-MM-DD")
+		}
+		rule.Until = &until
+	}
+	return rule, nil
+}
+
+// isSeriesOccurrence reports whether the series' rule produces date
+func isSeriesOccurrence(series *models.BookingSeries, date time.Time) bool {
+	for _, occurrence := range recurrence.Dates(series.Rule, series.StartDate, date) {
+		if occurrence.Equal(date) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,996 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/code-harsh006/food-delivery/internal/availability"
+	"github.com/code-harsh006/food-delivery/internal/localtime"
+	"github.com/code-harsh006/food-delivery/internal/models"
+	"github.com/code-harsh006/food-delivery/internal/outbox"
+	"github.com/code-harsh006/food-delivery/internal/staffing"
+	"github.com/code-harsh006/food-delivery/pkg/db"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// vendorScopeKey is the context key RequireVendorOwner stores the caller's vendor ID under
+const vendorScopeKey = "vendor_id"
+
+// RequireVendorOwner limits a route group to owners of a vendor. The vendor is taken from the
+// vendor_id query parameter, which may be left out when the caller owns only one vendor.
+// Handlers shared with the admin routes read it with staffVendorScope.
+func RequireVendorOwner() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		mongoDB := db.GetMongoDB()
+		if mongoDB == nil {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+				"error":   "Database not available",
+				"message": "MongoDB connection is not established",
+			})
+			return
+		}
+
+		userID := getUserIDFromContext(c)
+		if userID.IsZero() {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			return
+		}
+
+		filter := bson.M{"owner_id": userID}
+		if vendorID := c.Query("vendor_id"); vendorID != "" {
+			id, err := primitive.ObjectIDFromHex(vendorID)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid vendor ID"})
+				return
+			}
+			filter["_id"] = id
+		}
+
+		var vendors []models.Vendor
+		cursor, err := mongoDB.Collection("vendors").Find(c.Request.Context(), filter)
+		if err == nil {
+			err = cursor.All(c.Request.Context(), &vendors)
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+
+		switch len(vendors) {
+		case 0:
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You do not own this vendor"})
+			return
+		case 1:
+			c.Set(vendorScopeKey, vendors[0].ID)
+			c.Next()
+		default:
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "You own several vendors, set vendor_id"})
+		}
+	}
+}
+
+// staffVendorScope returns the vendor a vendor route is limited to, or a zero ID on admin routes
+func staffVendorScope(c *gin.Context) primitive.ObjectID {
+	if value, ok := c.Get(vendorScopeKey); ok {
+		if vendorID, ok := value.(primitive.ObjectID); ok {
+			return vendorID
+		}
+	}
+	return primitive.NilObjectID
+}
+
+// CreateTechnician adds a technician to a vendor's staff (admin or vendor)
+func CreateTechnician(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	var req models.CreateTechnicianRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	vendorID := staffVendorScope(c)
+	if vendorID.IsZero() {
+		id, err := primitive.ObjectIDFromHex(req.VendorID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A valid vendor_id is required"})
+			return
+		}
+		count, err := mongoDB.Collection("vendors").CountDocuments(context.Background(), bson.M{"_id": id})
+		if err != nil || count == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Vendor not found"})
+			return
+		}
+		vendorID = id
+	}
+
+	technician := models.Technician{
+		VendorID:     vendorID,
+		Name:         strings.TrimSpace(req.Name),
+		Phone:        req.Phone,
+		Skills:       staffing.NormalizeSkills(req.Skills),
+		WorkingHours: req.WorkingHours,
+		TimeOff:      req.TimeOff,
+		IsActive:     req.IsActive == nil || *req.IsActive,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+	if technician.TimeOff == nil {
+		technician.TimeOff = []models.TimeOff{}
+	}
+	if req.UserID != "" {
+		userID, err := primitive.ObjectIDFromHex(req.UserID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+			return
+		}
+		technician.UserID = userID
+	}
+
+	if err := validateTechnician(technician); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := mongoDB.Collection("technicians").InsertOne(context.Background(), technician)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create technician"})
+		return
+	}
+
+	technician.ID = result.InsertedID.(primitive.ObjectID)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "Technician created successfully",
+		"technician": technician,
+	})
+}
+
+// GetTechnicians lists technicians, optionally filtered by vendor, skill and whether they are
+// active (admin or vendor)
+func GetTechnicians(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	filter := bson.M{}
+	if vendorID := staffVendorScope(c); !vendorID.IsZero() {
+		filter["vendor_id"] = vendorID
+	} else if vendorID, err := primitive.ObjectIDFromHex(c.Query("vendor_id")); err == nil {
+		filter["vendor_id"] = vendorID
+	}
+	if skill := c.Query("skill"); skill != "" {
+		filter["skills"] = strings.ToLower(strings.TrimSpace(skill))
+	}
+	if active := c.Query("active"); active != "" {
+		filter["is_active"] = active == "true"
+	}
+
+	cursor, err := mongoDB.Collection("technicians").Find(context.Background(), filter,
+		options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch technicians"})
+		return
+	}
+	defer cursor.Close(context.Background())
+
+	technicians := []models.Technician{}
+	if err = cursor.All(context.Background(), &technicians); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode technicians"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"technicians": technicians,
+		"total":       len(technicians),
+	})
+}
+
+// UpdateTechnician changes a technician's details, skills, working hours or time off (admin or
+// vendor). Jobs already assigned are kept.
+func UpdateTechnician(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	technicianID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid technician ID"})
+		return
+	}
+
+	var req models.UpdateTechnicianRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter := bson.M{"_id": technicianID}
+	if vendorID := staffVendorScope(c); !vendorID.IsZero() {
+		filter["vendor_id"] = vendorID
+	}
+
+	collection := mongoDB.Collection("technicians")
+	var technician models.Technician
+	if err := collection.FindOne(context.Background(), filter).Decode(&technician); err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Technician not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	if req.UserID != nil {
+		technician.UserID = primitive.NilObjectID
+		if *req.UserID != "" {
+			userID, err := primitive.ObjectIDFromHex(*req.UserID)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+				return
+			}
+			technician.UserID = userID
+		}
+	}
+	if req.Name != nil {
+		technician.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Phone != nil {
+		technician.Phone = *req.Phone
+	}
+	if req.Skills != nil {
+		technician.Skills = staffing.NormalizeSkills(req.Skills)
+	}
+	if req.WorkingHours != nil {
+		technician.WorkingHours = req.WorkingHours
+	}
+	if req.TimeOff != nil {
+		technician.TimeOff = req.TimeOff
+	}
+	if req.IsActive != nil {
+		technician.IsActive = *req.IsActive
+	}
+	technician.UpdatedAt = time.Now()
+
+	if err := validateTechnician(technician); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	set := bson.M{
+		"name":          technician.Name,
+		"phone":         technician.Phone,
+		"skills":        technician.Skills,
+		"working_hours": technician.WorkingHours,
+		"time_off":      technician.TimeOff,
+		"is_active":     technician.IsActive,
+		"updated_at":    technician.UpdatedAt,
+	}
+	update := bson.M{"$set": set}
+	if technician.UserID.IsZero() {
+		update["$unset"] = bson.M{"user_id": ""}
+	} else {
+		set["user_id"] = technician.UserID
+	}
+
+	if _, err := collection.UpdateOne(context.Background(), bson.M{"_id": technicianID}, update); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update technician"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Technician updated successfully",
+		"technician": technician,
+	})
+}
+
+// DeleteTechnician removes a technician with no upcoming jobs (admin or vendor). Technicians
+// with upcoming jobs have to be reassigned or deactivated instead.
+func DeleteTechnician(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	technicianID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid technician ID"})
+		return
+	}
+
+	filter := bson.M{"_id": technicianID}
+	if vendorID := staffVendorScope(c); !vendorID.IsZero() {
+		filter["vendor_id"] = vendorID
+	}
+	count, err := mongoDB.Collection("technicians").CountDocuments(context.Background(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if count == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Technician not found"})
+		return
+	}
+
+	upcoming, err := mongoDB.Collection("bookings").CountDocuments(context.Background(), bson.M{
+		"technician_id": technicianID,
+		"status":        bson.M{"$in": []string{models.BookingStatusPending, models.BookingStatusConfirmed, models.BookingStatusInProgress}},
+		"job_end":       bson.M{"$gt": time.Now()},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if upcoming > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":         "Technician has upcoming jobs, reassign them or deactivate the technician",
+			"upcoming_jobs": upcoming,
+		})
+		return
+	}
+
+	result, err := mongoDB.Collection("technicians").DeleteOne(context.Background(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete technician"})
+		return
+	}
+	if result.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Technician not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Technician deleted successfully"})
+}
+
+// GetVendorBookings lists the bookings of a vendor's services, optionally for one day or only
+// those still without a technician (vendor)
+func GetVendorBookings(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	serviceIDs, err := mongoDB.Collection("services").Distinct(context.Background(), "_id", bson.M{"vendor_id": staffVendorScope(c)})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch services"})
+		return
+	}
+
+	filter := bson.M{"service_id": bson.M{"$in": serviceIDs}}
+	if date := c.Query("date"); date != "" {
+		day, err := localtime.ParseDate(date)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use YYYY-MM-DD"})
+			return
+		}
+		// The day is the vendor's local day
+		timezone, err := localtime.ForVendor(context.Background(), staffVendorScope(c), "")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+		start := localtime.Midnight(day, localtime.Location(timezone))
+		filter["scheduled_at"] = bson.M{"$gte": start, "$lt": start.AddDate(0, 0, 1)}
+	}
+	if status := c.Query("status"); status != "" {
+		filter["status"] = status
+	}
+	if c.Query("unassigned") == "true" {
+		filter["technician_id"] = bson.M{"$exists": false}
+	}
+
+	cursor, err := mongoDB.Collection("bookings").Find(context.Background(), filter,
+		options.Find().SetSort(bson.D{{Key: "scheduled_at", Value: 1}}))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bookings"})
+		return
+	}
+	defer cursor.Close(context.Background())
+
+	bookings := []models.Booking{}
+	if err = cursor.All(context.Background(), &bookings); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode bookings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"bookings": bookings,
+		"total":    len(bookings),
+	})
+}
+
+// AssignBookingTechnician assigns or reassigns the technician of a pending or confirmed booking,
+// or with "auto" picks the least busy technician of the vendor who has the skills and is free
+// (admin or vendor)
+func AssignBookingTechnician(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	bookingID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking ID"})
+		return
+	}
+
+	var req models.AssignTechnicianRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var technicianID primitive.ObjectID
+	if !req.Auto {
+		technicianID, err = primitive.ObjectIDFromHex(req.TechnicianID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Set a valid technician_id or auto"})
+			return
+		}
+	}
+
+	booking, service, ok := loadStaffedBooking(c, bookingID)
+	if !ok {
+		return
+	}
+
+	actor := bookingActor{role: actorAdmin, id: getUserIDFromContext(c)}
+	if !staffVendorScope(c).IsZero() {
+		actor.role = actorVendor
+	}
+
+	var updated models.Booking
+	err = db.WithTransaction(context.Background(), func(ctx context.Context) error {
+		var technician *models.Technician
+		var err error
+		if req.Auto {
+			technician, err = reserveAnyTechnician(ctx, booking, service)
+		} else {
+			technician, err = staffing.Reserve(ctx, technicianID, booking, service)
+		}
+		if err != nil {
+			return err
+		}
+
+		start, end := staffing.Window(booking, service)
+		now := time.Now()
+		err = mongoDB.Collection("bookings").FindOneAndUpdate(ctx,
+			bson.M{"_id": booking.ID, "status": booking.Status},
+			bson.M{
+				"$set": bson.M{
+					"technician_id":   technician.ID,
+					"technician_name": technician.Name,
+					"job_start":       start,
+					"job_end":         end,
+					"updated_at":      now,
+				},
+				"$inc": bson.M{"event_sequence": 1},
+			},
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&updated)
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				return errBookingStatusConflict
+			}
+			return err
+		}
+
+		if _, err := mongoDB.Collection("booking_statuses").InsertOne(ctx, models.BookingStatus{
+			BookingID: booking.ID,
+			Status:    booking.Status,
+			Message:   "Technician " + technician.Name + " assigned",
+			UpdatedBy: actor.String(),
+			CreatedAt: now,
+		}); err != nil {
+			return err
+		}
+
+		when := localBookingTime(booking)
+		if err := outbox.EnqueueNotification(ctx, models.Notification{
+			UserID:  booking.UserID,
+			Title:   "Technician Assigned",
+			Message: technician.Name + " will carry out your " + service.Name + " booking on " + when,
+			Type:    "booking",
+		}); err != nil {
+			return err
+		}
+
+		if err := notifyTechnician(ctx, technician.ID, models.Notification{
+			Title:   "New Job",
+			Message: "You have been assigned a " + service.Name + " booking on " + when,
+			Type:    "job",
+		}); err != nil {
+			return err
+		}
+
+		if !booking.TechnicianID.IsZero() && booking.TechnicianID != technician.ID {
+			return notifyTechnician(ctx, booking.TechnicianID, models.Notification{
+				Title:   "Job Reassigned",
+				Message: "The " + service.Name + " booking on " + when + " has been given to another technician",
+				Type:    "job",
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		if respondStaffingError(c, err) {
+			return
+		}
+		respondBookingTransitionError(c, &booking, booking.Status, err)
+		return
+	}
+	outbox.Wake()
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Technician assigned successfully",
+		"booking": updated,
+	})
+}
+
+// UnassignBookingTechnician removes the technician from a booking (admin or vendor)
+func UnassignBookingTechnician(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	bookingID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking ID"})
+		return
+	}
+
+	booking, service, ok := loadStaffedBooking(c, bookingID)
+	if !ok {
+		return
+	}
+	if booking.TechnicianID.IsZero() {
+		c.JSON(http.StatusConflict, gin.H{"error": "Booking has no technician"})
+		return
+	}
+
+	err = db.WithTransaction(context.Background(), func(ctx context.Context) error {
+		if err := unassignTechnician(ctx, booking); err != nil {
+			return err
+		}
+		return notifyTechnician(ctx, booking.TechnicianID, models.Notification{
+			Title:   "Job Unassigned",
+			Message: "You are no longer assigned the " + service.Name + " booking on " + localBookingTime(booking),
+			Type:    "job",
+		})
+	})
+	if err != nil {
+		respondBookingTransitionError(c, &booking, booking.Status, err)
+		return
+	}
+	outbox.Wake()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Technician unassigned successfully"})
+}
+
+// GetTechnicianJobs lists the signed-in technician's jobs for a day, today in their vendor's
+// timezone unless date is given
+func GetTechnicianJobs(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	technicianIDs, ok := currentTechnicianIDs(c)
+	if !ok {
+		return
+	}
+
+	// The day is the technician's vendor's local day; a technician that cannot be read falls
+	// back to the default timezone
+	var technician models.Technician
+	mongoDB.Collection("technicians").FindOne(context.Background(), bson.M{"_id": technicianIDs[0]}).Decode(&technician)
+	timezone, err := localtime.ForVendor(context.Background(), technician.VendorID, "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	loc := localtime.Location(timezone)
+
+	day := localtime.Today(loc)
+	if date := c.Query("date"); date != "" {
+		if day, err = localtime.ParseDate(date); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use YYYY-MM-DD"})
+			return
+		}
+	}
+	start := localtime.Midnight(day, loc)
+
+	cursor, err := mongoDB.Collection("bookings").Find(context.Background(),
+		bson.M{
+			"technician_id": bson.M{"$in": technicianIDs},
+			"scheduled_at":  bson.M{"$gte": start, "$lt": start.AddDate(0, 0, 1)},
+			"status":        bson.M{"$ne": models.BookingStatusCancelled},
+		},
+		options.Find().SetSort(bson.D{{Key: "job_start", Value: 1}}))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch jobs"})
+		return
+	}
+	defer cursor.Close(context.Background())
+
+	var bookings []models.Booking
+	if err = cursor.All(context.Background(), &bookings); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode jobs"})
+		return
+	}
+
+	// Show the service each job is for
+	serviceIDs := make([]primitive.ObjectID, 0, len(bookings))
+	for _, booking := range bookings {
+		serviceIDs = append(serviceIDs, booking.ServiceID)
+	}
+	names := make(map[primitive.ObjectID]string)
+	if len(serviceIDs) > 0 {
+		var services []models.Service
+		serviceCursor, err := mongoDB.Collection("services").Find(context.Background(), bson.M{"_id": bson.M{"$in": serviceIDs}})
+		if err == nil && serviceCursor.All(context.Background(), &services) == nil {
+			for _, service := range services {
+				names[service.ID] = service.Name
+			}
+		}
+	}
+
+	type technicianJob struct {
+		Booking     models.Booking `json:"booking"`
+		ServiceName string         `json:"service_name"`
+	}
+	jobs := make([]technicianJob, 0, len(bookings))
+	for _, booking := range bookings {
+		jobs = append(jobs, technicianJob{Booking: booking, ServiceName: names[booking.ServiceID]})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"date":  day.Format(localtime.DateFormat),
+		"jobs":  jobs,
+		"total": len(jobs),
+	})
+}
+
+// UpdateTechnicianJobStatus lets the signed-in technician start or complete one of their jobs
+func UpdateTechnicianJobStatus(c *gin.Context) {
+	// Check if MongoDB is connected
+	if db.GetMongoDB() == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	bookingID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking ID"})
+		return
+	}
+
+	var req struct {
+		Status  string `json:"status" binding:"required"`
+		Message string `json:"message"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !isBookingStatus(req.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking status"})
+		return
+	}
+
+	technicianIDs, ok := currentTechnicianIDs(c)
+	if !ok {
+		return
+	}
+
+	var booking *models.Booking
+	actor := bookingActor{role: actorTechnician, id: getUserIDFromContext(c)}
+	err = db.WithTransaction(context.Background(), func(ctx context.Context) error {
+		booking, err = transitionBooking(ctx, bson.M{"_id": bookingID, "technician_id": bson.M{"$in": technicianIDs}}, req.Status, actor, req.Message)
+		if err != nil {
+			return err
+		}
+
+		return outbox.EnqueueNotification(ctx, models.Notification{
+			UserID:  booking.UserID,
+			Title:   "Booking Updated",
+			Message: "Your booking is now " + strings.ReplaceAll(req.Status, "_", " "),
+			Type:    "booking",
+		})
+	})
+	if err != nil {
+		respondBookingTransitionError(c, booking, req.Status, err)
+		return
+	}
+	outbox.Wake()
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Job status updated successfully",
+		"booking": booking,
+	})
+}
+
+// keepTechnician re-checks the technician of a rescheduled booking at its new time. Their job
+// moves with the booking when they are still free; otherwise they are unassigned and the vendor
+// is asked to find someone else. Run it in the rescheduling transaction.
+func keepTechnician(ctx context.Context, booking models.Booking, service models.Service) error {
+	if booking.TechnicianID.IsZero() {
+		return nil
+	}
+
+	when := localBookingTime(booking)
+	_, err := staffing.Reserve(ctx, booking.TechnicianID, booking, service)
+	if err == nil {
+		start, end := staffing.Window(booking, service)
+		_, err := db.GetMongoDB().Collection("bookings").UpdateOne(ctx,
+			bson.M{"_id": booking.ID},
+			bson.M{"$set": bson.M{"job_start": start, "job_end": end}},
+		)
+		if err != nil {
+			return err
+		}
+		return notifyTechnician(ctx, booking.TechnicianID, models.Notification{
+			Title:   "Job Rescheduled",
+			Message: "Your " + service.Name + " job has moved to " + when,
+			Type:    "job",
+		})
+	}
+	if !isStaffingRejection(err) {
+		return err
+	}
+
+	if err := unassignTechnician(ctx, booking); err != nil {
+		return err
+	}
+	if err := notifyTechnician(ctx, booking.TechnicianID, models.Notification{
+		Title:   "Job Unassigned",
+		Message: "The " + service.Name + " booking you were assigned has moved to " + when + ", when you are not available",
+		Type:    "job",
+	}); err != nil {
+		return err
+	}
+	return notifyVendor(ctx, service.VendorID, models.Notification{
+		Title:   "Technician Needed",
+		Message: "A " + service.Name + " booking moved to " + when + " and its technician is not available, please assign another",
+		Type:    "booking",
+	})
+}
+
+// reserveAnyTechnician reserves the first suitable technician, trying the least busy first
+func reserveAnyTechnician(ctx context.Context, booking models.Booking, service models.Service) (*models.Technician, error) {
+	candidates, err := staffing.Candidates(ctx, booking, service)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, candidate := range candidates {
+		technician, err := staffing.Reserve(ctx, candidate.ID, booking, service)
+		if err == nil {
+			return technician, nil
+		}
+		if !isStaffingRejection(err) {
+			return nil, err
+		}
+	}
+	return nil, staffing.ErrNoTechnician
+}
+
+// unassignTechnician clears the technician from a booking
+func unassignTechnician(ctx context.Context, booking models.Booking) error {
+	result, err := db.GetMongoDB().Collection("bookings").UpdateOne(ctx,
+		bson.M{"_id": booking.ID, "technician_id": booking.TechnicianID},
+		bson.M{
+			"$unset": bson.M{"technician_id": "", "technician_name": "", "job_start": "", "job_end": ""},
+			"$set":   bson.M{"updated_at": time.Now()},
+			"$inc":   bson.M{"event_sequence": 1},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errBookingStatusConflict
+	}
+	return nil
+}
+
+// loadStaffedBooking loads a pending or confirmed booking and its service for (re)assignment,
+// writing the response and returning false when it cannot be staffed. On vendor routes only
+// bookings of the vendor's own services are found.
+func loadStaffedBooking(c *gin.Context, bookingID primitive.ObjectID) (models.Booking, models.Service, bool) {
+	mongoDB := db.GetMongoDB()
+
+	var booking models.Booking
+	if err := mongoDB.Collection("bookings").FindOne(context.Background(), bson.M{"_id": bookingID}).Decode(&booking); err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+			return booking, models.Service{}, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return booking, models.Service{}, false
+	}
+
+	var service models.Service
+	if err := mongoDB.Collection("services").FindOne(context.Background(), bson.M{"_id": booking.ServiceID}).Decode(&service); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch service"})
+		return booking, service, false
+	}
+
+	if vendorID := staffVendorScope(c); !vendorID.IsZero() && service.VendorID != vendorID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		return booking, service, false
+	}
+
+	if booking.Status != models.BookingStatusPending && booking.Status != models.BookingStatusConfirmed {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":  "Only pending or confirmed bookings can be assigned a technician",
+			"status": booking.Status,
+		})
+		return booking, service, false
+	}
+
+	return booking, staffedService(booking, service), true
+}
+
+// currentTechnicianIDs returns the technician records of the signed-in user, writing the
+// response and returning false when they are not an active technician
+func currentTechnicianIDs(c *gin.Context) ([]primitive.ObjectID, bool) {
+	userID := getUserIDFromContext(c)
+	if userID.IsZero() {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return nil, false
+	}
+
+	ids, err := db.GetMongoDB().Collection("technicians").Distinct(context.Background(), "_id", bson.M{"user_id": userID, "is_active": true})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return nil, false
+	}
+	if len(ids) == 0 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not registered as a technician"})
+		return nil, false
+	}
+
+	technicianIDs := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		if technicianID, ok := id.(primitive.ObjectID); ok {
+			technicianIDs = append(technicianIDs, technicianID)
+		}
+	}
+	return technicianIDs, true
+}
+
+// notifyTechnician queues a notification for a technician's account. It does nothing when the
+// technician has no account.
+func notifyTechnician(ctx context.Context, technicianID primitive.ObjectID, notification models.Notification) error {
+	var technician models.Technician
+	err := db.GetMongoDB().Collection("technicians").FindOne(ctx, bson.M{"_id": technicianID}).Decode(&technician)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		return err
+	}
+	if technician.UserID.IsZero() {
+		return nil
+	}
+
+	notification.UserID = technician.UserID
+	return outbox.EnqueueNotification(ctx, notification)
+}
+
+// validateTechnician checks a technician's name, working hours and time off
+func validateTechnician(technician models.Technician) error {
+	if technician.Name == "" {
+		return errors.New("name is required")
+	}
+	if len(technician.WorkingHours) == 0 {
+		return errors.New("working_hours must list at least one window")
+	}
+	for _, hours := range technician.WorkingHours {
+		if hours.Weekday < 0 || hours.Weekday > 6 {
+			return errors.New("weekday must be between 0 (Sunday) and 6 (Saturday)")
+		}
+		start, err := availability.ParseClock(hours.StartTime)
+		if err != nil {
+			return err
+		}
+		end, err := availability.ParseClock(hours.EndTime)
+		if err != nil {
+			return err
+		}
+		if end <= start {
+			return errors.New("working hours must end after they start")
+		}
+	}
+	for _, off := range technician.TimeOff {
+		if !off.End.After(off.Start) {
+			return errors.New("time off must end after it starts")
+		}
+	}
+	return nil
+}
+
+// isStaffingRejection reports whether err means a technician cannot take a booking, as opposed
+// to a database failure
+func isStaffingRejection(err error) bool {
+	switch {
+	case errors.Is(err, staffing.ErrTechnicianNotFound), errors.Is(err, staffing.ErrNoTechnician),
+		errors.Is(err, staffing.ErrWrongVendor), errors.Is(err, staffing.ErrMissingSkills),
+		errors.Is(err, staffing.ErrOffDuty), errors.Is(err, staffing.ErrDoubleBooked):
+		return true
+	}
+	return false
+}
+
+// respondStaffingError writes the response for a technician who cannot take a booking and
+// reports whether err was one
+func respondStaffingError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, staffing.ErrTechnicianNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Technician not found"})
+	case errors.Is(err, staffing.ErrWrongVendor), errors.Is(err, staffing.ErrMissingSkills):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	case errors.Is(err, staffing.ErrOffDuty), errors.Is(err, staffing.ErrDoubleBooked), errors.Is(err, staffing.ErrNoTechnician):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		return false
+	}
+	return true
+}
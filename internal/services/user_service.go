@@ -3,9 +3,11 @@ package services
 import (
 	"context"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/code-harsh006/food-delivery/internal/models"
+	"github.com/code-harsh006/food-delivery/internal/outbox"
 	"github.com/code-harsh006/food-delivery/pkg/db"
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
@@ -232,40 +234,37 @@ func UpdateBookingStatus(c *gin.Context) {
 		return
 	}
 
-	// Update booking status
-	bookingCollection := db.GetMongoDB().Collection("bookings")
-	result, err := bookingCollection.UpdateOne(
-		context.Background(),
-		bson.M{"_id": bookID},
-		bson.M{"$set": bson.M{
-			"status":     req.Status,
-			"updated_at": time.Now(),
-		}},
-	)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update booking status"})
+	if !isBookingStatus(req.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking status"})
 		return
 	}
 
-	if result.MatchedCount == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+	var booking *models.Booking
+	actor := bookingActor{role: actorAdmin, id: getUserIDFromContext(c)}
+	err = db.WithTransaction(context.Background(), func(ctx context.Context) error {
+		booking, err = transitionBooking(ctx, bson.M{"_id": bookID}, req.Status, actor, req.Message)
+		if err != nil {
+			return err
+		}
+
+		return outbox.EnqueueNotification(ctx, models.Notification{
+			UserID:  booking.UserID,
+			Title:   "Booking Updated",
+			Message: "Your booking is now " + strings.ReplaceAll(req.Status, "_", " "),
+			Type:    "booking",
+		})
+	})
+	if err != nil {
+		respondBookingTransitionError(c, booking, req.Status, err)
 		return
 	}
 
-	// Create status history entry
-	status := models.BookingStatus{
-		BookingID: bookID,
-		Status:    req.Status,
-		Message:   req.Message,
-		UpdatedBy: "admin", // This should come from auth context
-		CreatedAt: time.Now(),
-	}
-	statusCollection := db.GetMongoDB().Collection("booking_statuses")
-	statusCollection.InsertOne(context.Background(), status)
+	outbox.Wake()
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Booking status updated successfully",
 		"status":  req.Status,
+		"booking": booking,
 	})
 }
 
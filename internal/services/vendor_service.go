@@ -0,0 +1,65 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/code-harsh006/food-delivery/internal/localtime"
+	"github.com/code-harsh006/food-delivery/pkg/db"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SetVendorTimezone sets the IANA timezone a vendor's bookings are scheduled in. Existing
+// bookings keep the timezone they were made in.
+func SetVendorTimezone(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	vendorID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid vendor ID"})
+		return
+	}
+
+	var req struct {
+		Timezone string `json:"timezone" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !localtime.Valid(req.Timezone) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown timezone " + req.Timezone + ", use an IANA name such as Asia/Kolkata"})
+		return
+	}
+
+	result, err := mongoDB.Collection("vendors").UpdateOne(context.Background(), bson.M{"_id": vendorID}, bson.M{
+		"$set": bson.M{
+			"timezone":   req.Timezone,
+			"updated_at": time.Now(),
+		},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update vendor"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Vendor not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Vendor timezone updated successfully",
+		"timezone": req.Timezone,
+	})
+}
@@ -0,0 +1,527 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/code-harsh006/food-delivery/internal/availability"
+	"github.com/code-harsh006/food-delivery/internal/localtime"
+	"github.com/code-harsh006/food-delivery/internal/models"
+	"github.com/code-harsh006/food-delivery/internal/outbox"
+	"github.com/code-harsh006/food-delivery/internal/waitlist"
+	"github.com/code-harsh006/food-delivery/pkg/db"
+	"github.com/code-harsh006/food-delivery/pkg/logger"
+	"github.com/code-harsh006/food-delivery/pkg/money"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+// errHoldExpired is returned when a waitlist place is confirmed after its hold has passed on
+var errHoldExpired = errors.New("waitlist hold expired")
+
+// JoinWaitlist puts the caller in the queue for a fully booked slot
+func JoinWaitlist(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	var req models.JoinWaitlistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := getUserIDFromContext(c)
+	if userID.IsZero() {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	serviceID, err := primitive.ObjectIDFromHex(req.ServiceID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service ID"})
+		return
+	}
+
+	var service models.Service
+	err = mongoDB.Collection("services").FindOne(context.Background(), bson.M{"_id": serviceID, "is_active": true}).Decode(&service)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	timezone, err := localtime.Resolve(context.Background(), service, req.Region)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	var slot models.Booking
+	if err := setBookingTime(&slot, req.ScheduledDate, req.ScheduledTime, timezone); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cells, remaining, err := availability.Cells(context.Background(), service, bookingDay(slot), slot.LocalTime(), 0)
+	if err != nil {
+		if respondSlotError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check availability"})
+		return
+	}
+	if len(cells) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This service has no slots and can be booked at any time"})
+		return
+	}
+	if remaining > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "The slot still has room, book it instead", "remaining": remaining})
+		return
+	}
+
+	var existing models.WaitlistEntry
+	err = mongoDB.Collection("waitlist").FindOne(context.Background(), bson.M{
+		"user_id":      userID,
+		"service_id":   serviceID,
+		"scheduled_at": slot.ScheduledAt,
+		"status":       bson.M{"$in": []string{models.WaitlistStatusWaiting, models.WaitlistStatusOffered}},
+	}).Decode(&existing)
+	if err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "You are already on the waitlist for this slot", "entry": existing})
+		return
+	}
+	if err != mongo.ErrNoDocuments {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	entry := models.WaitlistEntry{
+		UserID:          userID,
+		ServiceID:       serviceID,
+		ScheduledAt:     slot.ScheduledAt,
+		Timezone:        slot.Timezone,
+		ScheduledDate:   slot.LocalDate(),
+		ScheduledTime:   slot.LocalTime(),
+		Region:          req.Region,
+		SpecialRequests: req.SpecialRequests,
+		Slots:           cells,
+		Status:          models.WaitlistStatusWaiting,
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
+	}
+	result, err := mongoDB.Collection("waitlist").InsertOne(context.Background(), entry)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to join waitlist"})
+		return
+	}
+	entry.ID = result.InsertedID.(primitive.ObjectID)
+
+	if entry.Position, err = waitlist.Position(context.Background(), entry); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch waitlist position"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Joined the waitlist successfully",
+		"entry":   entry,
+	})
+}
+
+// GetUserWaitlist lists the caller's waitlist entries, newest first, with their queue positions
+func GetUserWaitlist(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	userID := getUserIDFromContext(c)
+	if userID.IsZero() {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	filter := bson.M{"user_id": userID}
+	if status := c.Query("status"); status != "" {
+		filter["status"] = status
+	}
+
+	cursor, err := mongoDB.Collection("waitlist").Find(context.Background(), filter,
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch waitlist"})
+		return
+	}
+	defer cursor.Close(context.Background())
+
+	entries := []models.WaitlistEntry{}
+	if err = cursor.All(context.Background(), &entries); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode waitlist"})
+		return
+	}
+	for i := range entries {
+		if entries[i].Status != models.WaitlistStatusWaiting {
+			continue
+		}
+		if entries[i].Position, err = waitlist.Position(context.Background(), entries[i]); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch waitlist position"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries": entries,
+		"total":   len(entries),
+	})
+}
+
+// GetWaitlistEntry returns one of the caller's waitlist entries with its queue position
+func GetWaitlistEntry(c *gin.Context) {
+	// Check if MongoDB is connected
+	if db.GetMongoDB() == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	entry, ok := loadUserWaitlistEntry(c)
+	if !ok {
+		return
+	}
+
+	if entry.Status == models.WaitlistStatusWaiting {
+		var err error
+		if entry.Position, err = waitlist.Position(context.Background(), *entry); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch waitlist position"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"entry": entry})
+}
+
+// ConfirmWaitlistHold books the place held for the caller, priced as it is now. It fails once the
+// hold has expired and the place has passed to the next customer.
+func ConfirmWaitlistHold(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	entry, ok := loadUserWaitlistEntry(c)
+	if !ok {
+		return
+	}
+	if entry.Status != models.WaitlistStatusOffered {
+		c.JSON(http.StatusConflict, gin.H{"error": "No place is being held for this waitlist entry", "status": entry.Status})
+		return
+	}
+
+	var service models.Service
+	err := mongoDB.Collection("services").FindOne(context.Background(), bson.M{"_id": entry.ServiceID, "is_active": true}).Decode(&service)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	quote, err := bookingQuote(bookingItems([]models.BookingItem{bookingItem(service, nil)}), entry.Region, money.Money{}, 0, nil)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	booking := models.Booking{
+		ID:              primitive.NewObjectID(),
+		UserID:          entry.UserID,
+		ServiceID:       entry.ServiceID,
+		ScheduledAt:     entry.ScheduledAt,
+		Timezone:        entry.Timezone,
+		Status:          models.BookingStatusPending,
+		TotalAmount:     quote.Total,
+		Pricing:         quote,
+		PaymentStatus:   models.PaymentStatusPending,
+		SpecialRequests: entry.SpecialRequests,
+		CapacitySlots:   entry.Slots,
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
+	}
+
+	err = db.WithTransaction(context.Background(), func(ctx context.Context) error {
+		// The held slots pass to the booking, so nothing is claimed again
+		result, err := mongoDB.Collection("waitlist").UpdateOne(ctx,
+			bson.M{
+				"_id":             entry.ID,
+				"status":          models.WaitlistStatusOffered,
+				"hold_expires_at": bson.M{"$gt": time.Now()},
+			},
+			bson.M{"$set": bson.M{
+				"status":     models.WaitlistStatusBooked,
+				"booking_id": booking.ID,
+				"updated_at": time.Now(),
+			}},
+		)
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return errHoldExpired
+		}
+
+		return storeBooking(ctx, &booking, service, nil)
+	})
+	if err != nil {
+		if err == errHoldExpired {
+			c.JSON(http.StatusConflict, gin.H{"error": "Your hold has expired and the place has been offered to the next customer"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create booking"})
+		return
+	}
+	outbox.Wake()
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Booking created successfully",
+		"booking": booking,
+	})
+}
+
+// LeaveWaitlist takes the caller off a waitlist. A place held for them passes to the next customer.
+func LeaveWaitlist(c *gin.Context) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	entry, ok := loadUserWaitlistEntry(c)
+	if !ok {
+		return
+	}
+	if entry.Status != models.WaitlistStatusWaiting && entry.Status != models.WaitlistStatusOffered {
+		c.JSON(http.StatusConflict, gin.H{"error": "Waitlist entry is already " + entry.Status})
+		return
+	}
+
+	err := db.WithTransaction(context.Background(), func(ctx context.Context) error {
+		result, err := mongoDB.Collection("waitlist").UpdateOne(ctx,
+			bson.M{"_id": entry.ID, "status": entry.Status},
+			bson.M{"$set": bson.M{"status": models.WaitlistStatusLeft, "updated_at": time.Now()}},
+		)
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return errBookingStatusConflict
+		}
+
+		if entry.Status == models.WaitlistStatusOffered {
+			return releaseSlots(ctx, entry.Slots)
+		}
+		return nil
+	})
+	if err != nil {
+		if err == errBookingStatusConflict {
+			c.JSON(http.StatusConflict, gin.H{"error": "Waitlist entry was updated by someone else, please retry"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to leave waitlist"})
+		return
+	}
+	outbox.Wake()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Left the waitlist successfully"})
+}
+
+// StartWaitlistScheduler passes on expired holds and closes waitlists of slots that have started,
+// now and then on every interval, until ctx is cancelled
+func StartWaitlistScheduler(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := ExpireWaitlistHolds(ctx); err != nil {
+				logger.Error("Failed to expire waitlist holds", zap.Error(err))
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// ExpireWaitlistHolds offers every expired hold to the next customer waiting for it, or releases
+// it when nobody is, and expires the entries of slots that have already started
+func ExpireWaitlistHolds(ctx context.Context) error {
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		return nil
+	}
+
+	now := time.Now()
+	if _, err := mongoDB.Collection("waitlist").UpdateMany(ctx,
+		bson.M{"status": models.WaitlistStatusWaiting, "scheduled_at": bson.M{"$lte": now}},
+		bson.M{"$set": bson.M{"status": models.WaitlistStatusExpired, "updated_at": now}},
+	); err != nil {
+		return err
+	}
+
+	cursor, err := mongoDB.Collection("waitlist").Find(ctx, bson.M{
+		"status":          models.WaitlistStatusOffered,
+		"hold_expires_at": bson.M{"$lte": now},
+	})
+	if err != nil {
+		return err
+	}
+	var expired []models.WaitlistEntry
+	if err := cursor.All(ctx, &expired); err != nil {
+		return err
+	}
+
+	for _, entry := range expired {
+		err := db.WithTransaction(ctx, func(ctx context.Context) error {
+			result, err := mongoDB.Collection("waitlist").UpdateOne(ctx,
+				bson.M{"_id": entry.ID, "status": models.WaitlistStatusOffered},
+				bson.M{"$set": bson.M{"status": models.WaitlistStatusExpired, "updated_at": time.Now()}},
+			)
+			if err != nil {
+				return err
+			}
+			if result.MatchedCount == 0 {
+				return nil
+			}
+
+			if err := releaseSlots(ctx, entry.Slots); err != nil {
+				return err
+			}
+			return outbox.EnqueueNotification(ctx, models.Notification{
+				UserID:  entry.UserID,
+				Title:   "Waitlist Hold Expired",
+				Message: "The place held for you on " + localWaitlistTime(entry) + " was not confirmed in time and has been released",
+				Type:    "waitlist",
+			})
+		})
+		if err != nil {
+			logger.Error("Failed to expire waitlist hold", zap.String("entry_id", entry.ID.Hex()), zap.Error(err))
+		}
+	}
+	if len(expired) > 0 {
+		outbox.Wake()
+	}
+	return nil
+}
+
+// droppedSlots returns the slots in held that are not in kept
+func droppedSlots(held, kept []string) []string {
+	keep := make(map[string]bool, len(kept))
+	for _, id := range kept {
+		keep[id] = true
+	}
+	dropped := make([]string, 0, len(held))
+	for _, id := range held {
+		if !keep[id] {
+			dropped = append(dropped, id)
+		}
+	}
+	return dropped
+}
+
+// releaseSlots frees the slot capacity a booking or hold no longer needs, offering it to the
+// waitlist first. Run it inside the transaction that frees the capacity.
+func releaseSlots(ctx context.Context, ids []string) error {
+	offered, err := waitlist.Handoff(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range offered {
+		var service models.Service
+		if err := db.GetMongoDB().Collection("services").FindOne(ctx, bson.M{"_id": entry.ServiceID}).Decode(&service); err != nil && err != mongo.ErrNoDocuments {
+			return err
+		}
+		expires := entry.HoldExpiresAt.In(localtime.Location(entry.Timezone)).Format("15:04 MST")
+		if err := outbox.EnqueueNotification(ctx, models.Notification{
+			UserID:  entry.UserID,
+			Title:   "A Place Opened Up",
+			Message: "A place opened up for " + service.Name + " on " + localWaitlistTime(entry) + ". It is held for you until " + expires + ", confirm it to book",
+			Type:    "waitlist",
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// localWaitlistTime formats the start of a waitlisted slot in its own timezone
+func localWaitlistTime(entry models.WaitlistEntry) string {
+	return entry.ScheduledAt.In(localtime.Location(entry.Timezone)).Format(localtime.DateFormat + " 15:04 MST")
+}
+
+// loadUserWaitlistEntry loads the waitlist entry in the :id parameter if it belongs to the
+// caller, writing the error response otherwise
+func loadUserWaitlistEntry(c *gin.Context) (*models.WaitlistEntry, bool) {
+	entryID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid waitlist entry ID"})
+		return nil, false
+	}
+
+	userID := getUserIDFromContext(c)
+	if userID.IsZero() {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return nil, false
+	}
+
+	var entry models.WaitlistEntry
+	err = db.GetMongoDB().Collection("waitlist").FindOne(context.Background(), bson.M{"_id": entryID, "user_id": userID}).Decode(&entry)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Waitlist entry not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		}
+		return nil, false
+	}
+	return &entry, true
+}
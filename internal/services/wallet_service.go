@@ -0,0 +1,383 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/code-harsh006/food-delivery/internal/models"
+	"github.com/code-harsh006/food-delivery/internal/outbox"
+	"github.com/code-harsh006/food-delivery/internal/wallet"
+	"github.com/code-harsh006/food-delivery/pkg/config"
+	"github.com/code-harsh006/food-delivery/pkg/db"
+	"github.com/code-harsh006/food-delivery/pkg/logger"
+	"github.com/code-harsh006/food-delivery/pkg/money"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+)
+
+// StartWalletExpiryScheduler takes back expired promotional wallet credit every interval until
+// ctx is cancelled
+func StartWalletExpiryScheduler(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if expired, err := wallet.Expire(ctx); err != nil {
+				logger.Error("Failed to expire wallet credit", zap.Error(err))
+			} else if expired > 0 {
+				logger.Info("Expired promotional wallet credit", zap.Int("credits", expired))
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// GetWallet returns the current user's wallet balance
+func GetWallet(c *gin.Context) {
+	// Check if MongoDB is connected
+	if db.GetMongoDB() == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	userID := getUserIDFromContext(c)
+	if userID.IsZero() {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	balance, err := wallet.Balance(context.Background(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch wallet"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"wallet": balance})
+}
+
+// GetWalletStatement returns a page of the current user's wallet transactions, newest first
+func GetWalletStatement(c *gin.Context) {
+	// Check if MongoDB is connected
+	if db.GetMongoDB() == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	userID := getUserIDFromContext(c)
+	if userID.IsZero() {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	respondWalletStatement(c, userID)
+}
+
+// GetUserWallet returns a customer's wallet balance and a page of its statement (admin only)
+func GetUserWallet(c *gin.Context) {
+	// Check if MongoDB is connected
+	if db.GetMongoDB() == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	respondWalletStatement(c, userID)
+}
+
+// CreditWallet adds goodwill credit to a customer's wallet (admin only). Promotional credit
+// expires at expires_at, or WALLET_PROMO_CREDIT_DAYS after it is issued.
+func CreditWallet(c *gin.Context) {
+	adjustWallet(c, models.WalletKindCredit)
+}
+
+// DebitWallet takes credit back from a customer's wallet (admin only)
+func DebitWallet(c *gin.Context) {
+	adjustWallet(c, models.WalletKindDebit)
+}
+
+// adjustWallet credits or debits a customer's wallet for support and notifies the customer
+func adjustWallet(c *gin.Context, kind string) {
+	// Check if MongoDB is connected
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Database not available",
+			"message": "MongoDB connection is not established",
+		})
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req models.WalletAdjustmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !req.Amount.IsPositive() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Amount must be more than zero"})
+		return
+	}
+
+	var expiresAt *time.Time
+	if kind == models.WalletKindCredit && (req.Promotional || req.ExpiresAt != nil) {
+		expires := time.Now().AddDate(0, 0, config.Load().WalletPromoCreditDays)
+		if req.ExpiresAt != nil {
+			expires = *req.ExpiresAt
+		}
+		if !expires.After(time.Now()) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Expiry must be in the future"})
+			return
+		}
+		expiresAt = &expires
+	}
+
+	count, err := mongoDB.Collection("users").CountDocuments(context.Background(), bson.M{"_id": userID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if count == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	createdBy := bookingActor{role: actorAdmin, id: getUserIDFromContext(c)}.String()
+	var transaction *models.WalletTransaction
+	err = db.WithTransaction(context.Background(), func(ctx context.Context) error {
+		var err error
+		notification := models.Notification{UserID: userID, Type: "wallet"}
+		if kind == models.WalletKindCredit {
+			transaction, err = wallet.Grant(ctx, userID, req.Amount, expiresAt, req.Reason, createdBy)
+			notification.Title = "Wallet Credited"
+			notification.Message = req.Amount.String() + " has been added to your wallet: " + req.Reason
+		} else {
+			transaction, err = wallet.Withdraw(ctx, userID, req.Amount, req.Reason, createdBy)
+			notification.Title = "Wallet Debited"
+			notification.Message = req.Amount.String() + " has been taken from your wallet: " + req.Reason
+		}
+		if err != nil {
+			return err
+		}
+		return outbox.EnqueueNotification(ctx, notification)
+	})
+	if err != nil {
+		if errors.Is(err, wallet.ErrInsufficientFunds) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Wallet balance is too low"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update wallet"})
+		return
+	}
+	outbox.Wake()
+
+	balance, err := wallet.Balance(context.Background(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch wallet"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":     "Wallet updated successfully",
+		"transaction": transaction,
+		"wallet":      balance,
+	})
+}
+
+// respondWalletStatement writes a wallet's balance and the page of its statement asked for
+func respondWalletStatement(c *gin.Context, userID primitive.ObjectID) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	balance, err := wallet.Balance(context.Background(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch wallet"})
+		return
+	}
+	lines, total, err := wallet.Statement(context.Background(), userID, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch wallet statement"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"wallet":       balance,
+		"transactions": lines,
+		"total":        total,
+		"page":         page,
+		"limit":        limit,
+	})
+}
+
+// payBookingFromWallet holds a booking's total in the customer's wallet, which authorises the
+// payment like a card would, and confirms the booking if it is pending. Run it inside a
+// transaction.
+func payBookingFromWallet(ctx context.Context, bookingID, userID primitive.ObjectID) (*models.Booking, error) {
+	collection := db.GetMongoDB().Collection("bookings")
+	now := time.Now()
+
+	// The booking is read again in the transaction, so it cannot have been paid or cancelled since
+	var booking models.Booking
+	err := collection.FindOne(ctx, bson.M{
+		"_id":            bookingID,
+		"user_id":        userID,
+		"status":         bson.M{"$in": []string{models.BookingStatusPending, models.BookingStatusConfirmed}},
+		"payment_status": bson.M{"$in": []string{models.PaymentStatusPending, models.PaymentStatusFailed}},
+	}).Decode(&booking)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errBookingStatusConflict
+		}
+		return nil, err
+	}
+
+	// Hold the money before the booking says it is paid: without transactions a wallet that is
+	// too low must leave the booking unpaid
+	reference := wallet.Reference("booking", booking.ID)
+	if _, err := wallet.Hold(ctx, booking.UserID, booking.TotalAmount, reference, "Booking on "+localBookingTime(booking)); err != nil {
+		return nil, err
+	}
+
+	payment := models.BookingPayment{
+		Provider:  wallet.Provider,
+		Amount:    booking.TotalAmount,
+		Captured:  money.New(0, booking.TotalAmount.Currency),
+		Refunded:  money.New(0, booking.TotalAmount.Currency),
+		UpdatedAt: now,
+	}
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": booking.ID, "payment_status": bson.M{"$in": []string{models.PaymentStatusPending, models.PaymentStatusFailed}}},
+		bson.M{"$set": bson.M{
+			"payment":        payment,
+			"payment_status": models.PaymentStatusAuthorized,
+			"updated_at":     now,
+		}},
+	)
+	if err == nil && result.MatchedCount == 0 {
+		err = errBookingStatusConflict
+	}
+	if err != nil {
+		// Paid some other way meanwhile; outside a transaction the hold is not rolled back
+		wallet.Capture(ctx, booking.UserID, reference, money.New(0, booking.TotalAmount.Currency), "Booking on "+localBookingTime(booking))
+		return nil, err
+	}
+	booking.Payment = &payment
+	booking.PaymentStatus = models.PaymentStatusAuthorized
+
+	if booking.Status == models.BookingStatusPending {
+		actor := bookingActor{role: actorSystem}
+		if _, err := transitionBooking(ctx, bson.M{"_id": booking.ID}, models.BookingStatusConfirmed, actor, "Payment received"); err != nil {
+			return nil, err
+		}
+		booking.Status = models.BookingStatusConfirmed
+	}
+	return &booking, outbox.EnqueueNotification(ctx, models.Notification{
+		UserID:  booking.UserID,
+		Title:   "Payment Received",
+		Message: "We took " + booking.TotalAmount.String() + " from your wallet for your booking on " + localBookingTime(booking) + ", it is now confirmed",
+		Type:    "payment",
+	})
+}
+
+// settleWalletPayment carries out a payment action on a booking paid from the wallet straight
+// away, in the transaction that changes the booking: the money held is captured and the rest
+// released, or what was captured is refunded into the wallet.
+func settleWalletPayment(ctx context.Context, booking models.Booking, action models.PaymentAction) error {
+	collection := db.GetMongoDB().Collection("bookings")
+	reference := wallet.Reference("booking", booking.ID)
+	now := time.Now()
+
+	switch action.Action {
+	case models.PaymentActionCapture, models.PaymentActionCancel:
+		charge := action.Amount
+		if action.Action == models.PaymentActionCancel {
+			charge = money.New(0, booking.TotalAmount.Currency)
+		}
+		if _, err := wallet.Capture(ctx, booking.UserID, reference, charge, "Booking on "+localBookingTime(booking)); err != nil {
+			return err
+		}
+		set := bson.M{"payment.captured": charge, "payment.updated_at": now}
+		if _, err := collection.UpdateOne(ctx, bson.M{"_id": booking.ID}, bson.M{"$set": set}); err != nil {
+			return err
+		}
+		// A booking that was finished, rather than cancelled, is now paid
+		_, err := collection.UpdateOne(ctx,
+			bson.M{"_id": booking.ID, "payment_status": models.PaymentStatusAuthorized},
+			bson.M{"$set": bson.M{"payment_status": models.PaymentStatusPaid, "updated_at": now}},
+		)
+		return err
+
+	case models.PaymentActionRefund:
+		refund, err := wallet.Refund(ctx, booking.UserID, reference, action.Amount, "Refund of booking on "+localBookingTime(booking))
+		if err != nil || refund == nil {
+			return err
+		}
+		// The refund is what the sales account gave back, which is never more than was captured
+		returned := int64(0)
+		for _, entry := range refund.Entries {
+			if entry.Account == wallet.AccountSales {
+				returned -= entry.Amount.Minor
+			}
+		}
+		_, err = collection.UpdateOne(ctx, bson.M{"_id": booking.ID}, bson.M{
+			"$inc": bson.M{"payment.refunded.minor": returned},
+			"$set": bson.M{"payment.updated_at": now},
+		})
+		return err
+	}
+	return nil
+}
+
+// refundCardToWallet pays the refund of a booking paid by card into the customer's wallet instead
+// of back to the card. Run it inside the transaction that cancels the booking.
+func refundCardToWallet(ctx context.Context, booking models.Booking, action models.PaymentAction) error {
+	reference := wallet.Reference("booking", booking.ID)
+	if _, err := wallet.CreditRefund(ctx, booking.UserID, action.Amount, reference, "Refund of booking on "+localBookingTime(booking)); err != nil {
+		return err
+	}
+	_, err := db.GetMongoDB().Collection("bookings").UpdateOne(ctx, bson.M{"_id": booking.ID}, bson.M{
+		"$max": bson.M{"payment.refunded.minor": action.Amount.Minor},
+		"$set": bson.M{"payment.updated_at": time.Now()},
+	})
+	return err
+}
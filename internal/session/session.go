@@ -0,0 +1,43 @@
+package session
+
+import (
+	"context"
+	"errors"
+
+	"github.com/code-harsh006/food-delivery/internal/models"
+	"github.com/code-harsh006/food-delivery/pkg/db"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrNotAuthenticated is returned when the request carries no authenticated user
+var ErrNotAuthenticated = errors.New("authentication required")
+
+// UserID returns the MongoDB ID of the user authenticated by middleware.AuthMiddleware.
+// Tokens identify users by email, so the ID is looked up in the users collection.
+func UserID(c *gin.Context) (primitive.ObjectID, error) {
+	email := c.GetString("user_email")
+	if email == "" {
+		return primitive.NilObjectID, ErrNotAuthenticated
+	}
+
+	mongoDB := db.GetMongoDB()
+	if mongoDB == nil {
+		return primitive.NilObjectID, errors.New("database not available")
+	}
+
+	var user models.User
+	err := mongoDB.Collection("users").FindOne(context.Background(), bson.M{"email": email},
+		options.FindOne().SetProjection(bson.M{"_id": 1})).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return primitive.NilObjectID, ErrNotAuthenticated
+		}
+		return primitive.NilObjectID, err
+	}
+
+	return user.ID, nil
+}
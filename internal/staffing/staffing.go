@@ -0,0 +1,211 @@
+package staffing
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/code-harsh006/food-delivery/internal/availability"
+	"github.com/code-harsh006/food-delivery/internal/localtime"
+	"github.com/code-harsh006/food-delivery/internal/models"
+	"github.com/code-harsh006/food-delivery/pkg/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// defaultJobMinutes is how long a technician is booked for when the service has no duration
+const defaultJobMinutes = 60
+
+var (
+	ErrTechnicianNotFound = errors.New("technician not found")
+	ErrNoTechnician       = errors.New("no technician is available for this booking")
+	ErrWrongVendor        = errors.New("the technician does not work for the service's vendor")
+	ErrMissingSkills      = errors.New("the technician does not have the skills the service needs")
+	ErrOffDuty            = errors.New("the booking is outside the technician's working hours or during time off")
+	ErrDoubleBooked       = errors.New("the technician already has a job at this time")
+)
+
+// activeStatuses are the booking statuses that keep a technician busy
+var activeStatuses = []string{
+	models.BookingStatusPending,
+	models.BookingStatusConfirmed,
+	models.BookingStatusInProgress,
+}
+
+// Window returns the time a booking occupies its technician: from its start for the length of
+// all its services and add-ons, or the service's duration for older bookings, in the booking's
+// timezone
+func Window(booking models.Booking, service models.Service) (time.Time, time.Time) {
+	loc := localtime.Location(booking.Timezone)
+	start := booking.ScheduledAt.In(loc)
+
+	duration := service.Duration
+	if booking.Duration > 0 {
+		duration = booking.Duration
+	}
+	if duration <= 0 {
+		duration = defaultJobMinutes
+	}
+	return start, start.Add(time.Duration(duration) * time.Minute)
+}
+
+// Reserve checks that a technician can take a booking and locks their schedule for the rest of
+// the transaction: the technician's schedule version is bumped first, so a concurrent
+// transaction assigning the same technician conflicts and is retried against the new state.
+// Run it inside the db.WithTransaction that stores the assignment on the booking.
+func Reserve(ctx context.Context, technicianID primitive.ObjectID, booking models.Booking, service models.Service) (*models.Technician, error) {
+	var technician models.Technician
+	err := db.GetMongoDB().Collection("technicians").FindOneAndUpdate(ctx,
+		bson.M{"_id": technicianID, "is_active": true},
+		bson.M{"$inc": bson.M{"schedule_version": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&technician)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrTechnicianNotFound
+		}
+		return nil, err
+	}
+
+	if !service.VendorID.IsZero() && technician.VendorID != service.VendorID {
+		return nil, ErrWrongVendor
+	}
+	if !HasSkills(technician, service.Skills) {
+		return nil, ErrMissingSkills
+	}
+
+	start, end := Window(booking, service)
+	if !OnDuty(technician, start, end) {
+		return nil, ErrOffDuty
+	}
+
+	busy, err := db.GetMongoDB().Collection("bookings").CountDocuments(ctx, bson.M{
+		"_id":           bson.M{"$ne": booking.ID},
+		"technician_id": technician.ID,
+		"status":        bson.M{"$in": activeStatuses},
+		"job_start":     bson.M{"$lt": end},
+		"job_end":       bson.M{"$gt": start},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if busy > 0 {
+		return nil, ErrDoubleBooked
+	}
+
+	return &technician, nil
+}
+
+// Candidates lists the active technicians of the service's vendor with the skills it needs,
+// those with the fewest jobs on the booking's day first
+func Candidates(ctx context.Context, booking models.Booking, service models.Service) ([]models.Technician, error) {
+	if service.VendorID.IsZero() {
+		return nil, nil
+	}
+
+	filter := bson.M{"vendor_id": service.VendorID, "is_active": true}
+	if len(service.Skills) > 0 {
+		filter["skills"] = bson.M{"$all": service.Skills}
+	}
+
+	var technicians []models.Technician
+	cursor, err := db.GetMongoDB().Collection("technicians").Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &technicians); err != nil {
+		return nil, err
+	}
+
+	dayStart := localtime.Midnight(booking.LocalDate(), localtime.Location(booking.Timezone))
+	dayEnd := dayStart.AddDate(0, 0, 1)
+	load := make(map[primitive.ObjectID]int64, len(technicians))
+	for _, technician := range technicians {
+		jobs, err := db.GetMongoDB().Collection("bookings").CountDocuments(ctx, bson.M{
+			"technician_id": technician.ID,
+			"status":        bson.M{"$in": activeStatuses},
+			"job_start":     bson.M{"$gte": dayStart, "$lt": dayEnd},
+		})
+		if err != nil {
+			return nil, err
+		}
+		load[technician.ID] = jobs
+	}
+
+	sort.SliceStable(technicians, func(i, j int) bool {
+		if load[technicians[i].ID] != load[technicians[j].ID] {
+			return load[technicians[i].ID] < load[technicians[j].ID]
+		}
+		return technicians[i].Name < technicians[j].Name
+	})
+	return technicians, nil
+}
+
+// HasSkills reports whether a technician has every one of the skills
+func HasSkills(technician models.Technician, skills []string) bool {
+	for _, skill := range skills {
+		found := false
+		for _, have := range technician.Skills {
+			if have == skill {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// OnDuty reports whether the time from start to end falls inside one of the technician's
+// working hours on that weekday and does not overlap any of their time off. Working hours are
+// read in the timezone start is in.
+func OnDuty(technician models.Technician, start, end time.Time) bool {
+	for _, off := range technician.TimeOff {
+		if start.Before(off.End) && end.After(off.Start) {
+			return false
+		}
+	}
+
+	// Minutes after midnight; a job running past midnight ends after every working window closes
+	from := start.Hour()*60 + start.Minute()
+	to := from + int(end.Sub(start)/time.Minute)
+
+	for _, hours := range technician.WorkingHours {
+		if hours.Weekday != int(start.Weekday()) {
+			continue
+		}
+		open, err := availability.ParseClock(hours.StartTime)
+		if err != nil {
+			continue
+		}
+		closing, err := availability.ParseClock(hours.EndTime)
+		if err != nil {
+			continue
+		}
+		if from >= open && to <= closing {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeSkills lower-cases and trims skill names, dropping blanks and duplicates
+func NormalizeSkills(skills []string) []string {
+	normalized := make([]string, 0, len(skills))
+	seen := make(map[string]bool)
+	for _, skill := range skills {
+		skill = strings.ToLower(strings.TrimSpace(skill))
+		if skill == "" || seen[skill] {
+			continue
+		}
+		seen[skill] = true
+		normalized = append(normalized, skill)
+	}
+	return normalized
+}
@@ -0,0 +1,106 @@
+package waitlist
+
+import (
+	"context"
+	"time"
+
+	"github.com/code-harsh006/food-delivery/internal/availability"
+	"github.com/code-harsh006/food-delivery/internal/models"
+	"github.com/code-harsh006/food-delivery/pkg/config"
+	"github.com/code-harsh006/food-delivery/pkg/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Handoff passes slot capacity a booking or hold no longer needs to the customers waiting for it.
+// Entries are offered in the order they joined when every slot their booking needs is among ids;
+// each offered entry keeps its slots held until the hold expires, and whatever is left over is
+// released. Run it inside the transaction that frees the capacity and notify the returned entries.
+func Handoff(ctx context.Context, ids []string) ([]models.WaitlistEntry, error) {
+	collection := db.GetMongoDB().Collection("waitlist")
+	hold := time.Duration(config.Load().WaitlistHoldMinutes) * time.Minute
+
+	remaining := append([]string(nil), ids...)
+	offered := make([]models.WaitlistEntry, 0)
+	for len(remaining) > 0 {
+		now := time.Now()
+		var entry models.WaitlistEntry
+		err := collection.FindOne(ctx,
+			bson.M{
+				"status":       models.WaitlistStatusWaiting,
+				"scheduled_at": bson.M{"$gt": now},
+				"slots.0":      bson.M{"$exists": true},
+				"slots":        bson.M{"$not": bson.M{"$elemMatch": bson.M{"$nin": remaining}}},
+			},
+			options.FindOne().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}),
+		).Decode(&entry)
+		if err == mongo.ErrNoDocuments {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// A hold never outlasts the start of the slot it is for
+		expires := now.Add(hold)
+		if entry.ScheduledAt.Before(expires) {
+			expires = entry.ScheduledAt
+		}
+		result, err := collection.UpdateOne(ctx,
+			bson.M{"_id": entry.ID, "status": models.WaitlistStatusWaiting},
+			bson.M{"$set": bson.M{
+				"status":          models.WaitlistStatusOffered,
+				"hold_expires_at": expires,
+				"updated_at":      now,
+			}},
+		)
+		if err != nil {
+			return nil, err
+		}
+		if result.MatchedCount == 0 {
+			continue
+		}
+
+		entry.Status = models.WaitlistStatusOffered
+		entry.HoldExpiresAt = &expires
+		offered = append(offered, entry)
+		remaining = without(remaining, entry.Slots)
+	}
+
+	if err := availability.Release(ctx, remaining); err != nil {
+		return nil, err
+	}
+	return offered, nil
+}
+
+// Position returns a waiting entry's place in the queue for its slot, starting at 1
+func Position(ctx context.Context, entry models.WaitlistEntry) (int64, error) {
+	ahead, err := db.GetMongoDB().Collection("waitlist").CountDocuments(ctx, bson.M{
+		"status": models.WaitlistStatusWaiting,
+		"slots":  entry.Slots,
+		"$or": []bson.M{
+			{"created_at": bson.M{"$lt": entry.CreatedAt}},
+			{"created_at": entry.CreatedAt, "_id": bson.M{"$lt": entry.ID}},
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return ahead + 1, nil
+}
+
+// without returns ids minus the ones in taken
+func without(ids, taken []string) []string {
+	skip := make(map[string]bool, len(taken))
+	for _, id := range taken {
+		skip[id] = true
+	}
+	kept := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if !skip[id] {
+			kept = append(kept, id)
+		}
+	}
+	return kept
+}
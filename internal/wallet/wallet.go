@@ -0,0 +1,619 @@
+// Package wallet keeps customers' store credit in an append-only, double-entry ledger. Balances are
+// never stored: they are the sum of the entries on each account.
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/code-harsh006/food-delivery/internal/models"
+	"github.com/code-harsh006/food-delivery/pkg/db"
+	"github.com/code-harsh006/food-delivery/pkg/money"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Provider is the payment provider recorded on bookings paid from the wallet
+const Provider = "wallet"
+
+// System accounts, where the money in wallets comes from and goes to
+const (
+	AccountGoodwill    = "goodwill"    // credit issued by support
+	AccountAdjustments = "adjustments" // credit taken back by support
+	AccountRefunds     = "refunds"     // purchases paid some other way and refunded into wallets
+	AccountSales       = "sales"       // purchases paid from wallets
+	AccountExpired     = "expired"     // promotional credit that was not used in time
+)
+
+// A customer's accounts
+const (
+	accountCash = "cash"
+	promoPrefix = "promo:"
+	holdPrefix  = "hold:"
+)
+
+var (
+	// ErrInsufficientFunds is returned when a wallet does not hold enough for a payment or debit
+	ErrInsufficientFunds = errors.New("insufficient wallet balance")
+	// ErrInvalidAmount is returned for amounts that are not more than zero
+	ErrInvalidAmount = errors.New("amount must be more than zero")
+)
+
+// account is the balance of one of a customer's accounts
+type account struct {
+	Name      string     `bson:"_id"`
+	Minor     int64      `bson:"minor"`
+	ExpiresAt *time.Time `bson:"expires_at"`
+}
+
+// Reference returns the reference wallet transactions of a purchase carry, e.g. booking:64f1...
+func Reference(kind string, id primitive.ObjectID) string {
+	return kind + ":" + id.Hex()
+}
+
+// Balance returns what a customer's wallet holds. Promotional credit past its expiry is left out
+// even before it has been taken back.
+func Balance(ctx context.Context, userID primitive.ObjectID) (*models.WalletBalance, error) {
+	accounts, err := balances(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	balance := &models.WalletBalance{
+		Currency:    money.Currency(),
+		Cash:        amount(0),
+		Promotional: amount(0),
+		Held:        amount(0),
+		Expiring:    []models.WalletCredit{},
+	}
+	for _, a := range accounts {
+		switch {
+		case a.Name == accountCash:
+			balance.Cash = balance.Cash.Add(amount(a.Minor))
+		case strings.HasPrefix(a.Name, holdPrefix):
+			balance.Held = balance.Held.Add(amount(a.Minor))
+		case spendablePromo(a, now):
+			balance.Promotional = balance.Promotional.Add(amount(a.Minor))
+			balance.Expiring = append(balance.Expiring, models.WalletCredit{Amount: amount(a.Minor), ExpiresAt: *a.ExpiresAt})
+		}
+	}
+	sort.SliceStable(balance.Expiring, func(i, j int) bool {
+		return balance.Expiring[i].ExpiresAt.Before(balance.Expiring[j].ExpiresAt)
+	})
+	balance.Available = balance.Cash.Add(balance.Promotional)
+	return balance, nil
+}
+
+// Grant credits a customer's wallet on behalf of support. Credit with an expiry is promotional:
+// it is spent before cash, and whatever is left of it when it expires is taken back. Run it
+// inside db.WithTransaction, like every change to a wallet.
+func Grant(ctx context.Context, userID primitive.ObjectID, credit money.Money, expiresAt *time.Time, description, createdBy string) (*models.WalletTransaction, error) {
+	if !credit.IsPositive() {
+		return nil, ErrInvalidAmount
+	}
+	if err := lock(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	id := primitive.NewObjectID()
+	entry := models.WalletEntry{UserID: userID, Account: accountCash, Amount: credit}
+	if expiresAt != nil {
+		entry.Account = promoPrefix + id.Hex()
+		entry.ExpiresAt = expiresAt
+	}
+	return post(ctx, models.WalletTransaction{
+		ID:          id,
+		UserID:      userID,
+		Kind:        models.WalletKindCredit,
+		Description: description,
+		CreatedBy:   createdBy,
+		Entries:     []models.WalletEntry{entry, {Account: AccountGoodwill, Amount: credit.Neg()}},
+	})
+}
+
+// Withdraw takes credit back from a customer's wallet on behalf of support, promotional credit
+// first
+func Withdraw(ctx context.Context, userID primitive.ObjectID, debit money.Money, description, createdBy string) (*models.WalletTransaction, error) {
+	if !debit.IsPositive() {
+		return nil, ErrInvalidAmount
+	}
+	entries, err := draw(ctx, userID, debit)
+	if err != nil {
+		return nil, err
+	}
+	return post(ctx, models.WalletTransaction{
+		UserID:      userID,
+		Kind:        models.WalletKindDebit,
+		Description: description,
+		CreatedBy:   createdBy,
+		Entries:     append(entries, models.WalletEntry{Account: AccountAdjustments, Amount: debit}),
+	})
+}
+
+// CreditRefund pays the refund of a purchase that was paid some other way, e.g. by card, into
+// the customer's wallet as cash
+func CreditRefund(ctx context.Context, userID primitive.ObjectID, refund money.Money, reference, description string) (*models.WalletTransaction, error) {
+	if !refund.IsPositive() {
+		return nil, ErrInvalidAmount
+	}
+	if err := lock(ctx, userID); err != nil {
+		return nil, err
+	}
+	return post(ctx, models.WalletTransaction{
+		UserID:      userID,
+		Kind:        models.WalletKindRefund,
+		Reference:   reference,
+		Description: description,
+		Entries: []models.WalletEntry{
+			{UserID: userID, Account: accountCash, Amount: refund},
+			{Account: AccountRefunds, Amount: refund.Neg()},
+		},
+	})
+}
+
+// Pay takes a purchase's price from a customer's wallet, promotional credit first. It fails with
+// ErrInsufficientFunds when the wallet does not hold enough.
+func Pay(ctx context.Context, userID primitive.ObjectID, price money.Money, reference, description string) (*models.WalletTransaction, error) {
+	if !price.IsPositive() {
+		return nil, ErrInvalidAmount
+	}
+	entries, err := draw(ctx, userID, price)
+	if err != nil {
+		return nil, err
+	}
+	return post(ctx, models.WalletTransaction{
+		UserID:      userID,
+		Kind:        models.WalletKindPayment,
+		Reference:   reference,
+		Description: description,
+		Entries:     append(entries, models.WalletEntry{Account: AccountSales, Amount: price}),
+	})
+}
+
+// Hold sets a purchase's price aside in a customer's wallet until it is captured or released,
+// taking promotional credit first. A purchase is held for once.
+func Hold(ctx context.Context, userID primitive.ObjectID, price money.Money, reference, description string) (*models.WalletTransaction, error) {
+	if !price.IsPositive() {
+		return nil, ErrInvalidAmount
+	}
+	entries, err := draw(ctx, userID, price)
+	if err != nil {
+		return nil, err
+	}
+	return post(ctx, models.WalletTransaction{
+		UserID:      userID,
+		Kind:        models.WalletKindHold,
+		Reference:   reference,
+		Description: description,
+		Entries:     append(entries, models.WalletEntry{UserID: userID, Account: holdPrefix + reference, Amount: price}),
+	})
+}
+
+// Capture takes up to charge of the money held for a purchase and gives the rest back to the
+// accounts it was held from; the promotional credit that was held is taken first. A charge of
+// zero releases everything. Nothing happens when nothing is held, so settling twice is harmless.
+func Capture(ctx context.Context, userID primitive.ObjectID, reference string, charge money.Money, description string) (*models.WalletTransaction, error) {
+	if err := lock(ctx, userID); err != nil {
+		return nil, err
+	}
+	accounts, err := balances(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	held := int64(0)
+	for _, a := range accounts {
+		if a.Name == holdPrefix+reference {
+			held = a.Minor
+		}
+	}
+	if held <= 0 {
+		return nil, nil
+	}
+
+	var hold models.WalletTransaction
+	err = db.GetMongoDB().Collection("wallet_transactions").FindOne(ctx, bson.M{
+		"user_id":   userID,
+		"kind":      models.WalletKindHold,
+		"reference": reference,
+	}).Decode(&hold)
+	if err != nil {
+		return nil, err
+	}
+
+	take := min(max(charge.Minor, 0), held)
+	kind := models.WalletKindCapture
+	if take == 0 {
+		kind = models.WalletKindRelease
+	}
+	entries := []models.WalletEntry{
+		{UserID: userID, Account: holdPrefix + reference, Amount: amount(-held)},
+		{Account: AccountSales, Amount: amount(take)},
+	}
+	// The hold's entries list the accounts in the order they were drawn from
+	for _, source := range hold.Entries {
+		if source.UserID != userID || !source.Amount.IsNegative() {
+			continue
+		}
+		taken := min(-source.Amount.Minor, take)
+		take -= taken
+		entries = append(entries, models.WalletEntry{
+			UserID:    userID,
+			Account:   source.Account,
+			Amount:    amount(-source.Amount.Minor - taken),
+			ExpiresAt: source.ExpiresAt,
+		})
+	}
+
+	return post(ctx, models.WalletTransaction{
+		UserID:      userID,
+		Kind:        kind,
+		Reference:   reference,
+		Description: description,
+		Entries:     entries,
+	})
+}
+
+// Refund gives back up to refund of what a customer paid from their wallet for a purchase, to the
+// accounts it was paid from: cash first, then promotional credit, which keeps its expiry date.
+func Refund(ctx context.Context, userID primitive.ObjectID, reference string, refund money.Money, description string) (*models.WalletTransaction, error) {
+	if !refund.IsPositive() {
+		return nil, ErrInvalidAmount
+	}
+	if err := lock(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	cursor, err := db.GetMongoDB().Collection("wallet_transactions").Find(ctx, bson.M{"user_id": userID, "reference": reference})
+	if err != nil {
+		return nil, err
+	}
+	var transactions []models.WalletTransaction
+	if err := cursor.All(ctx, &transactions); err != nil {
+		return nil, err
+	}
+
+	// What each account has paid for the purchase so far, net of holds and earlier refunds
+	paid := map[string]*account{}
+	var order []*account
+	for _, transaction := range transactions {
+		for _, entry := range transaction.Entries {
+			if entry.UserID != userID || strings.HasPrefix(entry.Account, holdPrefix) {
+				continue
+			}
+			a, ok := paid[entry.Account]
+			if !ok {
+				a = &account{Name: entry.Account, ExpiresAt: entry.ExpiresAt}
+				paid[entry.Account] = a
+				order = append(order, a)
+			}
+			a.Minor -= entry.Amount.Minor
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return refundsBefore(*order[i], *order[j])
+	})
+
+	remaining := refund.Minor
+	var entries []models.WalletEntry
+	for _, a := range order {
+		if remaining == 0 {
+			break
+		}
+		back := min(max(a.Minor, 0), remaining)
+		if back == 0 {
+			continue
+		}
+		remaining -= back
+		entries = append(entries, models.WalletEntry{UserID: userID, Account: a.Name, Amount: amount(back), ExpiresAt: a.ExpiresAt})
+	}
+	returned := refund.Minor - remaining
+	if returned == 0 {
+		return nil, nil
+	}
+
+	return post(ctx, models.WalletTransaction{
+		UserID:      userID,
+		Kind:        models.WalletKindRefund,
+		Reference:   reference,
+		Description: description,
+		Entries:     append(entries, models.WalletEntry{Account: AccountSales, Amount: amount(-returned)}),
+	})
+}
+
+// Expire takes back what is left of promotional credit past its expiry date and returns how many
+// credits it took back. Each one is taken back in its own transaction.
+func Expire(ctx context.Context) (int, error) {
+	collection := db.GetMongoDB().Collection("wallet_transactions")
+	now := time.Now()
+
+	cursor, err := collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"entries.expires_at": bson.M{"$lte": now}}}},
+		{{Key: "$unwind", Value: "$entries"}},
+		{{Key: "$match", Value: bson.M{"entries.expires_at": bson.M{"$lte": now}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"user_id": "$entries.user_id", "account": "$entries.account"},
+			"minor": bson.M{"$sum": "$entries.amount.minor"},
+		}}},
+		{{Key: "$match", Value: bson.M{"minor": bson.M{"$gt": 0}}}},
+	})
+	if err != nil {
+		return 0, err
+	}
+	var lapsed []struct {
+		ID struct {
+			UserID  primitive.ObjectID `bson:"user_id"`
+			Account string             `bson:"account"`
+		} `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &lapsed); err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, credit := range lapsed {
+		userID, name := credit.ID.UserID, credit.ID.Account
+		taken := false
+		err := db.WithTransaction(ctx, func(ctx context.Context) error {
+			taken = false
+			if err := lock(ctx, userID); err != nil {
+				return err
+			}
+			accounts, err := balances(ctx, userID)
+			if err != nil {
+				return err
+			}
+			for _, a := range accounts {
+				if a.Name != name || a.Minor <= 0 {
+					continue
+				}
+				_, err := post(ctx, models.WalletTransaction{
+					UserID:      userID,
+					Kind:        models.WalletKindExpiry,
+					Description: "Promotional credit expired",
+					Entries: []models.WalletEntry{
+						{UserID: userID, Account: a.Name, Amount: amount(-a.Minor), ExpiresAt: a.ExpiresAt},
+						{Account: AccountExpired, Amount: amount(a.Minor)},
+					},
+				})
+				taken = err == nil
+				return err
+			}
+			return nil
+		})
+		if err != nil {
+			return expired, err
+		}
+		if taken {
+			expired++
+		}
+	}
+	return expired, nil
+}
+
+// Statement returns one page of a customer's wallet transactions, newest first, and how many
+// there are. Each line's balance is what the wallet held, holds aside, once it was made.
+func Statement(ctx context.Context, userID primitive.ObjectID, page, limit int) ([]models.WalletStatementLine, int64, error) {
+	collection := db.GetMongoDB().Collection("wallet_transactions")
+	filter := bson.M{"user_id": userID}
+
+	total, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	cursor, err := collection.Find(ctx, filter, options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(int64((page-1)*limit)).
+		SetLimit(int64(limit)))
+	if err != nil {
+		return nil, 0, err
+	}
+	var transactions []models.WalletTransaction
+	if err := cursor.All(ctx, &transactions); err != nil {
+		return nil, 0, err
+	}
+
+	lines := make([]models.WalletStatementLine, len(transactions))
+	if len(transactions) == 0 {
+		return lines, total, nil
+	}
+
+	// The balance before the oldest transaction on the page, carried forward line by line
+	oldest := transactions[len(transactions)-1]
+	balance, err := balanceBefore(ctx, userID, oldest)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := len(transactions) - 1; i >= 0; i-- {
+		transaction := transactions[i]
+		line := models.WalletStatementLine{
+			ID:          transaction.ID,
+			Kind:        transaction.Kind,
+			Reference:   transaction.Reference,
+			Description: transaction.Description,
+			Amount:      amount(0),
+			Held:        amount(0),
+			CreatedAt:   transaction.CreatedAt,
+		}
+		for _, entry := range transaction.Entries {
+			switch {
+			case entry.UserID != userID:
+			case strings.HasPrefix(entry.Account, holdPrefix):
+				line.Held = line.Held.Add(entry.Amount)
+			default:
+				line.Amount = line.Amount.Add(entry.Amount)
+			}
+		}
+		balance = balance.Add(line.Amount)
+		line.Balance = balance
+		lines[i] = line
+	}
+	return lines, total, nil
+}
+
+// balanceBefore returns what a customer's wallet held, holds aside, before a transaction was made
+func balanceBefore(ctx context.Context, userID primitive.ObjectID, transaction models.WalletTransaction) (money.Money, error) {
+	cursor, err := db.GetMongoDB().Collection("wallet_transactions").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"user_id": userID,
+			"$or": []bson.M{
+				{"created_at": bson.M{"$lt": transaction.CreatedAt}},
+				{"created_at": transaction.CreatedAt, "_id": bson.M{"$lt": transaction.ID}},
+			},
+		}}},
+		{{Key: "$unwind", Value: "$entries"}},
+		{{Key: "$match", Value: bson.M{
+			"entries.user_id": userID,
+			"entries.account": bson.M{"$not": primitive.Regex{Pattern: "^" + holdPrefix}},
+		}}},
+		{{Key: "$group", Value: bson.M{"_id": nil, "minor": bson.M{"$sum": "$entries.amount.minor"}}}},
+	})
+	if err != nil {
+		return money.Money{}, err
+	}
+	var sums []struct {
+		Minor int64 `bson:"minor"`
+	}
+	if err := cursor.All(ctx, &sums); err != nil {
+		return money.Money{}, err
+	}
+	if len(sums) == 0 {
+		return amount(0), nil
+	}
+	return amount(sums[0].Minor), nil
+}
+
+// balances returns the balance of each of a customer's accounts
+func balances(ctx context.Context, userID primitive.ObjectID) ([]account, error) {
+	cursor, err := db.GetMongoDB().Collection("wallet_transactions").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"user_id": userID}}},
+		{{Key: "$unwind", Value: "$entries"}},
+		{{Key: "$match", Value: bson.M{"entries.user_id": userID}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":        "$entries.account",
+			"minor":      bson.M{"$sum": "$entries.amount.minor"},
+			"expires_at": bson.M{"$max": "$entries.expires_at"},
+		}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var accounts []account
+	if err := cursor.All(ctx, &accounts); err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
+// draw locks a customer's wallet and returns the entries that take amount from it: promotional
+// credit that expires soonest first, then cash
+func draw(ctx context.Context, userID primitive.ObjectID, price money.Money) ([]models.WalletEntry, error) {
+	if err := lock(ctx, userID); err != nil {
+		return nil, err
+	}
+	accounts, err := balances(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var promos, cash []account
+	for _, a := range accounts {
+		switch {
+		case a.Minor <= 0:
+		case a.Name == accountCash:
+			cash = append(cash, a)
+		case spendablePromo(a, now):
+			promos = append(promos, a)
+		}
+	}
+	sort.SliceStable(promos, func(i, j int) bool {
+		return promos[i].ExpiresAt.Before(*promos[j].ExpiresAt)
+	})
+
+	remaining := price.Minor
+	var entries []models.WalletEntry
+	for _, a := range append(promos, cash...) {
+		if remaining == 0 {
+			break
+		}
+		taken := min(a.Minor, remaining)
+		remaining -= taken
+		entries = append(entries, models.WalletEntry{UserID: userID, Account: a.Name, Amount: amount(-taken), ExpiresAt: a.ExpiresAt})
+	}
+	if remaining > 0 {
+		return nil, ErrInsufficientFunds
+	}
+	return entries, nil
+}
+
+// lock marks a change to a customer's wallet. Two transactions that change the same wallet both
+// write its document, so one of them conflicts and is retried with the other's entries in view.
+func lock(ctx context.Context, userID primitive.ObjectID) error {
+	now := time.Now()
+	_, err := db.GetMongoDB().Collection("wallets").UpdateOne(ctx,
+		bson.M{"_id": userID},
+		bson.M{
+			"$inc":         bson.M{"sequence": 1},
+			"$set":         bson.M{"updated_at": now},
+			"$setOnInsert": bson.M{"created_at": now},
+		},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// post writes a transaction to the ledger, leaving out entries of zero
+func post(ctx context.Context, transaction models.WalletTransaction) (*models.WalletTransaction, error) {
+	entries := make([]models.WalletEntry, 0, len(transaction.Entries))
+	sum := int64(0)
+	for _, entry := range transaction.Entries {
+		if entry.Amount.IsZero() {
+			continue
+		}
+		sum += entry.Amount.Minor
+		entries = append(entries, entry)
+	}
+	if sum != 0 {
+		return nil, fmt.Errorf("wallet transaction does not balance: entries add up to %d", sum)
+	}
+
+	if transaction.ID.IsZero() {
+		transaction.ID = primitive.NewObjectID()
+	}
+	transaction.Entries = entries
+	transaction.CreatedAt = time.Now()
+	if _, err := db.GetMongoDB().Collection("wallet_transactions").InsertOne(ctx, transaction); err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
+
+// spendablePromo reports whether an account is promotional credit that can still be spent
+func spendablePromo(a account, now time.Time) bool {
+	return strings.HasPrefix(a.Name, promoPrefix) && a.ExpiresAt != nil && a.ExpiresAt.After(now)
+}
+
+// refundsBefore orders the accounts a refund goes back to: cash first, then promotional credit
+// that expires last
+func refundsBefore(a, b account) bool {
+	if a.Name == accountCash || b.Name == accountCash {
+		return a.Name == accountCash && b.Name != accountCash
+	}
+	if a.ExpiresAt == nil || b.ExpiresAt == nil {
+		return a.ExpiresAt == nil && b.ExpiresAt != nil
+	}
+	return a.ExpiresAt.After(*b.ExpiresAt)
+}
+
+// amount returns minor units of the configured currency
+func amount(minor int64) money.Money {
+	return money.New(minor, money.Currency())
+}
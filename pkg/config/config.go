@@ -3,6 +3,8 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
@@ -18,12 +20,15 @@ type Config struct {
 	Environment string
 
 	// Payment Configuration
-	StripeKey            string
-	StripePublishableKey string
-	StripeWebhookSecret  string
-	PayPalClientID       string
-	PayPalClientSecret   string
-	PayPalMode           string
+	PaymentProvider               string
+	StripeKey                     string
+	StripePublishableKey          string
+	StripeWebhookSecret           string
+	StripeAPIBaseURL              string
+	StripeWebhookToleranceSeconds int
+	PayPalClientID                string
+	PayPalClientSecret            string
+	PayPalMode                    string
 
 	// Map & Location Services
 	GoogleMapsAPIKey  string
@@ -84,6 +89,59 @@ type Config struct {
 	// Monitoring
 	SentryDSN          string
 	NewRelicLicenseKey string
+
+	// Recommendations
+	RecommendationWindowDays     int
+	RecommendationRefreshMinutes int
+	RecommendationLimit          int
+
+	// Cart
+	CartExpiryHours int
+
+	// Outbox
+	OutboxPollSeconds int
+	OutboxMaxAttempts int
+
+	// Pricing
+	Currency           string
+	DeliveryBaseFee    float64
+	DeliveryFeePerKM   float64
+	DeliveryIncludedKM float64
+	DeliveryZoneFees   map[string]float64
+	ServiceFeePercent  float64
+	ServiceFeeMinimum  float64
+	TaxRates           map[string]float64
+
+	// Idempotency
+	IdempotencyKeyTTLHours int
+
+	// Bookings
+	RescheduleCutoffHours int
+	RescheduleMaxCount    int
+
+	// Recurring bookings
+	SeriesHorizonDays    int
+	SeriesRefreshMinutes int
+
+	// Reviews
+	ReviewWindowDays int
+
+	// Waitlist
+	WaitlistHoldMinutes int
+	WaitlistPollSeconds int
+
+	// Reminders (before a booking starts)
+	ReminderOffsets           []time.Duration
+	TechnicianReminderOffsets []time.Duration
+	ReminderPollSeconds       int
+
+	// Wallet
+	WalletPromoCreditDays   int
+	WalletExpiryPollMinutes int
+
+	// Timezones (IANA names)
+	DefaultTimezone string
+	ZoneTimezones   map[string]string
 }
 
 func Load() *Config {
@@ -100,12 +158,15 @@ func Load() *Config {
 		Environment: getEnv("ENVIRONMENT", "development"),
 
 		// Payment Configuration
-		StripeKey:            getEnv("STRIPE_KEY", "sk_test_dummy"),
-		StripePublishableKey: getEnv("STRIPE_PUBLISHABLE_KEY", "pk_test_dummy"),
-		StripeWebhookSecret:  getEnv("STRIPE_WEBHOOK_SECRET", "whsec_dummy"),
-		PayPalClientID:       getEnv("PAYPAL_CLIENT_ID", ""),
-		PayPalClientSecret:   getEnv("PAYPAL_CLIENT_SECRET", ""),
-		PayPalMode:           getEnv("PAYPAL_MODE", "sandbox"),
+		PaymentProvider:               getEnv("PAYMENT_PROVIDER", "fake"),
+		StripeKey:                     getEnv("STRIPE_KEY", "sk_test_dummy"),
+		StripePublishableKey:          getEnv("STRIPE_PUBLISHABLE_KEY", "pk_test_dummy"),
+		StripeWebhookSecret:           getEnv("STRIPE_WEBHOOK_SECRET", ""),
+		StripeAPIBaseURL:              getEnv("STRIPE_API_BASE_URL", "https://api.stripe.com"),
+		StripeWebhookToleranceSeconds: getEnvAsInt("STRIPE_WEBHOOK_TOLERANCE_SECONDS", 300),
+		PayPalClientID:                getEnv("PAYPAL_CLIENT_ID", ""),
+		PayPalClientSecret:            getEnv("PAYPAL_CLIENT_SECRET", ""),
+		PayPalMode:                    getEnv("PAYPAL_MODE", "sandbox"),
 
 		// Map & Location Services
 		GoogleMapsAPIKey:  getEnv("GOOGLE_MAPS_API_KEY", ""),
@@ -166,6 +227,59 @@ func Load() *Config {
 		// Monitoring
 		SentryDSN:          getEnv("SENTRY_DSN", ""),
 		NewRelicLicenseKey: getEnv("NEW_RELIC_LICENSE_KEY", ""),
+
+		// Recommendations
+		RecommendationWindowDays:     getEnvAsInt("RECOMMENDATION_WINDOW_DAYS", 90),
+		RecommendationRefreshMinutes: getEnvAsInt("RECOMMENDATION_REFRESH_MINUTES", 60),
+		RecommendationLimit:          getEnvAsInt("RECOMMENDATION_LIMIT", 10),
+
+		// Cart
+		CartExpiryHours: getEnvAsInt("CART_EXPIRY_HOURS", 168),
+
+		// Outbox
+		OutboxPollSeconds: getEnvAsInt("OUTBOX_POLL_SECONDS", 5),
+		OutboxMaxAttempts: getEnvAsInt("OUTBOX_MAX_ATTEMPTS", 8),
+
+		// Pricing
+		Currency:           strings.ToUpper(getEnv("CURRENCY", "USD")),
+		DeliveryBaseFee:    getEnvAsFloat("DELIVERY_BASE_FEE", 2.99),
+		DeliveryFeePerKM:   getEnvAsFloat("DELIVERY_FEE_PER_KM", 0.5),
+		DeliveryIncludedKM: getEnvAsFloat("DELIVERY_INCLUDED_KM", 2),
+		DeliveryZoneFees:   getEnvAsFloatMap("DELIVERY_ZONE_FEES", map[string]float64{}),
+		ServiceFeePercent:  getEnvAsFloat("SERVICE_FEE_PERCENT", 5),
+		ServiceFeeMinimum:  getEnvAsFloat("SERVICE_FEE_MINIMUM", 0.99),
+		TaxRates:           getEnvAsFloatMap("TAX_RATES", map[string]float64{"default": 8}),
+
+		// Idempotency
+		IdempotencyKeyTTLHours: getEnvAsInt("IDEMPOTENCY_KEY_TTL_HOURS", 24),
+
+		// Bookings
+		RescheduleCutoffHours: getEnvAsInt("RESCHEDULE_CUTOFF_HOURS", 24),
+		RescheduleMaxCount:    getEnvAsInt("RESCHEDULE_MAX_COUNT", 2),
+
+		// Recurring bookings
+		SeriesHorizonDays:    getEnvAsInt("SERIES_HORIZON_DAYS", 14),
+		SeriesRefreshMinutes: getEnvAsInt("SERIES_REFRESH_MINUTES", 60),
+
+		// Reviews
+		ReviewWindowDays: getEnvAsInt("REVIEW_WINDOW_DAYS", 30),
+
+		// Waitlist
+		WaitlistHoldMinutes: getEnvAsInt("WAITLIST_HOLD_MINUTES", 15),
+		WaitlistPollSeconds: getEnvAsInt("WAITLIST_POLL_SECONDS", 60),
+
+		// Reminders (before a booking starts)
+		ReminderOffsets:           getEnvAsDurations("REMINDER_OFFSETS", []time.Duration{24 * time.Hour, time.Hour}),
+		TechnicianReminderOffsets: getEnvAsDurations("TECHNICIAN_REMINDER_OFFSETS", []time.Duration{time.Hour}),
+		ReminderPollSeconds:       getEnvAsInt("REMINDER_POLL_SECONDS", 60),
+
+		// Wallet
+		WalletPromoCreditDays:   getEnvAsInt("WALLET_PROMO_CREDIT_DAYS", 90),
+		WalletExpiryPollMinutes: getEnvAsInt("WALLET_EXPIRY_POLL_MINUTES", 60),
+
+		// Timezones (IANA names)
+		DefaultTimezone: getEnv("DEFAULT_TIMEZONE", "UTC"),
+		ZoneTimezones:   getEnvAsStringMap("ZONE_TIMEZONES", map[string]string{}),
 	}
 }
 
@@ -202,3 +316,60 @@ func getEnvAsFloat(key string, defaultValue float64) float64 {
 	}
 	return defaultValue
 }
+
+// getEnvAsFloatMap parses a comma-separated list of key:value pairs, e.g. "default:8,CA:7.25"
+func getEnvAsFloatMap(key string, defaultValue map[string]float64) map[string]float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	result := make(map[string]float64)
+	for _, pair := range strings.Split(value, ",") {
+		name, number, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok {
+			return defaultValue
+		}
+		floatValue, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+		if err != nil {
+			return defaultValue
+		}
+		result[strings.TrimSpace(name)] = floatValue
+	}
+	return result
+}
+
+func getEnvAsStringMap(key string, defaultValue map[string]string) map[string]string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	result := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		name, text, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok {
+			return defaultValue
+		}
+		result[strings.TrimSpace(name)] = strings.TrimSpace(text)
+	}
+	return result
+}
+
+// getEnvAsDurations parses a comma-separated list of durations, e.g. "24h,1h,30m"
+func getEnvAsDurations(key string, defaultValue []time.Duration) []time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	result := make([]time.Duration, 0)
+	for _, item := range strings.Split(value, ",") {
+		duration, err := time.ParseDuration(strings.TrimSpace(item))
+		if err != nil || duration <= 0 {
+			return defaultValue
+		}
+		result = append(result, duration)
+	}
+	return result
+}
@@ -0,0 +1,54 @@
+package db
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	transactionsOnce      sync.Once
+	transactionsSupported bool
+)
+
+// WithTransaction runs fn inside a multi-document transaction, retrying it on transient errors.
+// Standalone servers cannot run transactions, so there fn runs directly against ctx and the
+// writes are applied one by one. fn must use the context it is given for every operation.
+func WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if MongoClient == nil || !supportsTransactions(ctx) {
+		return fn(ctx)
+	}
+
+	session, err := MongoClient.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessCtx)
+	})
+	return err
+}
+
+// supportsTransactions reports whether the server is a replica set member or mongos
+func supportsTransactions(ctx context.Context) bool {
+	transactionsOnce.Do(func() {
+		var hello bson.M
+		err := MongoClient.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello)
+		if err != nil {
+			log.Printf("⚠️  Could not detect transaction support, writes will not be transactional: %v", err)
+			return
+		}
+
+		_, replicaSet := hello["setName"]
+		transactionsSupported = replicaSet || hello["msg"] == "isdbgrid"
+		if !transactionsSupported {
+			log.Println("⚠️  MongoDB is a standalone server, writes will not be transactional")
+		}
+	})
+	return transactionsSupported
+}
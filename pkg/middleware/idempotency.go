@@ -0,0 +1,222 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/code-harsh006/food-delivery/pkg/config"
+	"github.com/code-harsh006/food-delivery/pkg/db"
+	"github.com/code-harsh006/food-delivery/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+)
+
+const (
+	// IdempotencyKeyHeader carries the client-chosen key that makes a retried request safe
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayHeader is set on responses replayed from an earlier request with the same key
+	IdempotentReplayHeader = "Idempotent-Replayed"
+
+	// IdempotencyCollection stores the keys and their responses; expires_at carries a TTL index
+	IdempotencyCollection = "idempotency_keys"
+
+	maxIdempotencyKeyLength = 255
+	// A request still marked in progress after this long is assumed to have died with its server
+	idempotencyLockTimeout = time.Minute
+)
+
+// Response headers that are stored and replayed along with the body
+var replayedHeaders = []string{"Content-Type", "Location", "X-Cart-Token"}
+
+type idempotencyRecord struct {
+	ID          string            `bson:"_id"`
+	Key         string            `bson:"key"`
+	Fingerprint string            `bson:"fingerprint"`
+	Completed   bool              `bson:"completed"`
+	StatusCode  int               `bson:"status_code,omitempty"`
+	Headers     map[string]string `bson:"headers,omitempty"`
+	Body        []byte            `bson:"body,omitempty"`
+	LockedUntil time.Time         `bson:"locked_until"`
+	CreatedAt   time.Time         `bson:"created_at"`
+	ExpiresAt   time.Time         `bson:"expires_at"`
+}
+
+// recordingWriter keeps a copy of the response body so it can be stored for replay
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency makes POST, PUT, PATCH and DELETE requests that carry an Idempotency-Key header
+// safe to retry. The first request with a key is processed normally and its response is stored;
+// a retry with the same key and body gets the stored response back instead of running again.
+// Reusing a key with a different request is rejected with 422, and a retry that arrives while
+// the first request is still being processed with 409. Keys are scoped to the caller's
+// credentials, and responses with a 5xx status are not stored so the request can be retried.
+// Requests without the header, and all requests while MongoDB is unavailable, pass through.
+func Idempotency() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" || !isStateChanging(c.Request.Method) {
+			c.Next()
+			return
+		}
+
+		mongoDB := db.GetMongoDB()
+		if mongoDB == nil {
+			c.Next()
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key must be at most 255 characters"})
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := context.Background()
+		collection := mongoDB.Collection(IdempotencyCollection)
+		now := time.Now()
+		record := idempotencyRecord{
+			ID:          hashParts(callerScope(c), key),
+			Key:         key,
+			Fingerprint: hashParts(c.Request.Method, c.Request.URL.Path, c.Request.URL.RawQuery, string(body)),
+			LockedUntil: now.Add(idempotencyLockTimeout),
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(time.Duration(config.Load().IdempotencyKeyTTLHours) * time.Hour),
+		}
+
+		if _, err := collection.InsertOne(ctx, record); err != nil {
+			if !mongo.IsDuplicateKeyError(err) {
+				logger.Error("Failed to store idempotency key", zap.Error(err))
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process Idempotency-Key"})
+				c.Abort()
+				return
+			}
+			if !claimExistingKey(c, collection, record) {
+				return
+			}
+		}
+
+		writer := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+
+		status := writer.Status()
+		if status >= http.StatusInternalServerError {
+			if _, err := collection.DeleteOne(ctx, bson.M{"_id": record.ID}); err != nil {
+				logger.Error("Failed to release idempotency key", zap.Error(err))
+			}
+			return
+		}
+
+		headers := make(map[string]string)
+		for _, name := range replayedHeaders {
+			if value := writer.Header().Get(name); value != "" {
+				headers[name] = value
+			}
+		}
+		_, err = collection.UpdateOne(ctx, bson.M{"_id": record.ID}, bson.M{"$set": bson.M{
+			"completed":   true,
+			"status_code": status,
+			"headers":     headers,
+			"body":        writer.body.Bytes(),
+		}})
+		if err != nil {
+			logger.Error("Failed to store idempotent response", zap.Error(err))
+		}
+	}
+}
+
+// claimExistingKey handles a key that has been seen before. It writes the response and returns
+// false, unless the earlier request died before finishing, in which case it takes the key over
+// and returns true so the request is processed.
+func claimExistingKey(c *gin.Context, collection *mongo.Collection, record idempotencyRecord) bool {
+	ctx := context.Background()
+
+	var existing idempotencyRecord
+	if err := collection.FindOne(ctx, bson.M{"_id": record.ID}).Decode(&existing); err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusConflict, gin.H{"error": "A request with this Idempotency-Key is being processed, retry later"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process Idempotency-Key"})
+		}
+		c.Abort()
+		return false
+	}
+
+	if existing.Fingerprint != record.Fingerprint {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key was already used for a different request"})
+		c.Abort()
+		return false
+	}
+
+	if existing.Completed {
+		for name, value := range existing.Headers {
+			c.Header(name, value)
+		}
+		c.Header(IdempotentReplayHeader, "true")
+		c.Status(existing.StatusCode)
+		c.Writer.Write(existing.Body)
+		c.Abort()
+		return false
+	}
+
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": record.ID, "completed": false, "locked_until": bson.M{"$lt": time.Now()}},
+		bson.M{"$set": bson.M{"locked_until": record.LockedUntil}},
+	)
+	if err != nil || result.ModifiedCount == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "A request with this Idempotency-Key is being processed, retry later"})
+		c.Abort()
+		return false
+	}
+	return true
+}
+
+// callerScope identifies who sent a request, so two callers choosing the same key do not collide
+func callerScope(c *gin.Context) string {
+	return c.GetHeader("Authorization") + "|" + c.GetHeader("User-ID") + "|" + c.Query("user_id") + "|" + c.GetHeader("X-Cart-Token")
+}
+
+func isStateChanging(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func hashParts(parts ...string) string {
+	hash := sha256.New()
+	for _, part := range parts {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
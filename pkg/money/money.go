@@ -0,0 +1,294 @@
+// Package money holds amounts as a whole number of the currency's minor unit, e.g. cents, so
+// sums and rounding are exact.
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/code-harsh006/food-delivery/pkg/config"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+var (
+	// ErrInvalidAmount is returned for amounts that cannot be read as a decimal of the currency
+	ErrInvalidAmount = errors.New("invalid amount")
+	// ErrCurrencyMismatch is returned for stored amounts that are not in the configured currency
+	ErrCurrencyMismatch = errors.New("amount is not in the configured currency")
+)
+
+// exponents lists currencies whose minor unit is not a hundredth of the major unit
+var exponents = map[string]int{
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0,
+	"PYG": 0, "RWF": 0, "UGX": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+}
+
+// Money is an amount in minor units of a currency. The zero value is zero in no particular
+// currency and takes on the currency of whatever it is added to.
+//
+// In JSON it is a decimal string in major units, e.g. "12.50"; in BSON it is a document
+// {minor: 1250, currency: "USD"}.
+type Money struct {
+	Minor    int64
+	Currency string // ISO 4217 code
+}
+
+// Currency returns the currency amounts are kept in (CURRENCY)
+func Currency() string {
+	return config.Load().Currency
+}
+
+// Exponent returns the number of decimal places of a currency's minor unit
+func Exponent(currency string) int {
+	if exponent, ok := exponents[strings.ToUpper(currency)]; ok {
+		return exponent
+	}
+	return 2
+}
+
+// New returns an amount of minor units
+func New(minor int64, currency string) Money {
+	return Money{Minor: minor, Currency: strings.ToUpper(currency)}
+}
+
+// FromFloat converts an amount in major units, rounding to the minor unit with halves away from
+// zero. The small nudge absorbs binary floating point error so that e.g. 1.005 becomes 1.01.
+func FromFloat(amount float64, currency string) Money {
+	scaled := amount * math.Pow10(Exponent(currency))
+	return New(int64(math.Round(scaled+math.Copysign(1e-7, scaled))), currency)
+}
+
+// Parse reads a decimal amount in major units, e.g. "12.5" or "-3", with no more decimal places
+// than the currency has
+func Parse(value, currency string) (Money, error) {
+	value = strings.TrimSpace(value)
+	negative := strings.HasPrefix(value, "-")
+	digits := strings.TrimPrefix(value, "-")
+
+	whole, fraction, _ := strings.Cut(digits, ".")
+	exponent := Exponent(currency)
+	if whole == "" && fraction == "" || len(fraction) > exponent || !isDigits(whole) || !isDigits(fraction) {
+		return Money{}, fmt.Errorf("%w %q", ErrInvalidAmount, value)
+	}
+
+	minor, err := strconv.ParseInt(whole+fraction+strings.Repeat("0", exponent-len(fraction)), 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w %q", ErrInvalidAmount, value)
+	}
+	if negative {
+		minor = -minor
+	}
+	return New(minor, currency), nil
+}
+
+func isDigits(value string) bool {
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Add returns m + other
+func (m Money) Add(other Money) Money {
+	return Money{Minor: m.Minor + other.Minor, Currency: m.common(other)}
+}
+
+// Sub returns m - other
+func (m Money) Sub(other Money) Money {
+	return Money{Minor: m.Minor - other.Minor, Currency: m.common(other)}
+}
+
+// Mul returns m times a quantity
+func (m Money) Mul(quantity int) Money {
+	return Money{Minor: m.Minor * int64(quantity), Currency: m.Currency}
+}
+
+// Neg returns -m
+func (m Money) Neg() Money {
+	return Money{Minor: -m.Minor, Currency: m.Currency}
+}
+
+// Percent returns percent of m rounded to the minor unit, halves away from zero. The percentage
+// is taken as the decimal it is written as, so 8.875% of 10.00 is exactly 0.8875 before rounding.
+func (m Money) Percent(percent float64) Money {
+	rate, ok := new(big.Rat).SetString(strconv.FormatFloat(percent, 'f', -1, 64))
+	if !ok {
+		return Money{Currency: m.Currency}
+	}
+	amount := new(big.Rat).Mul(new(big.Rat).SetInt64(m.Minor), rate)
+	amount.Quo(amount, big.NewRat(100, 1))
+
+	// Round half away from zero: add a half towards the sign, then truncate
+	half := big.NewRat(1, 2)
+	if amount.Sign() < 0 {
+		half.Neg(half)
+	}
+	amount.Add(amount, half)
+	minor := new(big.Int).Quo(amount.Num(), amount.Denom())
+	return Money{Minor: minor.Int64(), Currency: m.Currency}
+}
+
+// Cmp compares m with other: -1 when m is less, 0 when equal, 1 when more
+func (m Money) Cmp(other Money) int {
+	m.common(other)
+	switch {
+	case m.Minor < other.Minor:
+		return -1
+	case m.Minor > other.Minor:
+		return 1
+	}
+	return 0
+}
+
+// IsZero reports whether the amount is zero
+func (m Money) IsZero() bool {
+	return m.Minor == 0
+}
+
+// IsPositive reports whether the amount is more than zero
+func (m Money) IsPositive() bool {
+	return m.Minor > 0
+}
+
+// IsNegative reports whether the amount is less than zero
+func (m Money) IsNegative() bool {
+	return m.Minor < 0
+}
+
+// Min returns the smaller of two amounts
+func Min(a, b Money) Money {
+	if a.Cmp(b) <= 0 {
+		return Money{Minor: a.Minor, Currency: a.common(b)}
+	}
+	return Money{Minor: b.Minor, Currency: a.common(b)}
+}
+
+// Max returns the larger of two amounts
+func Max(a, b Money) Money {
+	if a.Cmp(b) >= 0 {
+		return Money{Minor: a.Minor, Currency: a.common(b)}
+	}
+	return Money{Minor: b.Minor, Currency: a.common(b)}
+}
+
+// common returns the currency of an operation on m and other. Amounts read from requests and
+// from the database are all in the configured currency, so two different currencies only meet
+// through a programming error, which panics.
+func (m Money) common(other Money) string {
+	switch {
+	case m.Currency == "":
+		return other.Currency
+	case other.Currency == "" || other.Currency == m.Currency:
+		return m.Currency
+	}
+	panic(fmt.Sprintf("money: cannot combine %s with %s", m.Currency, other.Currency))
+}
+
+// String formats the amount in major units, e.g. "12.50"
+func (m Money) String() string {
+	currency := m.Currency
+	if currency == "" {
+		currency = Currency()
+	}
+	exponent := Exponent(currency)
+	minor := m.Minor
+	sign := ""
+	if minor < 0 {
+		sign = "-"
+		minor = -minor
+	}
+	if exponent == 0 {
+		return sign + strconv.FormatInt(minor, 10)
+	}
+
+	digits := fmt.Sprintf("%0*d", exponent+1, minor)
+	return sign + digits[:len(digits)-exponent] + "." + digits[len(digits)-exponent:]
+}
+
+// MarshalJSON writes the amount as a decimal string
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.String())
+}
+
+// UnmarshalJSON reads a decimal string or a JSON number in major units of the configured currency
+func (m *Money) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+
+	value := string(data)
+	if strings.HasPrefix(value, `"`) {
+		if err := json.Unmarshal(data, &value); err != nil {
+			return err
+		}
+	}
+
+	parsed, err := Parse(value, Currency())
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// stored is the BSON document an amount is kept as
+type stored struct {
+	Minor    int64  `bson:"minor"`
+	Currency string `bson:"currency"`
+}
+
+// MarshalBSONValue stores the amount as {minor, currency}. An amount without a currency is
+// stored in the configured currency.
+func (m Money) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	currency := m.Currency
+	if currency == "" {
+		currency = Currency()
+	}
+	return bson.MarshalValue(stored{Minor: m.Minor, Currency: currency})
+}
+
+// UnmarshalBSONValue reads an amount stored as {minor, currency}. Amounts stored as plain numbers
+// in major units, as they were before, are read in the configured currency. An amount stored in
+// another currency is an error rather than something that could later be combined with one in
+// the configured currency.
+func (m *Money) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	value := bson.RawValue{Type: t, Value: data}
+
+	switch t {
+	case bsontype.EmbeddedDocument:
+		var doc stored
+		if err := value.Unmarshal(&doc); err != nil {
+			return err
+		}
+		if doc.Currency != "" && !strings.EqualFold(doc.Currency, Currency()) {
+			return fmt.Errorf("%w: stored in %s, expected %s", ErrCurrencyMismatch, doc.Currency, Currency())
+		}
+		*m = New(doc.Minor, Currency())
+	case bsontype.Double:
+		*m = FromFloat(value.Double(), Currency())
+	case bsontype.Int32:
+		*m = FromFloat(float64(value.Int32()), Currency())
+	case bsontype.Int64:
+		*m = FromFloat(float64(value.Int64()), Currency())
+	case bsontype.Decimal128:
+		parsed, err := Parse(value.Decimal128().String(), Currency())
+		if err != nil {
+			return err
+		}
+		*m = parsed
+	case bsontype.Null, bsontype.Undefined:
+		*m = Money{}
+	default:
+		return fmt.Errorf("%w: cannot read BSON %s as money", ErrInvalidAmount, t)
+	}
+	return nil
+}
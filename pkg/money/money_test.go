@@ -0,0 +1,130 @@
+package money
+
+import (
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPercent(t *testing.T) {
+	tests := []struct {
+		name    string
+		amount  Money
+		percent float64
+		want    int64
+	}{
+		{"exact", New(1000, "USD"), 10, 100},
+		{"rounds half up", New(50, "USD"), 5, 3},                 // 2.5 cents
+		{"rounds below half down", New(49, "USD"), 5, 2},         // 2.45 cents
+		{"rate with many decimals", New(1000, "USD"), 8.875, 89}, // 88.75 cents
+		{"negative rounds half away from zero", New(-50, "USD"), 5, -3},
+		{"negative below half", New(-49, "USD"), 5, -2},
+		{"negative percent", New(50, "USD"), -5, -3},
+		{"zero decimal currency", New(105, "JPY"), 10, 11}, // 10.5 yen
+		{"zero", New(0, "USD"), 12.5, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.amount.Percent(tt.percent)
+			if got.Minor != tt.want || got.Currency != tt.amount.Currency {
+				t.Errorf("%v.Percent(%v) = %d %s, want %d %s", tt.amount, tt.percent, got.Minor, got.Currency, tt.want, tt.amount.Currency)
+			}
+		})
+	}
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		currency string
+		input    string
+		want     int64
+		wantErr  bool
+	}{
+		{"string", "USD", `"12.50"`, 1250, false},
+		{"number", "USD", `12.5`, 1250, false},
+		{"whole", "USD", `"3"`, 300, false},
+		{"negative", "USD", `"-0.05"`, -5, false},
+		{"too many decimals", "USD", `"12.505"`, 0, true},
+		{"too many decimals as number", "USD", `0.001`, 0, true},
+		{"decimals in zero decimal currency", "JPY", `"100.5"`, 0, true},
+		{"three decimal currency", "KWD", `"1.005"`, 1005, false},
+		{"not a number", "USD", `"abc"`, 0, true},
+		{"empty", "USD", `""`, 0, true},
+		{"exponent", "USD", `1e2`, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("CURRENCY", tt.currency)
+
+			var got Money
+			err := got.UnmarshalJSON([]byte(tt.input))
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidAmount) {
+					t.Errorf("UnmarshalJSON(%s) error = %v, want %v", tt.input, err, ErrInvalidAmount)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UnmarshalJSON(%s) error = %v", tt.input, err)
+			}
+			if got.Minor != tt.want || got.Currency != tt.currency {
+				t.Errorf("UnmarshalJSON(%s) = %d %s, want %d %s", tt.input, got.Minor, got.Currency, tt.want, tt.currency)
+			}
+		})
+	}
+}
+
+func TestUnmarshalBSONValue(t *testing.T) {
+	t.Setenv("CURRENCY", "USD")
+
+	decimal, _ := primitive.ParseDecimal128("7.25")
+	tests := []struct {
+		name    string
+		value   interface{}
+		want    int64
+		wantErr error
+	}{
+		{"stored document", bson.M{"minor": int64(1250), "currency": "USD"}, 1250, nil},
+		{"stored lower case currency", bson.M{"minor": int64(1250), "currency": "usd"}, 1250, nil},
+		{"legacy double", 12.5, 1250, nil},
+		{"legacy double with float error", 1.005, 101, nil},
+		{"legacy double negative", -0.1, -10, nil},
+		{"legacy int32", int32(12), 1200, nil},
+		{"legacy int64", int64(12), 1200, nil},
+		{"legacy decimal", decimal, 725, nil},
+		{"null", nil, 0, nil},
+		{"other currency", bson.M{"minor": int64(1250), "currency": "EUR"}, 0, ErrCurrencyMismatch},
+		{"string", "12.50", 0, ErrInvalidAmount},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := bson.Marshal(bson.M{"amount": tt.value})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var doc struct {
+				Amount Money `bson:"amount"`
+			}
+			err = bson.Unmarshal(data, &doc)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("decoding %v: error = %v, want %v", tt.value, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decoding %v: error = %v", tt.value, err)
+			}
+			if doc.Amount.Minor != tt.want {
+				t.Errorf("decoding %v = %d, want %d", tt.value, doc.Amount.Minor, tt.want)
+			}
+			if tt.value != nil && doc.Amount.Currency != "USD" {
+				t.Errorf("decoding %v: currency = %q, want USD", tt.value, doc.Amount.Currency)
+			}
+		})
+	}
+}